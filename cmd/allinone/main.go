@@ -8,19 +8,19 @@ import (
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sbasestarter/bizinters/userinters"
-	"github.com/sbasestarter/bizinters/userinters/userpass"
 	"github.com/sbasestarter/bizmongolib/mongolib"
 	"github.com/sbasestarter/bizmongolib/talk/model"
-	userpassauthenticator "github.com/sbasestarter/bizmongolib/user/authenticator/userpass"
 	"github.com/sbasestarter/userlib"
 	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
 	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
 	"github.com/sbasestarter/userlib/policy/single"
 	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libservicetoolset/servicetoolset"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/config"
 	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
 	"github.com/zservicer/talkbe/internal/impls"
 	"github.com/zservicer/talkbe/internal/server"
 	"google.golang.org/grpc"
@@ -57,27 +57,159 @@ func main() {
 	}
 
 	var rM talkinters.Model
+
+	var slaM defs.TalkSLAModel
+
+	var participantM defs.TalkParticipantModel
+
+	var messageExM defs.TalkMessageExModel
+
+	var flagM defs.ModerationFlagModel
+
+	var banM defs.CustomerBanModel
+
+	var auditM defs.AuditLogModel
+
+	var loginFailuresM defs.LoginFailuresModel
+
+	var twoFactorPolicyM defs.TwoFactorPolicyModel
+
+	var invitationM defs.ServicerInvitationModel
+
+	var servicerStatusController defs.ServicerStatusController
+
 	if cfg.Dev.UseMemoryModel {
 		rM = impls.NewMemModel()
+		slaM = impls.NewMemSLAModel()
+		participantM = impls.NewMemParticipantModel()
+		messageExM = impls.NewMemMessageExModel()
+		flagM = impls.NewMemModerationFlagModel()
+		banM = impls.NewMemCustomerBanModel()
+		auditM = impls.NewMemAuditLogModel()
+		loginFailuresM = impls.NewMemLoginFailuresModel()
+		twoFactorPolicyM = impls.NewMemTwoFactorPolicyModel()
+		invitationM = impls.NewMemInvitationModel()
+		servicerStatusController = impls.NewMemServicerStatusController()
 	} else {
 		rM, err = model.NewMongoModel(cfg.TalkMongoDSN, logger)
 		if err != nil {
 			logger.Fatal(err)
 		}
+
+		slaM, err = impls.NewMongoSLAModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		participantM, err = impls.NewMongoParticipantModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		messageExM, err = impls.NewMongoMessageExModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		flagM, err = impls.NewMongoModerationFlagModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		banM, err = impls.NewMongoCustomerBanModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		auditM, err = impls.NewMongoAuditLogModel(cfg.TalkMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		loginFailuresM, err = impls.NewMongoLoginFailuresModel(cfg.UserMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		twoFactorPolicyM, err = impls.NewMongoTwoFactorPolicyModel(cfg.UserMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		invitationM, err = impls.NewMongoInvitationModel(cfg.UserMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		servicerStatusController, err = impls.NewMongoServicerStatusController(cfg.UserMongoDSN)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	slaTracker := impls.NewSLATracker(slaM, cfg.SLA, logger)
+
+	var imageStore defs.BlobStore
+
+	if cfg.Attachment.Store != "" {
+		imageStore, err = impls.NewBlobStore(&cfg.Attachment)
+		if err != nil {
+			logger.Fatal(err)
+
+			return
+		}
+	}
+
+	imageProcessor := impls.NewImageProcessor(cfg.Image, imageStore, logger)
+
+	modelEx := impls.NewModelEx(rM, messageExM)
+	redactor, err := impls.NewRedactor(cfg.Redaction, modelEx, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	messageReviser := impls.NewMessageReviser(modelEx, cfg.MessageRevision, redactor)
+
+	moderator, err := impls.NewModerator(cfg.Moderation, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
 	}
 
-	modelEx := impls.NewModelEx(rM)
+	rateLimiter := impls.NewRateLimiter(cfg.RateLimit, modelEx, logger)
 	mdi := impls.NewAllInOneMDI(modelEx, logger)
 
-	customerUserCenter := userlib.NewUserCenter(cfg.CustomerTokenSecret, single.NewPolicy(userinters.AuthMethodNameAnonymous),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
-	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter)
-	customerMD := impls.NewCustomerMD(mdi, logger)
+	customerIdentityVerifier, err := impls.NewCustomerIdentityVerifier(cfg.CustomerIdentities)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	customerStatusController := memorystatuscontroller.NewStatusController()
+	customerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	customerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetCustomerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, single.NewPolicy(userinters.AuthMethodNameAnonymous),
+			customerStatusController, customerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter, customerIdentityVerifier)
+	customerMD := impls.NewCustomerMD(mdi, slaTracker, logger)
 	customerController := controller.NewCustomerController(customerMD, modelEx, logger)
-	grpcCustomerServer := server.NewCustomerServer(customerController, customerUserTokenHelper, modelEx, logger)
-	grpcCustomerUserServer := server.NewCustomerUserServer(customerUserCenter, customerUserTokenHelper)
+	grpcCustomerServer := server.NewCustomerServer(customerController, customerUserTokenHelper, modelEx, imageProcessor, messageReviser, redactor,
+		moderator, flagM, rateLimiter, banM, logger)
+	grpcCustomerUserServer := server.NewCustomerUserServer(customerUserCenter, customerUserTokenHelper, banM)
 
-	var serviceUserPassModel userpass.UserPasswordModel
+	var serviceUserPassModel defs.ServicerUserModel
 
 	if cfg.Dev.UseMemoryModel {
 		serviceUserPassModel = impls.NewMemUserPassModel()
@@ -88,33 +220,71 @@ func main() {
 
 			return
 		}
-		serviceUserPassModel = userpassauthenticator.NewMongoUserPasswordModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
+		serviceUserPassModel = impls.NewMongoServicerUserModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
 	}
 
-	servicerUserCenter := userlib.NewUserCenter(cfg.ServicerTokenSecret, single.NewPolicy(userinters.AuthMethodNameUserPassword),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
+	passwordPolicy := impls.NewPasswordPolicy(cfg.ServicerPasswordPolicy)
+	loginGuard := impls.NewLoginGuard(cfg.ServicerLoginGuard, loginFailuresM, auditM, logger)
+	invitations := impls.NewServicerInvitations(cfg.ServicerRegistration, invitationM)
+
+	twoFactor, err := impls.NewTwoFactorAuth(cfg.ServicerTOTP, cfg.ServicerPasswordSecret, serviceUserPassModel, twoFactorPolicyM)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	servicerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetServicerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, impls.NewServicerLoginPolicy(twoFactor),
+			servicerStatusController, servicerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	config.WatchReload(func(newCfg *config.Config) {
+		if err := customerUserCenter.UpdateKeys(newCfg.GetCustomerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateCustomerTokenKeysFailed")
+		}
+
+		if err := servicerUserCenter.UpdateKeys(newCfg.GetServicerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateServicerTokenKeysFailed")
+		}
+	})
 
 	servicerManager := userpassmanager.NewManager(cfg.ServicerPasswordSecret, serviceUserPassModel)
-	userID, err := servicerManager.Register(context.TODO(), "demo", "123456")
+	err = impls.BootstrapServicerOwner(context.TODO(), cfg.ServicerBootstrap, serviceUserPassModel, servicerManager,
+		passwordPolicy, logger)
+	if err != nil {
+		logger.Fatal(err)
 
-	if err == nil {
-		_ = servicerManager.UpdateUserAllExData(context.TODO(), userID, map[string]interface{}{
-			"permission": 1,
-			"actIDs": []string{
-				"actIDDemo",
-			},
-			"bizIDs": []string{
-				"bizIDDemo",
-			},
-		})
+		return
 	}
 
-	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager)
+	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager, servicerStatusController)
 
-	servicerMD := impls.NewServicerMD(mdi, logger)
+	servicerMD := impls.NewServicerMD(mdi, participantM, slaTracker, logger)
 	servicerController := controller.NewServicerController(servicerMD, modelEx, logger)
-	grpcServicerServer := server.NewServicerServer(servicerController, servicerUserTokenHelper, modelEx, logger)
-	grpcServicerUserServer := server.NewServicerUserServer(servicerManager, servicerUserCenter, servicerUserTokenHelper)
+	grpcServicerServer := server.NewServicerServer(servicerController, servicerUserTokenHelper, modelEx, imageProcessor, messageReviser, redactor,
+		moderator, flagM, rateLimiter, logger)
+
+	trustedProxies, err := server.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	grpcServicerUserServer := server.NewServicerUserServer(servicerManager, servicerUserCenter, servicerUserTokenHelper, passwordPolicy, loginGuard,
+		twoFactor, invitations, trustedProxies)
+	servicerAPIServer := server.NewServicerAPIServer(servicerController, servicerUserTokenHelper, modelEx, participantM,
+		impls.NewReporter(modelEx, slaM), redactor, flagM, banM, auditM,
+		impls.NewServicerUserAdmin(cfg.ServicerPasswordSecret, passwordPolicy, serviceUserPassModel), servicerUserCenter,
+		loginGuard, twoFactor, twoFactorPolicyM, invitations, trustedProxies, logger)
 
 	err = s.Start(func(s *grpc.Server) error {
 		talkpb.RegisterCustomerTalkServiceServer(s, grpcCustomerServer)
@@ -170,6 +340,23 @@ func main() {
 		log.Fatal(s.ListenAndServe())
 	}()
 
+	if cfg.ServicerAPIListen != "" {
+		go func() {
+			mux := http.NewServeMux()
+			servicerAPIServer.Setup(mux)
+
+			s := &http.Server{
+				Addr:              cfg.ServicerAPIListen,
+				ReadHeaderTimeout: 3 * time.Second,
+				Handler:           mux,
+			}
+
+			logger.Info("servicer api server listen on: ", cfg.ServicerAPIListen)
+
+			log.Fatal(s.ListenAndServe())
+		}()
+	}
+
 	logger.Info("grpc server listen on: ", cfg.Listen)
 	s.Wait()
 }
@@ -9,10 +9,12 @@ import (
 	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
 	"github.com/sbasestarter/userlib/policy/single"
 	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libservicetoolset/servicetoolset"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/config"
 	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
 	"github.com/zservicer/talkbe/internal/impls"
 	"github.com/zservicer/talkbe/internal/server"
 	"google.golang.org/grpc"
@@ -46,9 +48,33 @@ func main() {
 		return
 	}
 
-	customerUserCenter := userlib.NewUserCenter(cfg.CustomerTokenSecret, single.NewPolicy(userinters.AuthMethodNameAnonymous),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
-	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter)
+	customerIdentityVerifier, err := impls.NewCustomerIdentityVerifier(cfg.CustomerIdentities)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	customerStatusController := memorystatuscontroller.NewStatusController()
+	customerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	customerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetCustomerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, single.NewPolicy(userinters.AuthMethodNameAnonymous),
+			customerStatusController, customerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	config.WatchReload(func(newCfg *config.Config) {
+		if err := customerUserCenter.UpdateKeys(newCfg.GetCustomerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateCustomerTokenKeysFailed")
+		}
+	})
+
+	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter, customerIdentityVerifier)
 
 	rM, err := model.NewMongoModel(cfg.TalkMongoDSN, logger)
 	if err != nil {
@@ -57,14 +83,76 @@ func main() {
 		return
 	}
 
-	modelEx := impls.NewModelEx(rM)
+	messageExM, err := impls.NewMongoMessageExModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	modelEx := impls.NewModelEx(rM, messageExM)
+	redactor, err := impls.NewRedactor(cfg.Redaction, modelEx, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	messageReviser := impls.NewMessageReviser(modelEx, cfg.MessageRevision, redactor)
+
+	moderator, err := impls.NewModerator(cfg.Moderation, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	rateLimiter := impls.NewRateLimiter(cfg.RateLimit, modelEx, logger)
+
+	flagM, err := impls.NewMongoModerationFlagModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	banM, err := impls.NewMongoCustomerBanModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	slaM, err := impls.NewMongoSLAModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	slaTracker := impls.NewSLATracker(slaM, cfg.SLA, logger)
+
+	var imageStore defs.BlobStore
+
+	if cfg.Attachment.Store != "" {
+		imageStore, err = impls.NewBlobStore(&cfg.Attachment)
+		if err != nil {
+			logger.Fatal(err)
+
+			return
+		}
+	}
+
+	imageProcessor := impls.NewImageProcessor(cfg.Image, imageStore, logger)
+
 	mdi := impls.NewCustomerRabbitMQMDI(cfg.RabbitMQURL, modelEx, logger)
 
-	customerMD := impls.NewCustomerMD(mdi, logger)
+	customerMD := impls.NewCustomerMD(mdi, slaTracker, logger)
 
 	customerController := controller.NewCustomerController(customerMD, modelEx, logger)
 
-	grpcCustomerServer := server.NewCustomerServer(customerController, customerUserTokenHelper, modelEx, logger)
+	grpcCustomerServer := server.NewCustomerServer(customerController, customerUserTokenHelper, modelEx, imageProcessor, messageReviser, redactor,
+		moderator, flagM, rateLimiter, banM, logger)
 
 	err = s.Start(func(s *grpc.Server) error {
 		talkpb.RegisterCustomerTalkServiceServer(s, grpcCustomerServer)
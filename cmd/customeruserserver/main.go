@@ -8,6 +8,7 @@ import (
 	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
 	"github.com/sbasestarter/userlib/policy/single"
 	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libservicetoolset/servicetoolset"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/config"
@@ -44,10 +45,41 @@ func main() {
 		return
 	}
 
-	customerUserCenter := userlib.NewUserCenter(cfg.CustomerTokenSecret, single.NewPolicy(userinters.AuthMethodNameAnonymous),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
-	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter)
-	grpcCustomerUserServer := server.NewCustomerUserServer(customerUserCenter, customerUserTokenHelper)
+	banM, err := impls.NewMongoCustomerBanModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	customerIdentityVerifier, err := impls.NewCustomerIdentityVerifier(cfg.CustomerIdentities)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	customerStatusController := memorystatuscontroller.NewStatusController()
+	customerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	customerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetCustomerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, single.NewPolicy(userinters.AuthMethodNameAnonymous),
+			customerStatusController, customerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	config.WatchReload(func(newCfg *config.Config) {
+		if err := customerUserCenter.UpdateKeys(newCfg.GetCustomerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateCustomerTokenKeysFailed")
+		}
+	})
+
+	customerUserTokenHelper := impls.NewLocalCustomerUserTokenHelper(customerUserCenter, customerIdentityVerifier)
+	grpcCustomerUserServer := server.NewCustomerUserServer(customerUserCenter, customerUserTokenHelper, banM)
 
 	err = s.Start(func(s *grpc.Server) error {
 		talkpb.RegisterCustomerUserServicerServer(s, grpcCustomerUserServer)
@@ -1,21 +1,22 @@
 package main
 
 import (
+	"log"
+	"net/http"
 	"time"
 
 	"github.com/sbasestarter/bizinters/userinters"
 	"github.com/sbasestarter/bizmongolib/mongolib"
 	"github.com/sbasestarter/bizmongolib/talk/model"
-	userpassauthenticator "github.com/sbasestarter/bizmongolib/user/authenticator/userpass"
 	"github.com/sbasestarter/userlib"
 	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
 	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
-	"github.com/sbasestarter/userlib/policy/single"
-	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libservicetoolset/servicetoolset"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/config"
 	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
 	"github.com/zservicer/talkbe/internal/impls"
 	"github.com/zservicer/talkbe/internal/server"
 	"google.golang.org/grpc"
@@ -56,11 +57,57 @@ func main() {
 		return
 	}
 
-	servicerUserCenter := userlib.NewUserCenter(cfg.ServicerTokenSecret, single.NewPolicy(userinters.AuthMethodNameUserPassword),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
-	serviceUserPassModel := userpassauthenticator.NewMongoUserPasswordModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
+	passwordPolicy := impls.NewPasswordPolicy(cfg.ServicerPasswordPolicy)
+	serviceUserPassModel := impls.NewMongoServicerUserModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
+
+	twoFactorPolicyM, err := impls.NewMongoTwoFactorPolicyModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	invitationM, err := impls.NewMongoInvitationModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	twoFactor, err := impls.NewTwoFactorAuth(cfg.ServicerTOTP, cfg.ServicerPasswordSecret, serviceUserPassModel, twoFactorPolicyM)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerStatusController, err := impls.NewMongoServicerStatusController(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	servicerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetServicerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, impls.NewServicerLoginPolicy(twoFactor),
+			servicerStatusController, servicerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	config.WatchReload(func(newCfg *config.Config) {
+		if err := servicerUserCenter.UpdateKeys(newCfg.GetServicerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateServicerTokenKeysFailed")
+		}
+	})
+
 	servicerManager := userpassmanager.NewManager(cfg.ServicerPasswordSecret, serviceUserPassModel)
-	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager)
+	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager, servicerStatusController)
 
 	rM, err := model.NewMongoModel(cfg.TalkMongoDSN, logger)
 	if err != nil {
@@ -69,14 +116,110 @@ func main() {
 		return
 	}
 
-	modelEx := impls.NewModelEx(rM)
+	messageExM, err := impls.NewMongoMessageExModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	modelEx := impls.NewModelEx(rM, messageExM)
+	redactor, err := impls.NewRedactor(cfg.Redaction, modelEx, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	messageReviser := impls.NewMessageReviser(modelEx, cfg.MessageRevision, redactor)
+
+	moderator, err := impls.NewModerator(cfg.Moderation, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	rateLimiter := impls.NewRateLimiter(cfg.RateLimit, modelEx, logger)
+
+	flagM, err := impls.NewMongoModerationFlagModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	banM, err := impls.NewMongoCustomerBanModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	auditM, err := impls.NewMongoAuditLogModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	loginFailuresM, err := impls.NewMongoLoginFailuresModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	slaM, err := impls.NewMongoSLAModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	slaTracker := impls.NewSLATracker(slaM, cfg.SLA, logger)
+
+	var imageStore defs.BlobStore
+
+	if cfg.Attachment.Store != "" {
+		imageStore, err = impls.NewBlobStore(&cfg.Attachment)
+		if err != nil {
+			logger.Fatal(err)
+
+			return
+		}
+	}
+
+	imageProcessor := impls.NewImageProcessor(cfg.Image, imageStore, logger)
+
+	participantM, err := impls.NewMongoParticipantModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
 	mdi := impls.NewServicerRabbitMQMDI(cfg.RabbitMQURL, modelEx, logger)
 
-	servicerMD := impls.NewServicerMD(mdi, logger)
+	servicerMD := impls.NewServicerMD(mdi, participantM, slaTracker, logger)
 
 	servicerController := controller.NewServicerController(servicerMD, modelEx, logger)
 
-	grpcServicerServer := server.NewServicerServer(servicerController, servicerUserTokenHelper, modelEx, logger)
+	grpcServicerServer := server.NewServicerServer(servicerController, servicerUserTokenHelper, modelEx, imageProcessor, messageReviser, redactor,
+		moderator, flagM, rateLimiter, logger)
+
+	trustedProxies, err := server.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerAPIServer := server.NewServicerAPIServer(servicerController, servicerUserTokenHelper, modelEx, participantM,
+		impls.NewReporter(modelEx, slaM), redactor, flagM, banM, auditM,
+		impls.NewServicerUserAdmin(cfg.ServicerPasswordSecret, passwordPolicy, serviceUserPassModel), servicerUserCenter,
+		impls.NewLoginGuard(cfg.ServicerLoginGuard, loginFailuresM, auditM, logger), twoFactor, twoFactorPolicyM,
+		impls.NewServicerInvitations(cfg.ServicerRegistration, invitationM), trustedProxies, logger)
 
 	err = s.Start(func(s *grpc.Server) error {
 		talkpb.RegisterServiceTalkServiceServer(s, grpcServicerServer)
@@ -89,6 +232,23 @@ func main() {
 		return
 	}
 
+	if cfg.ServicerAPIListen != "" {
+		go func() {
+			mux := http.NewServeMux()
+			servicerAPIServer.Setup(mux)
+
+			s := &http.Server{
+				Addr:              cfg.ServicerAPIListen,
+				ReadHeaderTimeout: 3 * time.Second,
+				Handler:           mux,
+			}
+
+			logger.Info("servicer api server listen on: ", cfg.ServicerAPIListen)
+
+			log.Fatal(s.ListenAndServe())
+		}()
+	}
+
 	logger.Info("grpc server listen on: ", cfg.ServicerListen)
 	s.Wait()
 }
@@ -6,12 +6,10 @@ import (
 
 	"github.com/sbasestarter/bizinters/userinters"
 	"github.com/sbasestarter/bizmongolib/mongolib"
-	userpassauthenticator "github.com/sbasestarter/bizmongolib/user/authenticator/userpass"
 	"github.com/sbasestarter/userlib"
 	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
 	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
-	"github.com/sbasestarter/userlib/policy/single"
-	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libservicetoolset/servicetoolset"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/config"
@@ -55,26 +53,90 @@ func main() {
 		return
 	}
 
-	servicerUserCenter := userlib.NewUserCenter(cfg.ServicerTokenSecret, single.NewPolicy(userinters.AuthMethodNameUserPassword),
-		memorystatuscontroller.NewStatusController(), memoryauthingdatastorage.NewMemoryAuthingDataStorage(), logger)
-	serviceUserPassModel := userpassauthenticator.NewMongoUserPasswordModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
+	loginFailuresM, err := impls.NewMongoLoginFailuresModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	auditM, err := impls.NewMongoAuditLogModel(cfg.TalkMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	passwordPolicy := impls.NewPasswordPolicy(cfg.ServicerPasswordPolicy)
+	loginGuard := impls.NewLoginGuard(cfg.ServicerLoginGuard, loginFailuresM, auditM, logger)
+	twoFactorPolicyM, err := impls.NewMongoTwoFactorPolicyModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	serviceUserPassModel := impls.NewMongoServicerUserModel(mongoCli, mongoOptions.Auth.AuthSource, "servicer_users", logger)
+
+	invitationM, err := impls.NewMongoInvitationModel(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	twoFactor, err := impls.NewTwoFactorAuth(cfg.ServicerTOTP, cfg.ServicerPasswordSecret, serviceUserPassModel, twoFactorPolicyM)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerStatusController, err := impls.NewMongoServicerStatusController(cfg.UserMongoDSN)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	servicerAuthingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	servicerUserCenter, err := impls.NewKeyedUserCenter(cfg.GetServicerTokenKeys(), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, impls.NewServicerLoginPolicy(twoFactor),
+			servicerStatusController, servicerAuthingDataStorage, logger)
+	})
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	config.WatchReload(func(newCfg *config.Config) {
+		if err := servicerUserCenter.UpdateKeys(newCfg.GetServicerTokenKeys()); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("UpdateServicerTokenKeysFailed")
+		}
+	})
+
 	servicerManager := userpassmanager.NewManager(cfg.ServicerPasswordSecret, serviceUserPassModel)
-	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager)
-
-	userID, err := servicerManager.Register(context.TODO(), "demo", "123456")
-	if err == nil {
-		_ = servicerManager.UpdateUserAllExData(context.TODO(), userID, map[string]interface{}{
-			"permission": 1,
-			"actIDs": []string{
-				"actIDDemo",
-			},
-			"bizIDs": []string{
-				"bizIDDemo",
-			},
-		})
+	servicerUserTokenHelper := impls.NewLocalServicerUserTokenHelper(servicerUserCenter, servicerManager, servicerStatusController)
+
+	err = impls.BootstrapServicerOwner(context.TODO(), cfg.ServicerBootstrap, serviceUserPassModel, servicerManager,
+		passwordPolicy, logger)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
+	}
+
+	trustedProxies, err := server.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal(err)
+
+		return
 	}
 
-	grpcServicerUserServer := server.NewServicerUserServer(servicerManager, servicerUserCenter, servicerUserTokenHelper)
+	grpcServicerUserServer := server.NewServicerUserServer(servicerManager, servicerUserCenter, servicerUserTokenHelper, passwordPolicy, loginGuard,
+		twoFactor, impls.NewServicerInvitations(cfg.ServicerRegistration, invitationM), trustedProxies)
 
 	err = s.Start(func(s *grpc.Server) error {
 		talkpb.RegisterServicerUserServicerServer(s, grpcServicerUserServer)
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 
 	"github.com/sgostarter/i/l"
 	"github.com/sgostarter/libconfig"
@@ -20,19 +23,267 @@ type Config struct {
 	CustomerUserListen string `yaml:"CustomerUserListen"`
 	ServicerListen     string `yaml:"ServicerListen"`
 	ServicerUserListen string `yaml:"ServicerUserListen"`
+	ServicerAPIListen  string `yaml:"ServicerAPIListen"`
+
+	// TrustedProxies are the ips or the cidrs of the gateways and the reverse proxies whose forwarded client ips
+	// are honored, the loopback addresses if empty.
+	TrustedProxies []string `yaml:"TrustedProxies"`
 
 	TalkMongoDSN string `yaml:"TalkMongoDSN"`
 	RabbitMQURL  string `yaml:"RabbitMQURL"`
 
 	UserMongoDSN string `yaml:"UserMongoDSN"`
 
-	CustomerTokenSecret    string `yaml:"CustomerTokenSecret"`
-	ServicerTokenSecret    string `yaml:"ServicerTokenSecret"`
-	ServicerPasswordSecret string `yaml:"ServicerPasswordSecret"`
+	CustomerTokenSecret    string    `yaml:"CustomerTokenSecret"` // the legacy key, see TokenKeys
+	ServicerTokenSecret    string    `yaml:"ServicerTokenSecret"` // the legacy key, see TokenKeys
+	CustomerTokenKeys      TokenKeys `yaml:"CustomerTokenKeys"`
+	ServicerTokenKeys      TokenKeys `yaml:"ServicerTokenKeys"`
+	ServicerPasswordSecret string    `yaml:"ServicerPasswordSecret"`
+
+	ServicerPasswordPolicy PasswordPolicy `yaml:"ServicerPasswordPolicy"`
+	ServicerLoginGuard     LoginGuard     `yaml:"ServicerLoginGuard"`
+	ServicerTOTP           TOTP           `yaml:"ServicerTOTP"`
+	ServicerRegistration   Registration   `yaml:"ServicerRegistration"`
+	ServicerBootstrap      Bootstrap      `yaml:"ServicerBootstrap"`
+
+	// CustomerIdentities verifies the customer identities signed by the host apps, keyed by actID.
+	CustomerIdentities map[string]CustomerIdentity `yaml:"CustomerIdentities"`
+
+	SLA SLA `yaml:"SLA"`
+
+	// Attachment is shared with the gateways, the original images are stored there.
+	Attachment Attachment `yaml:"Attachment"`
+	Image      Image      `yaml:"Image"`
+
+	MessageRevision MessageRevision `yaml:"MessageRevision"`
+	Redaction       Redaction       `yaml:"Redaction"`
+	Moderation      Moderation      `yaml:"Moderation"`
+	RateLimit       RateLimit       `yaml:"RateLimit"`
 
 	Dev Dev `yaml:"Dev"`
 }
 
+type TokenKey struct {
+	ID      string `yaml:"ID"`
+	Secret  string `yaml:"Secret"`
+	Retired bool   `yaml:"Retired"` // the tokens signed by the key are rejected
+}
+
+// TokenKeys are the keyed secrets of the tokens, the new tokens are signed by the active key and the tokens
+// signed by the other non retired keys are still accepted, the renewed ones are signed by the active key.
+// A key is rotated by adding the new key, activating it, and retiring the old one after the token lifetime,
+// the keys are reloaded on SIGHUP.
+type TokenKeys struct {
+	ActiveKeyID string     `yaml:"ActiveKeyID"`
+	Keys        []TokenKey `yaml:"Keys"`
+}
+
+// WithLegacySecret adds the single secret of the older configs as the key with the empty id, unless the key
+// is configured, so the tokens issued before the keys keep working. The legacy secret is used even if it's empty
+// when no key is configured, as the older configs did.
+func (keys TokenKeys) WithLegacySecret(secret string) TokenKeys {
+	if secret == "" && len(keys.Keys) > 0 {
+		return keys
+	}
+
+	for _, key := range keys.Keys {
+		if key.ID == "" {
+			return keys
+		}
+	}
+
+	keys.Keys = append(append(make([]TokenKey, 0, len(keys.Keys)+1), keys.Keys...), TokenKey{
+		Secret: secret,
+	})
+
+	return keys
+}
+
+func (cfg *Config) GetCustomerTokenKeys() TokenKeys {
+	return cfg.CustomerTokenKeys.WithLegacySecret(cfg.CustomerTokenSecret)
+}
+
+func (cfg *Config) GetServicerTokenKeys() TokenKeys {
+	return cfg.ServicerTokenKeys.WithLegacySecret(cfg.ServicerTokenSecret)
+}
+
+// PasswordPolicy is enforced when the passwords are set, the zero values disable the rules.
+type PasswordPolicy struct {
+	MinLength     int  `yaml:"MinLength"`
+	RequireUpper  bool `yaml:"RequireUpper"`
+	RequireLower  bool `yaml:"RequireLower"`
+	RequireDigit  bool `yaml:"RequireDigit"`
+	RequireSymbol bool `yaml:"RequireSymbol"`
+	HistorySize   int  `yaml:"HistorySize"` // the last N passwords, including the current one, can't be reused
+}
+
+// LoginGuard delays the login attempts after failures, progressively, and locks the user name or the client ip
+// out after too many failures, the zero values mean the defaults.
+type LoginGuard struct {
+	MaxFailures          int   `yaml:"MaxFailures"`   // failures of a user name before the lockout
+	IPMaxFailures        int   `yaml:"IPMaxFailures"` // failures from a client ip before the lockout
+	DelaySeconds         int64 `yaml:"DelaySeconds"`  // the delay after the first failure, doubled by every failure
+	MaxDelaySeconds      int64 `yaml:"MaxDelaySeconds"`
+	LockoutSeconds       int64 `yaml:"LockoutSeconds"`
+	FailureWindowSeconds int64 `yaml:"FailureWindowSeconds"` // the failures are forgotten after a quiet window
+}
+
+// Registration controls the servicer registration, the invitations created by the user managers preset the acts,
+// the bizs and the role of the registered servicers.
+type Registration struct {
+	DisableOpen          bool  `yaml:"DisableOpen"`          // the registrations without invitation codes are rejected
+	InvitationTTLSeconds int64 `yaml:"InvitationTTLSeconds"` // the max lifetime of the invitations, 7 days by default
+}
+
+// Bootstrap creates the initial owner of the servicers on the first run, when there is no servicer yet,
+// the password is generated if it's empty and written to PasswordFile with the mode 0600, PasswordFile is
+// required then. Nothing is created if UserName is empty. The owner must change the password on the first login.
+type Bootstrap struct {
+	UserName     string   `yaml:"UserName"`
+	Password     string   `yaml:"Password"`
+	PasswordFile string   `yaml:"PasswordFile"`
+	ActIDs       []string `yaml:"ActIDs"`
+	BizIDs       []string `yaml:"BizIDs"`
+}
+
+// TOTP configures the two-factor authentication of the servicers, the secrets are encrypted by a key derived
+// from the servicer password secret.
+type TOTP struct {
+	Issuer string `yaml:"Issuer"` // shown by the authenticator apps, "talk" by default
+}
+
+type CustomerIdentityAlg string
+
+const (
+	CustomerIdentityAlgHS256 CustomerIdentityAlg = "HS256"
+	CustomerIdentityAlgRS256 CustomerIdentityAlg = "RS256"
+)
+
+type CustomerIdentity struct {
+	Alg       CustomerIdentityAlg `yaml:"Alg"`
+	Secret    string              `yaml:"Secret"`    // the shared secret for HS256
+	PublicKey string              `yaml:"PublicKey"` // the PEM encoded public key for RS256
+	Required  bool                `yaml:"Required"`  // the anonymous customers are rejected in the act
+}
+
+type Image struct {
+	MaxSizeBytes     int64    `yaml:"MaxSizeBytes"`
+	Formats          []string `yaml:"Formats"` // image.DecodeConfig format names
+	ThumbnailMaxEdge int      `yaml:"ThumbnailMaxEdge"`
+	MaxPixels        int64    `yaml:"MaxPixels"` // width x height, checked before decoding
+}
+
+// MessageRevision limits how long after sending a message the sender can recall or edit it,
+// zero means the default window and a negative value disables it.
+type MessageRevision struct {
+	RecallWindowSeconds int64 `yaml:"RecallWindowSeconds"`
+	EditWindowSeconds   int64 `yaml:"EditWindowSeconds"`
+}
+
+type RedactionMode string
+
+const (
+	RedactionModeStorage  RedactionMode = "storage"  // the stored text is masked
+	RedactionModeServicer RedactionMode = "servicer" // the text is masked for servicers only
+	RedactionModeEncrypt  RedactionMode = "encrypt"  // the stored text is masked, the original is kept encrypted for admins
+)
+
+type RedactionRule struct {
+	Name    string `yaml:"Name"`
+	Pattern string `yaml:"Pattern"`
+	Luhn    bool   `yaml:"Luhn"` // the digits of a match must pass the Luhn check, for card numbers
+}
+
+type RedactionPolicy struct {
+	Mode  RedactionMode `yaml:"Mode"`  // empty disables the redaction
+	Rules []string      `yaml:"Rules"` // rule names, empty means all rules
+}
+
+// Redaction masks sensitive data in incoming text messages, the built-in card, phone and id number
+// rules are used if no rule is configured.
+type Redaction struct {
+	Rules      []RedactionRule            `yaml:"Rules"`
+	Default    RedactionPolicy            `yaml:"Default"`
+	ActIDs     map[string]RedactionPolicy `yaml:"ActIDs"`
+	EncryptKey string                     `yaml:"EncryptKey"` // hex encoded AES-256 key
+}
+
+func (redaction *Redaction) GetPolicy(actID string) RedactionPolicy {
+	if policy, ok := redaction.ActIDs[actID]; ok {
+		return policy
+	}
+
+	return redaction.Default
+}
+
+type ModerationType string
+
+const (
+	ModerationTypeWordList ModerationType = "wordList"
+	ModerationTypeHTTP     ModerationType = "http"
+)
+
+// Moderation checks incoming messages, the word list is used if Type is empty, no word allows all messages.
+// The http moderator posts a defs.ModerationRequest to URL and expects a defs.ModerationResult.
+type Moderation struct {
+	Type ModerationType `yaml:"Type"`
+
+	RejectWords []string `yaml:"RejectWords"`
+	FlagWords   []string `yaml:"FlagWords"`
+	MaskWords   []string `yaml:"MaskWords"`
+
+	URL            string `yaml:"URL"`
+	TimeoutSeconds int64  `yaml:"TimeoutSeconds"`
+	FailOpen       bool   `yaml:"FailOpen"` // allow the messages if the http moderator fails
+}
+
+// RateLimitBucket is a token bucket, zero means the default and a negative rate disables it.
+type RateLimitBucket struct {
+	PerSecond float64 `yaml:"PerSecond"`
+	Burst     int     `yaml:"Burst"`
+}
+
+type RateLimitPolicy struct {
+	User RateLimitBucket `yaml:"User"` // per customer or servicer
+	Talk RateLimitBucket `yaml:"Talk"` // per talk, the customer and servicer sides are limited separately
+}
+
+// RateLimit limits the incoming messages, a stream is kicked out after KickViolations rejected messages
+// in ViolationWindowSeconds, zero means the default and a negative value disables the kick.
+type RateLimit struct {
+	Default                RateLimitPolicy            `yaml:"Default"`
+	ActIDs                 map[string]RateLimitPolicy `yaml:"ActIDs"`
+	KickViolations         int                        `yaml:"KickViolations"`
+	ViolationWindowSeconds int64                      `yaml:"ViolationWindowSeconds"`
+}
+
+func (rateLimit *RateLimit) GetPolicy(actID string) RateLimitPolicy {
+	if policy, ok := rateLimit.ActIDs[actID]; ok {
+		return policy
+	}
+
+	return rateLimit.Default
+}
+
+type SLAThreshold struct {
+	FirstResponseSeconds int64 `yaml:"FirstResponseSeconds"`
+	HandleSeconds        int64 `yaml:"HandleSeconds"`
+}
+
+type SLA struct {
+	Default     SLAThreshold            `yaml:"Default"`
+	BizIDs      map[string]SLAThreshold `yaml:"BizIDs"`
+	WebhookURLs []string                `yaml:"WebhookURLs"`
+}
+
+func (sla *SLA) GetThreshold(bizID string) SLAThreshold {
+	if threshold, ok := sla.BizIDs[bizID]; ok {
+		return threshold
+	}
+
+	return sla.Default
+}
+
 type Dev struct {
 	UseMemoryModel           bool `yaml:"UseMemoryModel"`
 	RabbitMQUseSharedChannel bool `yaml:"RabbitMQUseSharedChannel"`
@@ -56,3 +307,35 @@ func GetConfig() *Config {
 
 	return &_cfg
 }
+
+// ReloadConfig reads config.yaml again into a new config, the one of GetConfig is kept.
+func ReloadConfig() (*Config, error) {
+	cfg := &Config{
+		Logger: GetConfig().Logger,
+	}
+
+	if _, err := libconfig.Load("config.yaml", cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// WatchReload calls fn with the reloaded config on every SIGHUP.
+func WatchReload(fn func(cfg *Config)) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	go func() {
+		for range ch {
+			cfg, err := ReloadConfig()
+			if err != nil {
+				GetConfig().Logger.WithFields(l.ErrorField(err)).Error("ReloadConfigFailed")
+
+				continue
+			}
+
+			fn(cfg)
+		}
+	}()
+}
@@ -19,6 +19,38 @@ type WSConfig struct {
 	ServicerListen               string                          `yaml:"ServicerListen"`
 	ServicerGRPCClientConfig     *clienttoolset.GRPCClientConfig `yaml:"ServicerGRPCClientConfig"`
 	ServicerUserGRPCClientConfig *clienttoolset.GRPCClientConfig `yaml:"ServicerUserGRPCClientConfig"`
+	ServicerAPIURL               string                          `yaml:"ServicerAPIURL"`
+
+	// TrustedProxies are the ips or the cidrs of the reverse proxies whose forwarded client ips are honored,
+	// the loopback addresses if empty.
+	TrustedProxies []string `yaml:"TrustedProxies"`
+
+	Attachment Attachment `yaml:"Attachment"`
+}
+
+type BlobStoreType string
+
+const (
+	BlobStoreTypeLocal BlobStoreType = "local"
+	BlobStoreTypeS3    BlobStoreType = "s3"
+)
+
+type S3BlobStore struct {
+	Endpoint  string `yaml:"Endpoint"`
+	Region    string `yaml:"Region"`
+	Bucket    string `yaml:"Bucket"`
+	AccessKey string `yaml:"AccessKey"`
+	SecretKey string `yaml:"SecretKey"`
+}
+
+type Attachment struct {
+	Store        BlobStoreType `yaml:"Store"` // empty disables attachments
+	LocalDir     string        `yaml:"LocalDir"`
+	S3           S3BlobStore   `yaml:"S3"`
+	MaxSizeBytes int64         `yaml:"MaxSizeBytes"`
+	// LinkSecret signs the download links, the gateways serving the same attachments share it,
+	// a random one is used if it's empty.
+	LinkSecret string `yaml:"LinkSecret"`
 }
 
 var (
@@ -4,6 +4,7 @@ go 1.19
 
 require (
 	github.com/godruoyi/go-snowflake v0.0.1
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/gorilla/websocket v1.5.0
 	github.com/sbasestarter/bizinters v0.0.3
 	github.com/sbasestarter/bizmongolib v0.0.3
@@ -1,6 +1,11 @@
 package controller
 
+import "time"
+
 const (
 	defMaxCache        = 10
 	defMaxMessageCache = 100
+
+	slaCheckDuration      = time.Second * 30
+	queuePositionDuration = time.Second * 30
 )
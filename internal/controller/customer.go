@@ -6,7 +6,8 @@ import (
 	"github.com/zservicer/talkbe/internal/defs"
 )
 
-func NewCustomer(actID, bizID string, uniqueID uint64, talkID string, createTalkFlag bool, userID uint64, chSendMessage chan *talkpb.TalkResponse) defs.Customer {
+func NewCustomer(actID, bizID string, uniqueID uint64, talkID string, createTalkFlag bool, userID uint64, userName string,
+	chSendMessage chan *talkpb.TalkResponse) defs.Customer {
 	return &customerImpl{
 		actID:          actID,
 		bizID:          bizID,
@@ -14,6 +15,7 @@ func NewCustomer(actID, bizID string, uniqueID uint64, talkID string, createTalk
 		talkID:         talkID,
 		createTalkFlag: createTalkFlag,
 		userID:         userID,
+		userName:       userName,
 		chSendMessage:  chSendMessage,
 	}
 }
@@ -26,6 +28,7 @@ type customerImpl struct {
 	talkID         string
 	createTalkFlag bool
 	userID         uint64
+	userName       string
 	chSendMessage  chan *talkpb.TalkResponse
 }
 
@@ -53,6 +56,10 @@ func (impl *customerImpl) GetUserID() uint64 {
 	return impl.userID
 }
 
+func (impl *customerImpl) GetUserName() string {
+	return impl.userName
+}
+
 func (impl *customerImpl) SendMessage(msg *talkpb.TalkResponse) error {
 	select {
 	case impl.chSendMessage <- msg:
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/commerr"
@@ -36,6 +37,7 @@ func NewCustomerControllerEx(maxCache, maxMessageCache int, md defs.CustomerMD,
 		chUninstallCustomer: make(chan defs.Customer, maxCache),
 		chCustomerClose:     make(chan defs.Customer, maxCache),
 		chCustomerMessage:   make(chan *customerMessage, maxMessageCache),
+		chMessageRevision:   make(chan *messageRevision, maxMessageCache),
 		chMainRoutineRunner: make(chan func(), maxMessageCache),
 	}
 
@@ -45,9 +47,15 @@ func NewCustomerControllerEx(maxCache, maxMessageCache int, md defs.CustomerMD,
 }
 
 type customerMessage struct {
-	customer defs.Customer
-	seqID    uint64
-	message  *talkinters.TalkMessageW
+	customer  defs.Customer
+	seqID     uint64
+	messageID string
+	message   *talkinters.TalkMessageW
+}
+
+type messageRevision struct {
+	talkID   string
+	revision *defs.MessageRevision
 }
 
 type CustomerController struct {
@@ -60,6 +68,7 @@ type CustomerController struct {
 	chUninstallCustomer chan defs.Customer
 	chCustomerMessage   chan *customerMessage
 	chCustomerClose     chan defs.Customer
+	chMessageRevision   chan *messageRevision
 
 	chMainRoutineRunner chan func()
 }
@@ -116,7 +125,7 @@ func (c *CustomerController) CustomerClose(customer defs.Customer) error {
 	return nil
 }
 
-func (c *CustomerController) CustomerMessageIncoming(customer defs.Customer, seqID uint64,
+func (c *CustomerController) CustomerMessageIncoming(customer defs.Customer, seqID uint64, messageID string,
 	message *talkinters.TalkMessageW) error {
 	if customer == nil || message == nil {
 		return commerr.ErrInvalidArgument
@@ -124,9 +133,27 @@ func (c *CustomerController) CustomerMessageIncoming(customer defs.Customer, seq
 
 	select {
 	case c.chCustomerMessage <- &customerMessage{
-		customer: customer,
-		seqID:    seqID,
-		message:  message,
+		customer:  customer,
+		seqID:     seqID,
+		messageID: messageID,
+		message:   message,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *CustomerController) CustomerMessageRevised(talkID string, revision *defs.MessageRevision) error {
+	if talkID == "" || revision == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chMessageRevision <- &messageRevision{
+		talkID:   talkID,
+		revision: revision,
 	}:
 	default:
 		return commerr.ErrCanceled
@@ -148,16 +175,23 @@ func (c *CustomerController) mainRoutine(ctx context.Context, exiting func() boo
 
 	md := c.md
 
+	queuePositionTicker := time.NewTicker(queuePositionDuration)
+	defer queuePositionTicker.Stop()
+
 	for !exiting() {
 		select {
 		case <-ctx.Done():
 			continue
+		case <-queuePositionTicker.C:
+			md.RefreshQueuePositions(ctx)
 		case customer := <-c.chInstallCustomer:
 			md.InstallCustomer(ctx, customer)
 		case customer := <-c.chUninstallCustomer:
 			md.UninstallCustomer(ctx, customer)
 		case msgD := <-c.chCustomerMessage:
-			md.CustomerMessageIncoming(ctx, msgD.customer, msgD.seqID, msgD.message)
+			md.CustomerMessageIncoming(ctx, msgD.customer, msgD.seqID, msgD.messageID, msgD.message)
+		case mr := <-c.chMessageRevision:
+			md.MessageRevised(ctx, mr.talkID, mr.revision)
 		case customer := <-c.chCustomerClose:
 			md.CustomerClose(ctx, customer)
 		case runner := <-c.chMainRoutineRunner:
@@ -6,33 +6,48 @@ import (
 	"github.com/zservicer/talkbe/internal/defs"
 )
 
-func NewServicer(userID, uniqueID uint64, chSendMessage chan *talkpb.ServiceResponse, actIDs, bizIDs []string) defs.Servicer {
+func NewServicer(userID uint64, userName string, uniqueID uint64, sessionID string, chSendMessage chan *talkpb.ServiceResponse,
+	actIDs, bizIDs []string, roles defs.ServicerRoles) defs.Servicer {
 	return &servicerImpl{
 		userID:        userID,
+		userName:      userName,
 		uniqueID:      uniqueID,
+		sessionID:     sessionID,
 		chSendMessage: chSendMessage,
 		actIDs:        actIDs,
 		bizIDs:        bizIDs,
+		roles:         roles,
 	}
 }
 
 type servicerImpl struct {
 	userID        uint64
+	userName      string
 	uniqueID      uint64
+	sessionID     string
 	chSendMessage chan *talkpb.ServiceResponse
 
 	actIDs []string
 	bizIDs []string
+	roles  defs.ServicerRoles
 }
 
 func (impl *servicerImpl) GetUserID() uint64 {
 	return impl.userID
 }
 
+func (impl *servicerImpl) GetUserName() string {
+	return impl.userName
+}
+
 func (impl *servicerImpl) GetUniqueID() uint64 {
 	return impl.uniqueID
 }
 
+func (impl *servicerImpl) GetSessionID() string {
+	return impl.sessionID
+}
+
 func (impl *servicerImpl) SendMessage(msg *talkpb.ServiceResponse) error {
 	select {
 	case impl.chSendMessage <- msg:
@@ -61,3 +76,7 @@ func (impl *servicerImpl) GetActIDs() []string {
 func (impl *servicerImpl) GetBizIDs() []string {
 	return impl.bizIDs
 }
+
+func (impl *servicerImpl) GetRoles() defs.ServicerRoles {
+	return impl.roles
+}
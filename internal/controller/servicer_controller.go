@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/commerr"
@@ -40,6 +41,13 @@ func NewServicerControllerEx(maxCache, maxMessageCache int, md defs.ServicerMD,
 		chServicerQueryPendingTalks:  make(chan defs.Servicer),
 		chServicerReloadTalk:         make(chan *servicerWithTalk, maxCache),
 		chServicerMessage:            make(chan *servicerMessage, maxMessageCache),
+		chServicerWatchTalk:          make(chan *servicerWatchTalk, maxCache),
+		chServicerWhisper:            make(chan *servicerWhisper, maxMessageCache),
+		chServicerTalkParticipant:    make(chan *servicerTalkParticipant, maxCache),
+		chServicerCloseTalk:          make(chan *servicerCloseTalk, maxCache),
+		chMessageRevision:            make(chan *messageRevision, maxMessageCache),
+		chCustomerBan:                make(chan *defs.CustomerBan, maxCache),
+		chServicerKick:               make(chan *servicerKick, maxCache),
 		chMainRoutineRunner:          make(chan func(), maxMessageCache),
 	}
 
@@ -49,10 +57,11 @@ func NewServicerControllerEx(maxCache, maxMessageCache int, md defs.ServicerMD,
 }
 
 type servicerMessage struct {
-	servicer defs.Servicer
-	seqID    uint64
-	talkID   string
-	message  *talkinters.TalkMessageW
+	servicer  defs.Servicer
+	seqID     uint64
+	talkID    string
+	messageID string
+	message   *talkinters.TalkMessageW
 }
 
 type servicerWithTalk struct {
@@ -60,6 +69,35 @@ type servicerWithTalk struct {
 	servicer defs.Servicer
 }
 
+type servicerWatchTalk struct {
+	talkID     string
+	servicerID uint64
+	watch      bool
+}
+
+type servicerWhisper struct {
+	talkID  string
+	whisper *defs.WhisperMessage
+}
+
+type servicerTalkParticipant struct {
+	talkID     string
+	servicerID uint64
+	join       bool
+}
+
+type servicerCloseTalk struct {
+	talkID       string
+	servicerID   uint64
+	servicerName string
+}
+
+type servicerKick struct {
+	servicerID uint64
+	sessionID  string
+	reason     string
+}
+
 type ServicerController struct {
 	md         defs.ServicerMD
 	m          defs.ModelEx
@@ -74,6 +112,13 @@ type ServicerController struct {
 	chServicerQueryPendingTalks  chan defs.Servicer
 	chServicerReloadTalk         chan *servicerWithTalk
 	chServicerMessage            chan *servicerMessage
+	chServicerWatchTalk          chan *servicerWatchTalk
+	chServicerWhisper            chan *servicerWhisper
+	chServicerTalkParticipant    chan *servicerTalkParticipant
+	chServicerCloseTalk          chan *servicerCloseTalk
+	chMessageRevision            chan *messageRevision
+	chCustomerBan                chan *defs.CustomerBan
+	chServicerKick               chan *servicerKick
 	chMainRoutineRunner          chan func()
 }
 
@@ -194,7 +239,7 @@ func (c *ServicerController) ServicerReloadTalk(servicer defs.Servicer, talkID s
 	return nil
 }
 
-func (c *ServicerController) ServicerMessageIncoming(servicer defs.Servicer, seqID uint64, talkID string,
+func (c *ServicerController) ServicerMessageIncoming(servicer defs.Servicer, seqID uint64, talkID, messageID string,
 	message *talkinters.TalkMessageW) error {
 	if servicer == nil || talkID == "" || message == nil {
 		return commerr.ErrInvalidArgument
@@ -202,10 +247,134 @@ func (c *ServicerController) ServicerMessageIncoming(servicer defs.Servicer, seq
 
 	select {
 	case c.chServicerMessage <- &servicerMessage{
-		servicer: servicer,
-		seqID:    seqID,
+		servicer:  servicer,
+		seqID:     seqID,
+		talkID:    talkID,
+		messageID: messageID,
+		message:   message,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *ServicerController) ServicerWatchTalk(servicerID uint64, talkID string, watch bool) error {
+	if servicerID == 0 || talkID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chServicerWatchTalk <- &servicerWatchTalk{
+		talkID:     talkID,
+		servicerID: servicerID,
+		watch:      watch,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *ServicerController) ServicerWhisper(talkID string, whisper *defs.WhisperMessage) error {
+	if talkID == "" || whisper == nil || whisper.Text == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chServicerWhisper <- &servicerWhisper{
+		talkID:  talkID,
+		whisper: whisper,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *ServicerController) ServicerTalkParticipant(talkID string, servicerID uint64, join bool) error {
+	if talkID == "" || servicerID == 0 {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chServicerTalkParticipant <- &servicerTalkParticipant{
+		talkID:     talkID,
+		servicerID: servicerID,
+		join:       join,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *ServicerController) ServicerCloseTalk(talkID string, servicerID uint64, servicerName string) error {
+	if talkID == "" || servicerID == 0 {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chServicerCloseTalk <- &servicerCloseTalk{
+		talkID:       talkID,
+		servicerID:   servicerID,
+		servicerName: servicerName,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+func (c *ServicerController) ServicerMessageRevised(talkID string, revision *defs.MessageRevision) error {
+	if talkID == "" || revision == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chMessageRevision <- &messageRevision{
 		talkID:   talkID,
-		message:  message,
+		revision: revision,
+	}:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+// CustomerBanned kicks out the live streams of the banned customer.
+func (c *ServicerController) CustomerBanned(ban *defs.CustomerBan) error {
+	if ban == nil || ban.ActID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chCustomerBan <- ban:
+	default:
+		return commerr.ErrCanceled
+	}
+
+	return nil
+}
+
+// KickServicer kicks out the live streams of a servicer, e.g. a disabled one, or only the ones of a session if
+// the session id isn't empty.
+func (c *ServicerController) KickServicer(servicerID uint64, sessionID, reason string) error {
+	if servicerID == 0 {
+		return commerr.ErrInvalidArgument
+	}
+
+	select {
+	case c.chServicerKick <- &servicerKick{
+		servicerID: servicerID,
+		sessionID:  sessionID,
+		reason:     reason,
 	}:
 	default:
 		return commerr.ErrCanceled
@@ -227,10 +396,15 @@ func (c *ServicerController) mainRoutine(ctx context.Context, exiting func() boo
 
 	md := c.md
 
+	slaCheckTicker := time.NewTicker(slaCheckDuration)
+	defer slaCheckTicker.Stop()
+
 	for !exiting() {
 		select {
 		case <-ctx.Done():
 			continue
+		case <-slaCheckTicker.C:
+			md.CheckSLA(ctx)
 		case servicer := <-c.chInstallServicer:
 			md.InstallServicer(ctx, servicer)
 		case servicer := <-c.chUninstallServicer:
@@ -246,7 +420,21 @@ func (c *ServicerController) mainRoutine(ctx context.Context, exiting func() boo
 		case at := <-c.chServicerReloadTalk:
 			md.ServicerReloadTalk(ctx, at.servicer, at.talkID)
 		case msgD := <-c.chServicerMessage:
-			md.ServiceMessage(ctx, msgD.servicer, msgD.talkID, msgD.seqID, msgD.message)
+			md.ServiceMessage(ctx, msgD.servicer, msgD.talkID, msgD.seqID, msgD.messageID, msgD.message)
+		case mr := <-c.chMessageRevision:
+			md.MessageRevised(ctx, mr.talkID, mr.revision)
+		case wt := <-c.chServicerWatchTalk:
+			md.ServicerWatchTalk(ctx, wt.talkID, wt.servicerID, wt.watch)
+		case w := <-c.chServicerWhisper:
+			md.ServicerWhisper(ctx, w.talkID, w.whisper)
+		case tp := <-c.chServicerTalkParticipant:
+			md.ServicerTalkParticipant(ctx, tp.talkID, tp.servicerID, tp.join)
+		case ct := <-c.chServicerCloseTalk:
+			md.ServicerCloseTalk(ctx, ct.talkID, ct.servicerID, ct.servicerName)
+		case ban := <-c.chCustomerBan:
+			md.CustomerBanned(ctx, ban)
+		case kick := <-c.chServicerKick:
+			md.KickServicer(ctx, kick.servicerID, kick.sessionID, kick.reason)
 		case runner := <-c.chMainRoutineRunner:
 			runner()
 		}
@@ -0,0 +1,26 @@
+package defs
+
+import (
+	"context"
+	"io"
+)
+
+type BlobInfo struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, info *BlobInfo, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FileRef references an uploaded attachment, the key is prefixed with the talk id it was uploaded to.
+type FileRef struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+}
@@ -0,0 +1,40 @@
+package defs
+
+import "context"
+
+const (
+	AuditActionCustomerBan            = "customerBan"
+	AuditActionCustomerUnban          = "customerUnban"
+	AuditActionServicerDisable        = "servicerDisable"
+	AuditActionServicerEnable         = "servicerEnable"
+	AuditActionServicerDelete         = "servicerDelete"
+	AuditActionServicerPasswordReset  = "servicerPasswordReset"
+	AuditActionServicerPasswordChange = "servicerPasswordChange"
+	AuditActionServicerLockout        = "servicerLockout"
+	AuditActionServicerUnlock         = "servicerUnlock"
+	AuditActionServicerRoleSet        = "servicerRoleSet"
+	AuditActionServicerSessionRevoke  = "servicerSessionRevoke"
+	AuditActionServicerSessionsRevoke = "servicerSessionsRevoke"
+	AuditActionServicerTOTPEnable     = "servicerTOTPEnable"
+	AuditActionServicerTOTPDisable    = "servicerTOTPDisable"
+	AuditActionServicerTOTPReset      = "servicerTOTPReset"
+	AuditActionTwoFactorRequire       = "twoFactorRequire"
+	AuditActionInvitationCreate       = "invitationCreate"
+	AuditActionInvitationRevoke       = "invitationRevoke"
+)
+
+type AuditLog struct {
+	ID               string `bson:"_id" json:"id"`
+	At               int64  `bson:"At" json:"at"`
+	ActID            string `bson:"ActID" json:"actID,omitempty"`
+	OperatorID       uint64 `bson:"OperatorID" json:"operatorID,omitempty"`
+	OperatorUserName string `bson:"OperatorUserName" json:"operatorUserName,omitempty"`
+	Action           string `bson:"Action" json:"action"`
+	Target           string `bson:"Target" json:"target,omitempty"`
+	Detail           string `bson:"Detail" json:"detail,omitempty"`
+}
+
+type AuditLogModel interface {
+	AddAuditLog(ctx context.Context, log *AuditLog) error
+	QueryAuditLogs(ctx context.Context, actIDs []string, startAt, finishAt int64) ([]*AuditLog, error)
+}
@@ -0,0 +1,45 @@
+package defs
+
+import "context"
+
+// CustomerBan bans a customer user id, or an anonymous identity by its user name if UserID is zero,
+// in the act. The ban is active until ExpireAt, zero means forever, or it's revoked.
+type CustomerBan struct {
+	ID       string `bson:"_id" json:"id"`
+	ActID    string `bson:"ActID" json:"actID"`
+	UserID   uint64 `bson:"UserID" json:"userID,omitempty"`
+	UserName string `bson:"UserName" json:"userName,omitempty"`
+	Reason   string `bson:"Reason" json:"reason,omitempty"`
+
+	CreatedAt int64  `bson:"CreatedAt" json:"createdAt"`
+	ExpireAt  int64  `bson:"ExpireAt" json:"expireAt,omitempty"`
+	CreatorID uint64 `bson:"CreatorID" json:"creatorID"`
+	RevokedAt int64  `bson:"RevokedAt" json:"revokedAt,omitempty"`
+	RevokerID uint64 `bson:"RevokerID" json:"revokerID,omitempty"`
+}
+
+func (ban *CustomerBan) Active(now int64) bool {
+	return ban.RevokedAt == 0 && (ban.ExpireAt == 0 || ban.ExpireAt > now)
+}
+
+func (ban *CustomerBan) Match(userID uint64, userName, actID string) bool {
+	if ban.ActID != actID {
+		return false
+	}
+
+	if ban.UserID != 0 {
+		return ban.UserID == userID
+	}
+
+	return ban.UserName == userName
+}
+
+type CustomerBanModel interface {
+	AddCustomerBan(ctx context.Context, ban *CustomerBan) error
+	GetCustomerBan(ctx context.Context, id string) (*CustomerBan, error)
+	RevokeCustomerBan(ctx context.Context, id string, at int64, revokerID uint64) error
+	// QueryCustomerBans returns the bans of the acts, only the active ones at activeAt if it's not zero.
+	QueryCustomerBans(ctx context.Context, actIDs []string, activeAt int64) ([]*CustomerBan, error)
+	// FindActiveCustomerBan returns commerr.ErrNotFound if the customer isn't banned.
+	FindActiveCustomerBan(ctx context.Context, userID uint64, userName, actID string, now int64) (*CustomerBan, error)
+}
@@ -9,6 +9,7 @@ type Customer interface {
 	GetUniqueID() uint64
 	GetTalkID() string
 	GetUserID() uint64
+	GetUserName() string
 	SendMessage(msg *talkpb.TalkResponse) error
 	Remove(msg string)
 
@@ -0,0 +1,17 @@
+package defs
+
+// CustomerIdentity is a customer identity signed by a host app, the external id is bound to the customer token,
+// so the customer gets the same user id on all devices.
+type CustomerIdentity struct {
+	ExternalID string
+	UserName   string
+	ActID      string
+	BizID      string
+}
+
+type CustomerIdentityVerifier interface {
+	// Verify checks the identity token signed with the key of its act.
+	Verify(identityToken string) (*CustomerIdentity, error)
+	// Required returns true if the anonymous customers are rejected in the act.
+	Required(actID string) bool
+}
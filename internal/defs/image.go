@@ -0,0 +1,22 @@
+package defs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+)
+
+var (
+	ErrImageInvalid       = fmt.Errorf("%w: imageInvalid", commerr.ErrInvalidArgument)
+	ErrImageTooLarge      = fmt.Errorf("%w: imageTooLarge", commerr.ErrInvalidArgument)
+	ErrImageTooManyPixels = fmt.Errorf("%w: imageTooManyPixels", commerr.ErrInvalidArgument)
+	ErrImageFormat        = fmt.Errorf("%w: imageFormatNotAllowed", commerr.ErrInvalidArgument)
+)
+
+type ImageProcessor interface {
+	// Process validates an image message, the image is replaced by its thumbnail and the
+	// key of the stored original is kept in the text.
+	Process(ctx context.Context, talkID string, message *talkinters.TalkMessageW) error
+}
@@ -0,0 +1,55 @@
+package defs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+)
+
+var (
+	ErrInvitationRequired = fmt.Errorf("%w: invitationRequired", commerr.ErrPermissionDenied)
+	ErrInvitationInvalid  = fmt.Errorf("%w: invitationInvalid", commerr.ErrPermissionDenied)
+)
+
+// ServicerInvitation presets the acts, the bizs and the role of a servicer registered with it, it's single-use.
+type ServicerInvitation struct {
+	ID              string       `bson:"_id" json:"id"` // the hash of the code, the code is shown only on the creation
+	ActIDs          []string     `bson:"ActIDs" json:"actIDs"`
+	BizIDs          []string     `bson:"BizIDs" json:"bizIDs"`
+	Role            ServicerRole `bson:"Role" json:"role"` // granted in all the acts
+	CreatorID       uint64       `bson:"CreatorID" json:"creatorID"`
+	CreatorUserName string       `bson:"CreatorUserName" json:"creatorUserName"`
+	CreateAt        int64        `bson:"CreateAt" json:"createAt"`
+	ExpireAt        int64        `bson:"ExpireAt" json:"expireAt"`
+	UsedAt          int64        `bson:"UsedAt" json:"usedAt,omitempty"`
+	UsedBy          string       `bson:"UsedBy" json:"usedBy,omitempty"` // the registered user name
+}
+
+type ServicerInvitationModel interface {
+	AddInvitation(ctx context.Context, invitation *ServicerInvitation) error
+	GetInvitation(ctx context.Context, id string) (*ServicerInvitation, error)
+	// UseInvitation claims an unused and unexpired invitation for the user name atomically, it returns
+	// commerr.ErrNotFound if there is no such invitation.
+	UseInvitation(ctx context.Context, id, userName string, now int64) (*ServicerInvitation, error)
+	// ReleaseInvitation reverts the claim of a failed registration.
+	ReleaseInvitation(ctx context.Context, id string) error
+	// QueryInvitations returns the invitations for any of the actIDs, empty actIDs means all invitations.
+	QueryInvitations(ctx context.Context, actIDs []string) ([]*ServicerInvitation, error)
+	DeleteInvitation(ctx context.Context, id string) error
+}
+
+type ServicerInvitations interface {
+	// CreateInvitation fills the id and the times of the invitation, the ttl is limited by the configuration,
+	// the role is agent by default.
+	CreateInvitation(ctx context.Context, invitation *ServicerInvitation, ttl time.Duration) (code string, err error)
+	// UseInvitation returns ErrInvitationInvalid for the unknown, used or expired codes.
+	UseInvitation(ctx context.Context, code, userName string) (*ServicerInvitation, error)
+	ReleaseInvitation(ctx context.Context, invitation *ServicerInvitation) error
+	GetInvitation(ctx context.Context, id string) (*ServicerInvitation, error)
+	QueryInvitations(ctx context.Context, actIDs []string) ([]*ServicerInvitation, error)
+	RevokeInvitation(ctx context.Context, id string) error
+	// OpenRegistration returns false if the registrations must use invitations.
+	OpenRegistration() bool
+}
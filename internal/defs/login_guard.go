@@ -0,0 +1,29 @@
+package defs
+
+import (
+	"context"
+	"time"
+)
+
+// LoginFailures tracks the failed login attempts of a key, a user name or a client ip.
+type LoginFailures struct {
+	Key          string `bson:"_id" json:"key"`
+	Count        int    `bson:"Count" json:"count"`
+	LastFailedAt int64  `bson:"LastFailedAt" json:"lastFailedAt"`
+	LockedUntil  int64  `bson:"LockedUntil" json:"lockedUntil,omitempty"`
+}
+
+type LoginFailuresModel interface {
+	// GetLoginFailures returns commerr.ErrNotFound if there is no failure of the key.
+	GetLoginFailures(ctx context.Context, key string) (*LoginFailures, error)
+	SetLoginFailures(ctx context.Context, failures *LoginFailures) error
+	DeleteLoginFailures(ctx context.Context, key string) error
+}
+
+type LoginGuard interface {
+	// Check returns the duration to wait before the next attempt of the user name from the client ip.
+	Check(ctx context.Context, userName, ip string) (retryAfter time.Duration, err error)
+	Fail(ctx context.Context, userName, ip string) error
+	Succeed(ctx context.Context, userName, ip string) error
+	Unlock(ctx context.Context, userName string) error
+}
@@ -15,8 +15,10 @@ type CustomerMD interface {
 	InstallCustomer(ctx context.Context, customer Customer)
 	UninstallCustomer(ctx context.Context, customer Customer)
 	CustomerMessageIncoming(ctx context.Context, customer Customer,
-		seqID uint64, message *talkinters.TalkMessageW)
+		seqID uint64, messageID string, message *talkinters.TalkMessageW)
+	MessageRevised(ctx context.Context, talkID string, revision *MessageRevision)
 	CustomerClose(ctx context.Context, customer Customer)
+	RefreshQueuePositions(ctx context.Context)
 }
 
 type ServicerMD interface {
@@ -28,7 +30,16 @@ type ServicerMD interface {
 	ServicerQueryAttachedTalks(ctx context.Context, servicer Servicer)
 	ServicerQueryPendingTalks(ctx context.Context, servicer Servicer)
 	ServicerReloadTalk(ctx context.Context, servicer Servicer, talkID string)
-	ServiceMessage(ctx context.Context, servicer Servicer, talkID string, seqID uint64, message *talkinters.TalkMessageW)
+	ServiceMessage(ctx context.Context, servicer Servicer, talkID string, seqID uint64, messageID string,
+		message *talkinters.TalkMessageW)
+	MessageRevised(ctx context.Context, talkID string, revision *MessageRevision)
+	ServicerWatchTalk(ctx context.Context, talkID string, servicerID uint64, watch bool)
+	ServicerWhisper(ctx context.Context, talkID string, whisper *WhisperMessage)
+	ServicerTalkParticipant(ctx context.Context, talkID string, servicerID uint64, join bool)
+	ServicerCloseTalk(ctx context.Context, talkID string, servicerID uint64, servicerName string)
+	CustomerBanned(ctx context.Context, ban *CustomerBan)
+	KickServicer(ctx context.Context, servicerID uint64, sessionID, reason string)
+	CheckSLA(ctx context.Context)
 }
 
 type MD interface {
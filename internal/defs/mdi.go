@@ -9,6 +9,12 @@ import (
 type CustomerObserver interface {
 	OnMessageIncoming(senderUniqueID uint64, talkID string, message *talkinters.TalkMessageW)
 	OnTalkClose(talkID string)
+
+	OnServicerAttachMessage(talkID string, servicerID uint64)
+
+	OnMessageRevision(talkID string, revision *MessageRevision)
+
+	OnCustomerBan(ban *CustomerBan)
 }
 
 type ServicerObserver interface {
@@ -19,6 +25,16 @@ type ServicerObserver interface {
 
 	OnServicerAttachMessage(talkID string, servicerID uint64)
 	OnServicerDetachMessage(talkID string, servicerID uint64)
+
+	OnServicerWatchMessage(talkID string, servicerID uint64, watch bool)
+	OnWhisperMessage(talkID string, whisper *WhisperMessage)
+	OnServicerParticipantMessage(talkID string, servicerID uint64, join bool)
+
+	OnMessageRevision(talkID string, revision *MessageRevision)
+
+	OnServicerKick(servicerID uint64, sessionID, reason string)
+
+	OnSLABreach(breach *SLABreach)
 }
 
 type Observer interface {
@@ -35,6 +51,7 @@ type MDIBase interface {
 	RemoveTrackTalk(ctx context.Context, talkID string)
 
 	SendMessage(senderUniqueID uint64, talkID string, message *talkinters.TalkMessageW)
+	SendMessageRevision(talkID string, revision *MessageRevision)
 }
 
 type CustomerMDI interface {
@@ -49,6 +66,13 @@ type ServicerMDI interface {
 	SetServicerObserver(ob ServicerObserver)
 	SendServicerAttachMessage(talkID string, servicerID uint64)
 	SendServiceDetachMessage(talkID string, servicerID uint64)
+	SendServicerWatchMessage(talkID string, servicerID uint64, watch bool)
+	SendWhisperMessage(talkID string, whisper *WhisperMessage)
+	SendServicerParticipantMessage(talkID string, servicerID uint64, join bool)
+	SendCustomerBanMessage(ban *CustomerBan)
+	SendServicerKickMessage(servicerID uint64, sessionID, reason string)
+	SendTalkCloseMessage(talkID string)
+	SendSLABreachMessage(breach *SLABreach)
 }
 
 type MDI interface {
@@ -0,0 +1,131 @@
+package defs
+
+import "github.com/sbasestarter/bizinters/talkinters"
+
+// local message types, the payload is a json encoded RichMessage in TalkMessageW.Data.
+const (
+	TalkMessageTypeFile talkinters.TalkMessageType = iota + 100
+	TalkMessageTypeAudio
+	TalkMessageTypeLocation
+	TalkMessageTypeCard
+	TalkMessageTypeQuickReply
+	TalkMessageTypeReply
+	TalkMessageTypeQuote
+	TalkMessageTypeSystem
+	TalkMessageTypeRating
+)
+
+const (
+	RichMessageKindFile       = "file"
+	RichMessageKindAudio      = "audio"
+	RichMessageKindLocation   = "location"
+	RichMessageKindCard       = "card"
+	RichMessageKindQuickReply = "quickReply"
+	RichMessageKindReply      = "reply" // the customer tapped a button of a card or quick reply
+	RichMessageKindQuote      = "quote"
+	RichMessageKindRating     = "rating"   // written by the server for the rate command, can't be sent by users
+	RichMessageKindSystem     = "system"   // written by the server, can't be sent by users
+	RichMessageKindRecalled   = "recalled" // sent in place of a recalled message, can't be sent by users
+)
+
+type AudioMessage struct {
+	File            *FileRef `json:"file"`
+	DurationSeconds float64  `json:"durationSeconds"`
+}
+
+type LocationMessage struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+type ReplyButton struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"` // opened by the client instead of replying
+}
+
+type CardMessage struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	ImageURL    string         `json:"imageURL,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Buttons     []*ReplyButton `json:"buttons,omitempty"`
+}
+
+type QuickReplyMessage struct {
+	Text    string         `json:"text"`
+	Buttons []*ReplyButton `json:"buttons"`
+}
+
+// RatingMessage is the customer satisfaction score of the talk, from MinRatingScore to MaxRatingScore, the last
+// rating of a talk counts.
+type RatingMessage struct {
+	Score int `json:"score"`
+}
+
+const (
+	MinRatingScore = 1
+	MaxRatingScore = 5
+)
+
+// ReplyMessage refers the card or quick reply message by its id.
+type ReplyMessage struct {
+	MessageID string `json:"messageID"`
+	ButtonID  string `json:"buttonID"`
+	Title     string `json:"title"`
+}
+
+// QuoteMessage answers an earlier message of the same talk, the snippet of the quoted message is
+// filled by the server.
+type QuoteMessage struct {
+	MessageID             string `json:"messageID"`
+	Text                  string `json:"text"`
+	QuotedAt              int64  `json:"quotedAt,omitempty"`
+	QuotedCustomerMessage bool   `json:"quotedCustomerMessage,omitempty"`
+	Snippet               string `json:"snippet,omitempty"`
+}
+
+const (
+	SystemEventAttach   = "attach"
+	SystemEventDetach   = "detach"
+	SystemEventTransfer = "transfer"
+	SystemEventClose    = "close"
+)
+
+// SystemMessage records a talk event in the message history, the text is the narrative shown to users.
+type SystemMessage struct {
+	Event          string `json:"event"`
+	ServicerID     uint64 `json:"servicerID,omitempty"`
+	ServicerName   string `json:"servicerName,omitempty"`
+	FromServicerID uint64 `json:"fromServicerID,omitempty"` // the previous servicer of a transfer
+	ClosedBy       string `json:"closedBy,omitempty"`
+	Text           string `json:"text"`
+}
+
+// RichMessage travels in the text field of the pb message, prefixed with vo.RichMessagePrefix.
+type RichMessage struct {
+	Kind       string             `json:"kind"`
+	File       *FileRef           `json:"file,omitempty"`
+	Audio      *AudioMessage      `json:"audio,omitempty"`
+	Location   *LocationMessage   `json:"location,omitempty"`
+	Card       *CardMessage       `json:"card,omitempty"`
+	QuickReply *QuickReplyMessage `json:"quickReply,omitempty"`
+	Reply      *ReplyMessage      `json:"reply,omitempty"`
+	Quote      *QuoteMessage      `json:"quote,omitempty"`
+	System     *SystemMessage     `json:"system,omitempty"`
+	Rating     *RatingMessage     `json:"rating,omitempty"`
+}
+
+// Buttons returns the reply buttons of a card or quick reply.
+func (m *RichMessage) Buttons() []*ReplyButton {
+	switch {
+	case m.Card != nil:
+		return m.Card.Buttons
+	case m.QuickReply != nil:
+		return m.QuickReply.Buttons
+	default:
+		return nil
+	}
+}
@@ -13,4 +13,11 @@ type ModelEx interface {
 	GetTalkInfo(ctx context.Context, actIDs, bizIDs []string, talkID string) (*talkinters.TalkInfoR, error)
 	GetServicerTalkInfos(ctx context.Context, actIDs, bizIDs []string, servicerID uint64) ([]*talkinters.TalkInfoR, error)
 	GetTalkServicerID(ctx context.Context, actIDs, bizIDs []string, talkID string) (servicerID uint64, err error)
+
+	// AddTalkMessageWithID adds a message and returns the id used to recall or edit it.
+	AddTalkMessageWithID(ctx context.Context, talkID string, message *talkinters.TalkMessageW) (messageID string, err error)
+	GetTalkMessageEx(ctx context.Context, talkID, messageID string) (*TalkMessageEx, error)
+	GetTalkMessageExs(ctx context.Context, talkID string) ([]*TalkMessageEx, error)
+	RecallTalkMessage(ctx context.Context, talkID, messageID string, at int64) error
+	AddTalkMessageEdit(ctx context.Context, talkID, messageID string, edit *TalkMessageEdit) error
 }
@@ -0,0 +1,50 @@
+package defs
+
+import "context"
+
+type ModerationAction string
+
+const (
+	ModerationActionAllow  ModerationAction = "allow"
+	ModerationActionMask   ModerationAction = "mask"
+	ModerationActionReject ModerationAction = "reject"
+	ModerationActionFlag   ModerationAction = "flag"
+)
+
+type ModerationResult struct {
+	Action ModerationAction `json:"action"`
+	Text   string           `json:"text,omitempty"`   // the text to store instead of the original, empty keeps the original
+	Reason string           `json:"reason,omitempty"` // sent to the sender on reject, recorded on flag
+}
+
+type ModerationRequest struct {
+	TalkID          string `json:"talkID"`
+	SenderID        uint64 `json:"senderID"`
+	CustomerMessage bool   `json:"customerMessage"`
+	Text            string `json:"text"`
+}
+
+// Moderator checks the text of every incoming message before it's stored.
+type Moderator interface {
+	Moderate(ctx context.Context, request *ModerationRequest) (*ModerationResult, error)
+}
+
+// ModerationFlag records a flagged message for the supervisor review.
+type ModerationFlag struct {
+	ID              string `bson:"_id" json:"id"`
+	TalkID          string `bson:"TalkID" json:"talkID"`
+	ActID           string `bson:"ActID" json:"actID"`
+	BizID           string `bson:"BizID" json:"bizID"`
+	MessageID       string `bson:"MessageID" json:"messageID"`
+	At              int64  `bson:"At" json:"at"`
+	SenderID        uint64 `bson:"SenderID" json:"senderID"`
+	SenderUserName  string `bson:"SenderUserName" json:"senderUserName"`
+	CustomerMessage bool   `bson:"CustomerMessage" json:"customerMessage"`
+	Text            string `bson:"Text" json:"text"`
+	Reason          string `bson:"Reason" json:"reason"`
+}
+
+type ModerationFlagModel interface {
+	AddModerationFlag(ctx context.Context, flag *ModerationFlag) error
+	QueryModerationFlags(ctx context.Context, actIDs, bizIDs []string, startAt, finishAt int64) ([]*ModerationFlag, error)
+}
@@ -0,0 +1,16 @@
+package defs
+
+import "context"
+
+type TalkParticipants struct {
+	TalkID      string   `bson:"_id" json:"talkID"`
+	ServicerIDs []uint64 `bson:"ServicerIDs" json:"servicerIDs"`
+}
+
+// TalkParticipantModel stores the servicers invited to a talk besides the attached one.
+type TalkParticipantModel interface {
+	AddTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error
+	RemoveTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error
+	GetTalkParticipants(ctx context.Context, talkID string) ([]uint64, error)
+	GetServicerParticipantTalkIDs(ctx context.Context, servicerID uint64) ([]string, error)
+}
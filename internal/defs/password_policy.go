@@ -0,0 +1,26 @@
+package defs
+
+import (
+	"fmt"
+
+	"github.com/sgostarter/i/commerr"
+)
+
+var (
+	ErrPasswordTooShort    = fmt.Errorf("%w: passwordTooShort", commerr.ErrInvalidArgument)
+	ErrPasswordNeedsUpper  = fmt.Errorf("%w: passwordNeedsUpper", commerr.ErrInvalidArgument)
+	ErrPasswordNeedsLower  = fmt.Errorf("%w: passwordNeedsLower", commerr.ErrInvalidArgument)
+	ErrPasswordNeedsDigit  = fmt.Errorf("%w: passwordNeedsDigit", commerr.ErrInvalidArgument)
+	ErrPasswordNeedsSymbol = fmt.Errorf("%w: passwordNeedsSymbol", commerr.ErrInvalidArgument)
+	ErrPasswordReused      = fmt.Errorf("%w: passwordReused", commerr.ErrInvalidArgument)
+	ErrWrongPassword       = fmt.Errorf("%w: wrongPassword", commerr.ErrUnauthenticated)
+	// ErrPasswordChangeRequired rejects the tokens of the users who must change the password first.
+	ErrPasswordChangeRequired = fmt.Errorf("%w: passwordChangeRequired", commerr.ErrPermissionDenied)
+)
+
+type PasswordPolicy interface {
+	// Check returns one of the ErrPasswordXxx errors if the password breaks the policy.
+	Check(password string) error
+	// HistorySize returns the number of the last passwords which can't be reused.
+	HistorySize() int
+}
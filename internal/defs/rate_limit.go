@@ -0,0 +1,16 @@
+package defs
+
+import "context"
+
+type RateLimitDecision int
+
+const (
+	RateLimitAllow RateLimitDecision = iota
+	RateLimitReject
+	RateLimitKick // the message is rejected and the stream should be kicked out
+)
+
+type RateLimiter interface {
+	// Take takes a token from the buckets of the user and the talk for an incoming message.
+	Take(ctx context.Context, customer bool, userID uint64, talkID string) RateLimitDecision
+}
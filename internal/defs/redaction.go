@@ -0,0 +1,23 @@
+package defs
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+)
+
+// RedactedText is kept in TalkMessageW.Data of a redacted text message.
+type RedactedText struct {
+	Masked    string `json:"masked,omitempty"`    // shown to servicers, the text keeps the original
+	Encrypted []byte `json:"encrypted,omitempty"` // the original, the text is masked
+}
+
+type Redactor interface {
+	// Redact masks the sensitive data of a incoming text message by the policy of the talk, the free texts
+	// of rich messages are masked in storage.
+	Redact(ctx context.Context, talkID string, message *talkinters.TalkMessageW) error
+	// RedactText masks the text if the policy of the talk is enabled, it's used for the moderation.
+	RedactText(ctx context.Context, talkID, text string) string
+	// Reveal returns the original text of a message.
+	Reveal(message *talkinters.TalkMessageW) (string, error)
+}
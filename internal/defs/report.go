@@ -0,0 +1,46 @@
+package defs
+
+import "context"
+
+type ReportGroupKey string
+
+const (
+	ReportGroupKeyServicer ReportGroupKey = "servicer"
+	ReportGroupKeyBizID    ReportGroupKey = "bizID"
+	ReportGroupKeyDay      ReportGroupKey = "day"
+	ReportGroupKeyWeek     ReportGroupKey = "week"
+	ReportGroupKeyMonth    ReportGroupKey = "month"
+)
+
+type ReportRequest struct {
+	ActIDs   []string         `json:"actIDs"`
+	BizIDs   []string         `json:"bizIDs"`
+	StartAt  int64            `json:"startAt"`
+	FinishAt int64            `json:"finishAt"`
+	GroupBy  []ReportGroupKey `json:"groupBy"`
+}
+
+type ReportRow struct {
+	ServicerID uint64 `json:"servicerID,omitempty"`
+	BizID      string `json:"bizID,omitempty"`
+	Period     string `json:"period,omitempty"`
+
+	TalksCreated int64 `json:"talksCreated"`
+	TalksClosed  int64 `json:"talksClosed"`
+
+	AvgFirstResponseSeconds float64 `json:"avgFirstResponseSeconds"`
+	AvgHandleSeconds        float64 `json:"avgHandleSeconds"`
+	AvgCSAT                 float64 `json:"avgCSAT"` // the average rating of the rated talks
+	RatedTalks              int64   `json:"ratedTalks"`
+	TalksPerServicer        float64 `json:"talksPerServicer"`
+}
+
+type Report struct {
+	StartAt  int64        `json:"startAt"`
+	FinishAt int64        `json:"finishAt"`
+	Rows     []*ReportRow `json:"rows"`
+}
+
+type Reporter interface {
+	Report(ctx context.Context, request *ReportRequest) (*Report, error)
+}
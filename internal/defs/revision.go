@@ -0,0 +1,76 @@
+package defs
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+)
+
+// TalkMessageTypeRecalled replaces the content of a recalled message when it's loaded.
+const TalkMessageTypeRecalled talkinters.TalkMessageType = 200
+
+// TalkMessageEdit is redacted as a new text message, the data is the redaction kept in TalkMessageW.Data.
+type TalkMessageEdit struct {
+	At   int64  `bson:"At" json:"at"`
+	Text string `bson:"Text" json:"text"`
+	Data []byte `bson:"Data,omitempty" json:"data,omitempty"`
+}
+
+// TalkMessageEx keeps the id, the edit history and the recalled state of a talk message,
+// the talk model can't update stored messages, so they are matched by the sender, time and content digest.
+// The snippet and the reply buttons are kept to check the quotes and replies without loading the talk.
+type TalkMessageEx struct {
+	MessageID       string                     `bson:"_id" json:"messageID"`
+	TalkID          string                     `bson:"TalkID" json:"talkID"`
+	At              int64                      `bson:"At" json:"at"`
+	SenderID        uint64                     `bson:"SenderID" json:"senderID"`
+	CustomerMessage bool                       `bson:"CustomerMessage" json:"customerMessage"`
+	Type            talkinters.TalkMessageType `bson:"Type" json:"type"`
+	Digest          string                     `bson:"Digest" json:"-"`
+	RecalledAt      int64                      `bson:"RecalledAt" json:"recalledAt,omitempty"`
+	Edits           []*TalkMessageEdit         `bson:"Edits" json:"edits,omitempty"`
+	Snippet         string                     `bson:"Snippet,omitempty" json:"-"`
+	Buttons         []*ReplyButton             `bson:"Buttons,omitempty" json:"-"`
+}
+
+type TalkMessageExModel interface {
+	AddTalkMessageEx(ctx context.Context, messageEx *TalkMessageEx) error
+	RemoveTalkMessageEx(ctx context.Context, talkID, messageID string) error
+	GetTalkMessageEx(ctx context.Context, talkID, messageID string) (*TalkMessageEx, error)
+	// GetTalkMessageExs returns the records in the order of the messages.
+	GetTalkMessageExs(ctx context.Context, talkID string) ([]*TalkMessageEx, error)
+	RecallTalkMessage(ctx context.Context, talkID, messageID string, at int64) error
+	AddTalkMessageEdit(ctx context.Context, talkID, messageID string, edit *TalkMessageEdit) error
+}
+
+const (
+	MessageCommandRecall = "recall"
+	MessageCommandEdit   = "edit"
+	MessageCommandRate   = "rate" // the customer rates the talk, the rating message is written by the server
+)
+
+// MessageCommand asks to recall or edit a message of the sender, the message is referred by its id,
+// or by its time if the id is unknown. The rate command carries the score only.
+type MessageCommand struct {
+	Command   string `json:"command"`
+	MessageID string `json:"messageID,omitempty"`
+	At        int64  `json:"at,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Score     int    `json:"score,omitempty"`
+}
+
+// MessageRevision is broadcast to all connections of the talk after a message is recalled or edited.
+type MessageRevision struct {
+	TalkID          string           `json:"talkID"`
+	MessageID       string           `json:"messageID"`
+	At              int64            `json:"at"`
+	SenderID        uint64           `json:"senderID"`
+	CustomerMessage bool             `json:"customerMessage"`
+	RecalledAt      int64            `json:"recalledAt,omitempty"`
+	Edit            *TalkMessageEdit `json:"edit,omitempty"`
+}
+
+type MessageReviser interface {
+	Revise(ctx context.Context, talkID string, senderID uint64, customerMessage bool,
+		command *MessageCommand) (*MessageRevision, error)
+}
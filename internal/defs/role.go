@@ -0,0 +1,119 @@
+package defs
+
+import "golang.org/x/exp/slices"
+
+type ServicerRole string
+
+const (
+	ServicerRoleAgent      ServicerRole = "agent"
+	ServicerRoleSupervisor ServicerRole = "supervisor"
+	ServicerRoleAdmin      ServicerRole = "admin"
+	ServicerRoleOwner      ServicerRole = "owner"
+)
+
+type Permission string
+
+const (
+	PermissionAttach      Permission = "attach"      // attach the pending talks and talk to the customers
+	PermissionMonitor     Permission = "monitor"     // watch and whisper into the talks of the others
+	PermissionTransfer    Permission = "transfer"    // invite and remove the participants of the talks of the others
+	PermissionCloseOthers Permission = "closeOthers" // close the talks attached by the others
+	PermissionModerate    Permission = "moderate"    // ban the customers, read the flags and the original messages
+	PermissionReadReports Permission = "readReports" // the reports and the audit logs
+	PermissionExport      Permission = "export"      // export the talks
+	PermissionManageUsers Permission = "manageUsers" // the servicers, the invitations and the two-factor policies
+)
+
+// AllActs keys the role granted in all the acts of a servicer.
+const AllActs = "*"
+
+var rolePermissions = map[ServicerRole][]Permission{
+	ServicerRoleAgent: {PermissionAttach},
+	ServicerRoleSupervisor: {PermissionAttach, PermissionMonitor, PermissionTransfer, PermissionCloseOthers,
+		PermissionModerate, PermissionReadReports},
+	ServicerRoleAdmin: {PermissionAttach, PermissionMonitor, PermissionTransfer, PermissionCloseOthers,
+		PermissionModerate, PermissionReadReports, PermissionExport, PermissionManageUsers},
+	ServicerRoleOwner: {PermissionAttach, PermissionMonitor, PermissionTransfer, PermissionCloseOthers,
+		PermissionModerate, PermissionReadReports, PermissionExport, PermissionManageUsers},
+}
+
+var roleLevels = map[ServicerRole]int{
+	ServicerRoleAgent:      1,
+	ServicerRoleSupervisor: 2,
+	ServicerRoleAdmin:      3,
+	ServicerRoleOwner:      4,
+}
+
+func (role ServicerRole) Valid() bool {
+	return roleLevels[role] > 0
+}
+
+func (role ServicerRole) Can(permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// CanManage returns true if the role manages the servicers of the other role and grants the other role,
+// the owners manage everyone, the others manage the lower roles only.
+func (role ServicerRole) CanManage(other ServicerRole) bool {
+	if !role.Can(PermissionManageUsers) {
+		return false
+	}
+
+	return role == ServicerRoleOwner || roleLevels[role] > roleLevels[other]
+}
+
+// ServicerRoles maps the actIDs to the roles of a servicer, AllActs for the role in all the acts.
+type ServicerRoles map[string]ServicerRole
+
+// Role returns the higher one of the role in the act and the role in all the acts.
+func (roles ServicerRoles) Role(actID string) ServicerRole {
+	role := roles[AllActs]
+
+	if actRole := roles[actID]; roleLevels[actRole] > roleLevels[role] {
+		role = actRole
+	}
+
+	return role
+}
+
+func (roles ServicerRoles) Can(actID string, permission Permission) bool {
+	return roles.Role(actID).Can(permission)
+}
+
+// PermittedActIDs narrows the acts of a servicer, empty for all acts, to the ones with the permission,
+// ok is false if the permission isn't granted in any act.
+func (roles ServicerRoles) PermittedActIDs(actIDs []string, permission Permission) (permitted []string, ok bool) {
+	if roles[AllActs].Can(permission) {
+		return actIDs, true
+	}
+
+	for actID, role := range roles {
+		if actID == AllActs || !role.Can(permission) {
+			continue
+		}
+
+		if len(actIDs) == 0 || slices.Contains(actIDs, actID) {
+			permitted = append(permitted, actID)
+		}
+	}
+
+	slices.Sort(permitted)
+
+	return permitted, len(permitted) > 0
+}
+
+// CanManage returns true if the roles manage the roles of a servicer in all the acts of the servicer,
+// empty actIDs means all acts.
+func (roles ServicerRoles) CanManage(actIDs []string, userRoles ServicerRoles) bool {
+	if len(actIDs) == 0 {
+		actIDs = []string{AllActs}
+	}
+
+	for _, actID := range actIDs {
+		if !roles.Role(actID).CanManage(userRoles.Role(actID)) {
+			return false
+		}
+	}
+
+	return true
+}
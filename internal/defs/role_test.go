@@ -0,0 +1,50 @@
+package defs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServicerRoles(t *testing.T) {
+	assert.True(t, ServicerRoleAgent.Can(PermissionAttach))
+	assert.False(t, ServicerRoleAgent.Can(PermissionMonitor))
+	assert.True(t, ServicerRoleSupervisor.Can(PermissionCloseOthers))
+	assert.False(t, ServicerRoleSupervisor.Can(PermissionManageUsers))
+	assert.True(t, ServicerRoleAdmin.Can(PermissionExport))
+	assert.False(t, ServicerRole("root").Can(PermissionAttach))
+
+	assert.False(t, ServicerRoleSupervisor.CanManage(ServicerRoleAgent))
+	assert.True(t, ServicerRoleAdmin.CanManage(ServicerRoleSupervisor))
+	assert.False(t, ServicerRoleAdmin.CanManage(ServicerRoleAdmin))
+	assert.True(t, ServicerRoleOwner.CanManage(ServicerRoleOwner))
+
+	roles := ServicerRoles{
+		AllActs: ServicerRoleAgent,
+		"a1":    ServicerRoleSupervisor,
+		"a2":    ServicerRoleAdmin,
+		"a3":    ServicerRoleOwner,
+	}
+
+	assert.Equal(t, ServicerRoleAgent, roles.Role("a4"))
+	assert.Equal(t, ServicerRoleSupervisor, roles.Role("a1"))
+
+	actIDs, ok := roles.PermittedActIDs([]string{"a1", "a2", "a4"}, PermissionAttach)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a1", "a2", "a4"}, actIDs)
+
+	actIDs, ok = roles.PermittedActIDs([]string{"a1", "a2", "a4"}, PermissionMonitor)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a1", "a2"}, actIDs)
+
+	actIDs, ok = roles.PermittedActIDs(nil, PermissionManageUsers)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a2", "a3"}, actIDs)
+
+	_, ok = roles.PermittedActIDs([]string{"a1"}, PermissionManageUsers)
+	assert.False(t, ok)
+
+	assert.True(t, roles.CanManage([]string{"a2", "a3"}, ServicerRoles{AllActs: ServicerRoleSupervisor}))
+	assert.False(t, roles.CanManage([]string{"a2", "a3"}, ServicerRoles{"a2": ServicerRoleAdmin}))
+	assert.False(t, roles.CanManage(nil, ServicerRoles{AllActs: ServicerRoleAgent}))
+}
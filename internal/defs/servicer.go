@@ -4,10 +4,20 @@ import "github.com/zservicer/protorepo/gens/talkpb"
 
 type Servicer interface {
 	GetUserID() uint64
+	GetUserName() string
 	GetUniqueID() uint64
+	GetSessionID() string
 	SendMessage(msg *talkpb.ServiceResponse) error
 	Remove(msg string)
 
 	GetActIDs() []string
 	GetBizIDs() []string
+	GetRoles() ServicerRoles
+}
+
+type WhisperMessage struct {
+	SenderID       uint64 `json:"senderID"`
+	SenderUserName string `json:"senderUserName"`
+	At             int64  `json:"at"`
+	Text           string `json:"text"`
 }
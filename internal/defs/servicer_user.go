@@ -0,0 +1,50 @@
+package defs
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/userinters/userpass"
+)
+
+// ServicerUserModel extends the user password model with the operations of the servicer administration.
+type ServicerUserModel interface {
+	userpass.UserPasswordModel
+
+	UpdateUserPassword(ctx context.Context, userID uint64, password string) error
+	// QueryUsers returns a page of the users in any of the actIDs, empty actIDs means all users,
+	// the keyword matches the user names.
+	QueryUsers(ctx context.Context, actIDs []string, keyword string, offset, limit int64) (users []*userpass.User,
+		total int64, err error)
+}
+
+type ServicerUser struct {
+	ID          uint64        `json:"id"`
+	UserName    string        `json:"userName"`
+	CreateAt    int64         `json:"createAt"`
+	Roles       ServicerRoles `json:"roles"`
+	Disabled    bool          `json:"disabled"`
+	TOTPEnabled bool          `json:"totpEnabled"`
+	ActIDs      []string      `json:"actIDs"`
+	BizIDs      []string      `json:"bizIDs"`
+
+	// PasswordChangeRequired is set for the bootstrapped owner until the password is changed.
+	PasswordChangeRequired bool `json:"passwordChangeRequired,omitempty"`
+}
+
+type ServicerUserAdmin interface {
+	QueryServicerUsers(ctx context.Context, actIDs []string, keyword string, offset, limit int64) (users []*ServicerUser,
+		total int64, err error)
+	GetServicerUser(ctx context.Context, userID uint64) (*ServicerUser, error)
+	SetServicerUserDisabled(ctx context.Context, userID uint64, disabled bool) error
+	DeleteServicerUser(ctx context.Context, userID uint64) error
+	// SetServicerUserRole sets the role of the servicer in the act, or in all acts for AllActs, the empty role
+	// removes it. The servicers without any role have no permission.
+	SetServicerUserRole(ctx context.Context, userID uint64, actID string, role ServicerRole) error
+	// ResetServicerUserPassword and ChangeServicerUserPassword enforce the password policy, the tokens of
+	// the user are invalidated. ChangeServicerUserPassword clears the password change requirement.
+	ResetServicerUserPassword(ctx context.Context, userID uint64, password string) error
+	ChangeServicerUserPassword(ctx context.Context, userID uint64, oldPassword, newPassword string) error
+	// VerifyServicerUserPassword returns ErrWrongPassword if the user name or the password is wrong,
+	// and commerr.ErrPermissionDenied for the disabled users.
+	VerifyServicerUserPassword(ctx context.Context, userName, password string) (*ServicerUser, error)
+}
@@ -0,0 +1,33 @@
+package defs
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/userinters"
+)
+
+// ServicerSession is a login of a servicer, the renewed tokens keep the session of the login.
+type ServicerSession struct {
+	ID         string `bson:"_id" json:"id"`
+	UserID     uint64 `bson:"UserID" json:"userID"`
+	Device     string `bson:"Device" json:"device"`
+	IP         string `bson:"IP" json:"ip"`
+	CreateAt   int64  `bson:"CreateAt" json:"createAt"`
+	LastSeenAt int64  `bson:"LastSeenAt" json:"lastSeenAt"`
+	ExpireAt   int64  `bson:"ExpireAt" json:"expireAt"` // the expiration of the latest token of the session
+}
+
+// ServicerStatusController persists the banned tokens of the user center and the sessions of the servicers.
+type ServicerStatusController interface {
+	userinters.StatusController
+
+	AddSession(ctx context.Context, session *ServicerSession) error
+	// GetSession returns commerr.ErrNotFound for the unknown or revoked sessions.
+	GetSession(ctx context.Context, id string) (*ServicerSession, error)
+	// TouchSession updates the last seen time, and the expiration if it isn't zero.
+	TouchSession(ctx context.Context, id string, lastSeenAt, expireAt int64) error
+	// QuerySessions returns the sessions of the user unexpired at now, the latest seen first.
+	QuerySessions(ctx context.Context, userID uint64, now int64) ([]*ServicerSession, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, userID uint64) error
+}
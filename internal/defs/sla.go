@@ -0,0 +1,72 @@
+package defs
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+)
+
+type TalkSLA struct {
+	TalkID string `bson:"_id" json:"talkID"`
+	ActID  string `bson:"ActID" json:"actID"`
+	BizID  string `bson:"BizID" json:"bizID"`
+
+	ServicerID uint64 `bson:"ServicerID" json:"servicerID"` // the last attached servicer
+	Rating     int    `bson:"Rating" json:"rating"`         // the last rating of the customer, 0 if not rated
+
+	StartAt         int64 `bson:"StartAt" json:"startAt"`
+	FirstResponseAt int64 `bson:"FirstResponseAt" json:"firstResponseAt"`
+	AttachedAt      int64 `bson:"AttachedAt" json:"attachedAt"`
+	ClosedAt        int64 `bson:"ClosedAt" json:"closedAt"`
+
+	FirstResponseSeconds int64 `bson:"FirstResponseSeconds" json:"firstResponseSeconds"`
+	HandleSeconds        int64 `bson:"HandleSeconds" json:"handleSeconds"`
+
+	FirstResponseBreached bool `bson:"FirstResponseBreached" json:"firstResponseBreached"`
+	HandleBreached        bool `bson:"HandleBreached" json:"handleBreached"`
+}
+
+type TalkSLAModel interface {
+	GetTalkSLA(ctx context.Context, talkID string) (*TalkSLA, error)
+	// UpdateTalkSLA keeps the breach flags, they're set by MarkTalkSLABreached only.
+	UpdateTalkSLA(ctx context.Context, sla *TalkSLA) error
+	// SetTalkSLAServicer sets the attached servicer, the attached time is set by the first attaching only.
+	SetTalkSLAServicer(ctx context.Context, talkID string, servicerID uint64, at int64) error
+	// SetTalkSLAFirstResponse sets the first response once, updated is false if it's set already.
+	SetTalkSLAFirstResponse(ctx context.Context, talkID string, at int64) (updated bool, err error)
+	SetTalkSLARating(ctx context.Context, talkID string, rating int) error
+	// SetTalkSLAClosed sets the close time and the handle time once, updated is false if it's set already.
+	SetTalkSLAClosed(ctx context.Context, talkID string, at int64) (updated bool, err error)
+	// MarkTalkSLABreached flags the breach of the metric atomically, marked is false if it's flagged already,
+	// so a breach is reported once by all the instances.
+	MarkTalkSLABreached(ctx context.Context, talkID string, metric SLAMetric) (marked bool, err error)
+	QueryTalkSLAs(ctx context.Context, actIDs, bizIDs []string, startAt, finishAt int64) ([]*TalkSLA, error)
+	// QueryOpenTalkSLAs returns a page of the open records after the talk id, in the order of the talk ids.
+	QueryOpenTalkSLAs(ctx context.Context, afterTalkID string, limit int64) ([]*TalkSLA, error)
+}
+
+type SLAMetric string
+
+const (
+	SLAMetricFirstResponse SLAMetric = "firstResponse"
+	SLAMetricHandle        SLAMetric = "handle"
+)
+
+type SLABreach struct {
+	TalkID           string    `json:"talkID"`
+	ActID            string    `json:"actID"`
+	BizID            string    `json:"bizID"`
+	Metric           SLAMetric `json:"metric"`
+	ThresholdSeconds int64     `json:"thresholdSeconds"`
+	ElapsedSeconds   int64     `json:"elapsedSeconds"`
+}
+
+type SLATracker interface {
+	TalkCreated(ctx context.Context, talkInfo *talkinters.TalkInfoR)
+	TalkAttached(ctx context.Context, talkID string, servicerID uint64, at int64)
+	ServicerResponded(ctx context.Context, talkID string, at int64)
+	CustomerRated(ctx context.Context, talkID string, score int)
+	TalkClosed(ctx context.Context, talkID string, at int64)
+
+	CheckBreaches(ctx context.Context, now int64) []*SLABreach
+}
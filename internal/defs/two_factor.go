@@ -0,0 +1,55 @@
+package defs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sgostarter/i/commerr"
+)
+
+const (
+	AuthMethodNameTOTP = "totp"
+)
+
+var (
+	// ErrTOTPRequired means the password is verified and the login continues with a TOTP or recovery code.
+	ErrTOTPRequired           = errors.New("totpRequired")
+	ErrTOTPEnrollmentRequired = fmt.Errorf("%w: totpEnrollmentRequired", commerr.ErrPermissionDenied)
+	ErrTOTPNotEnrolled        = fmt.Errorf("%w: totpNotEnrolled", commerr.ErrNotFound)
+	ErrWrongTOTPCode          = fmt.Errorf("%w: wrongTOTPCode", commerr.ErrUnauthenticated)
+)
+
+// TOTPEnrollment is shown once, the recovery codes are single-use replacements of the TOTP codes.
+type TOTPEnrollment struct {
+	URI           string   `json:"uri"` // the otpauth:// provisioning uri for the authenticator apps
+	Secret        string   `json:"secret"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorPolicyModel interface {
+	// IsTwoFactorRequired returns true if any of the acts requires the two-factor authentication.
+	IsTwoFactorRequired(ctx context.Context, actIDs []string) (bool, error)
+	SetTwoFactorRequired(ctx context.Context, actID string, required bool) error
+}
+
+type TwoFactorAuth interface {
+	// Enroll generates a pending secret and recovery codes, they take effect after the activation. An enabled
+	// user confirms the re-enrollment with a current code.
+	Enroll(ctx context.Context, userID uint64, code string) (*TOTPEnrollment, error)
+	Activate(ctx context.Context, userID uint64, code string) error
+	// Disable is rejected with ErrTOTPEnrollmentRequired if any act of the user requires the two-factor authentication.
+	Disable(ctx context.Context, userID uint64, code string) error
+	// Reset disables the two-factor authentication without a code, for the admins.
+	Reset(ctx context.Context, userID uint64) error
+
+	Enabled(ctx context.Context, userID uint64) (bool, error)
+	Required(ctx context.Context, userID uint64) (bool, error)
+
+	// NewAuthenticator verifies a TOTP or recovery code of the user, it's the second login step.
+	NewAuthenticator(userName, code string) userinters.Authenticator
+	// SignContinueID binds the continue id of the second step to the user, the ids are guessable.
+	SignContinueID(userName string, continueID uint64) string
+	VerifyContinueID(userName, signedContinueID string) (uint64, error)
+}
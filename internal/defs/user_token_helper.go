@@ -12,19 +12,41 @@ type UserTokenExtractor interface {
 
 type ServicerUserTokenExplain interface {
 	ExplainToken(ctx context.Context, token string, renewToken bool) (newToken string, userID uint64,
-		userName string, admin bool, actIDs, bizIDs []string, err error)
+		userName string, roles ServicerRoles, actIDs, bizIDs []string, err error)
+	// GetServicerRoles returns the acts and the roles of a servicer.
+	GetServicerRoles(ctx context.Context, userID uint64) (actIDs []string, roles ServicerRoles, err error)
 }
 
 type ServicerExDataGen interface {
 	GenExData(actIDs, bizIDs []string) map[string]interface{}
+	GenRolesExData(roles ServicerRoles) map[string]interface{}
+}
+
+type ServicerUserGenAuthenticator interface {
+	NewAuthenticator(userName, password string) userinters.Authenticator
+}
+
+type ServicerUserSessionHelper interface {
+	// StartSession records the session of a token issued by the login, the tokens of unknown sessions are invalid.
+	StartSession(ctx context.Context, token, device, ip string) (*ServicerSession, error)
+	ExtractSessionFromGRPCContext(ctx context.Context) (*ServicerSession, error)
+	QuerySessions(ctx context.Context, userID uint64) ([]*ServicerSession, error)
+	// RevokeSession and RevokeUserSessions invalidate the tokens of the sessions, the renewed ones too.
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID uint64) error
 }
 
 type ServicerUserTokenHelper interface {
 	UserTokenExtractor
 	ServicerUserTokenExplain
 	ServicerExDataGen
+	ServicerUserGenAuthenticator
+	ServicerUserSessionHelper
 	ExtractUserFromGRPCContext(ctx context.Context, renewToken bool) (newToken string, userID uint64,
-		userName string, admin bool, actIDs, bizIDs []string, err error)
+		userName string, roles ServicerRoles, actIDs, bizIDs []string, err error)
+	// ExtractUserForPasswordChange accepts the tokens rejected with ErrPasswordChangeRequired, it's only for
+	// changing the password.
+	ExtractUserForPasswordChange(ctx context.Context) (userID uint64, userName string, err error)
 }
 
 type CustomerUserGenAnonymousAuthenticator interface {
@@ -36,10 +58,19 @@ type CustomerUserTokenExplain interface {
 		userName, actID, bizID string, err error)
 }
 
+type CustomerUserIdentityHelper interface {
+	ExtractIdentityTokenFromGRPCContext(ctx context.Context) (identityToken string, err error)
+	// VerifyIdentity returns the user id bound to the verified identity.
+	VerifyIdentity(identityToken string) (userID uint64, identity *CustomerIdentity, err error)
+	IdentityRequired(actID string) bool
+	NewVerifiedAuthenticator(identity *CustomerIdentity) userinters.Authenticator
+}
+
 type CustomerUserTokenHelper interface {
 	UserTokenExtractor
 	CustomerUserTokenExplain
 	CustomerUserGenAnonymousAuthenticator
+	CustomerUserIdentityHelper
 	ExtractUserFromGRPCContext(ctx context.Context, renewToken bool) (newToken string, userID uint64,
 		userName, actID, bizID string, err error)
 }
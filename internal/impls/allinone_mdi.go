@@ -48,6 +48,11 @@ func (impl *allInOneMDIImpl) SendMessage(senderUniqueID uint64, talkID string, m
 	impl.servicerOb.OnMessageIncoming(senderUniqueID, talkID, message)
 }
 
+func (impl *allInOneMDIImpl) SendMessageRevision(talkID string, revision *defs.MessageRevision) {
+	impl.customerOb.OnMessageRevision(talkID, revision)
+	impl.servicerOb.OnMessageRevision(talkID, revision)
+}
+
 func (impl *allInOneMDIImpl) SetCustomerObserver(ob defs.CustomerObserver) {
 	impl.customerOb = ob
 }
@@ -66,9 +71,34 @@ func (impl *allInOneMDIImpl) SetServicerObserver(ob defs.ServicerObserver) {
 }
 
 func (impl *allInOneMDIImpl) SendServicerAttachMessage(talkID string, servicerID uint64) {
+	impl.customerOb.OnServicerAttachMessage(talkID, servicerID)
 	impl.servicerOb.OnServicerAttachMessage(talkID, servicerID)
 }
 
 func (impl *allInOneMDIImpl) SendServiceDetachMessage(talkID string, servicerID uint64) {
 	impl.servicerOb.OnServicerDetachMessage(talkID, servicerID)
 }
+
+func (impl *allInOneMDIImpl) SendServicerWatchMessage(talkID string, servicerID uint64, watch bool) {
+	impl.servicerOb.OnServicerWatchMessage(talkID, servicerID, watch)
+}
+
+func (impl *allInOneMDIImpl) SendWhisperMessage(talkID string, whisper *defs.WhisperMessage) {
+	impl.servicerOb.OnWhisperMessage(talkID, whisper)
+}
+
+func (impl *allInOneMDIImpl) SendServicerParticipantMessage(talkID string, servicerID uint64, join bool) {
+	impl.servicerOb.OnServicerParticipantMessage(talkID, servicerID, join)
+}
+
+func (impl *allInOneMDIImpl) SendCustomerBanMessage(ban *defs.CustomerBan) {
+	impl.customerOb.OnCustomerBan(ban)
+}
+
+func (impl *allInOneMDIImpl) SendServicerKickMessage(servicerID uint64, sessionID, reason string) {
+	impl.servicerOb.OnServicerKick(servicerID, sessionID, reason)
+}
+
+func (impl *allInOneMDIImpl) SendSLABreachMessage(breach *defs.SLABreach) {
+	impl.servicerOb.OnSLABreach(breach)
+}
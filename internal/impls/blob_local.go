@@ -0,0 +1,154 @@
+package impls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	blobMetaSuffix = ".meta"
+)
+
+var blobKeyRegexp = regexp.MustCompile(`^[0-9A-Za-z_-]+(/[0-9A-Za-z_-]+)*$`)
+
+func NewBlobStore(cfg *config.Attachment) (defs.BlobStore, error) {
+	switch cfg.Store {
+	case config.BlobStoreTypeLocal:
+		return NewLocalBlobStore(cfg.LocalDir)
+	case config.BlobStoreTypeS3:
+		return NewS3BlobStore(&cfg.S3)
+	default:
+		return nil, commerr.ErrInvalidArgument
+	}
+}
+
+func NewLocalBlobStore(dir string) (defs.BlobStore, error) {
+	if dir == "" {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &localBlobStoreImpl{
+		dir: dir,
+	}, nil
+}
+
+type localBlobStoreImpl struct {
+	dir string
+}
+
+func (impl *localBlobStoreImpl) Put(ctx context.Context, key string, info *defs.BlobInfo, r io.Reader) (err error) {
+	if !blobKeyRegexp.MatchString(key) || info == nil || r == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	fileName := impl.fileName(key)
+
+	if err = os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(fileName), ".upload-*")
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	size, err := io.Copy(f, r)
+
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+
+	if err != nil {
+		return
+	}
+
+	infoCopy := *info
+	infoCopy.Size = size
+
+	d, err := json.Marshal(&infoCopy)
+	if err != nil {
+		return
+	}
+
+	if err = os.WriteFile(fileName+blobMetaSuffix, d, 0o600); err != nil {
+		return
+	}
+
+	err = os.Rename(f.Name(), fileName)
+
+	return
+}
+
+func (impl *localBlobStoreImpl) Get(ctx context.Context, key string) (io.ReadCloser, *defs.BlobInfo, error) {
+	if !blobKeyRegexp.MatchString(key) {
+		return nil, nil, commerr.ErrInvalidArgument
+	}
+
+	fileName := impl.fileName(key)
+
+	d, err := os.ReadFile(fileName + blobMetaSuffix)
+	if err != nil {
+		return nil, nil, localBlobError(err)
+	}
+
+	var info defs.BlobInfo
+
+	if err = json.Unmarshal(d, &info); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, localBlobError(err)
+	}
+
+	return f, &info, nil
+}
+
+func (impl *localBlobStoreImpl) Delete(ctx context.Context, key string) error {
+	if !blobKeyRegexp.MatchString(key) {
+		return commerr.ErrInvalidArgument
+	}
+
+	fileName := impl.fileName(key)
+
+	if err := os.Remove(fileName); err != nil {
+		return localBlobError(err)
+	}
+
+	_ = os.Remove(fileName + blobMetaSuffix)
+
+	return nil
+}
+
+func (impl *localBlobStoreImpl) fileName(key string) string {
+	return filepath.Join(impl.dir, filepath.FromSlash(key))
+}
+
+func localBlobError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return commerr.ErrNotFound
+	}
+
+	return err
+}
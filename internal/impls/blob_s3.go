@@ -0,0 +1,322 @@
+package impls
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	s3MetaNameHeader   = "X-Amz-Meta-Name"
+	s3UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	s3SignAlgorithm    = "AWS4-HMAC-SHA256"
+	s3Service          = "s3"
+	s3DefaultRegion    = "us-east-1"
+	s3AmzDateFormat    = "20060102T150405Z"
+	s3ShortDateFormat  = "20060102"
+	s3RequestTimeout   = time.Minute * 5
+	s3ErrorBodyMaxSize = 1024
+)
+
+// NewS3BlobStore stores blobs in a S3 compatible service with path style addressing, requests are signed with signature v4.
+func NewS3BlobStore(cfg *config.S3BlobStore) (defs.BlobStore, error) {
+	if cfg == nil || cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	return &s3BlobStoreImpl{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		signer: &s3Signer{
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			region:    region,
+			now:       time.Now,
+		},
+		client: &http.Client{
+			Timeout: s3RequestTimeout,
+		},
+	}, nil
+}
+
+type s3BlobStoreImpl struct {
+	endpoint *url.URL
+	bucket   string
+	signer   *s3Signer
+	client   *http.Client
+}
+
+func (impl *s3BlobStoreImpl) Put(ctx context.Context, key string, info *defs.BlobInfo, r io.Reader) error {
+	if !blobKeyRegexp.MatchString(key) || info == nil || r == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	size := info.Size
+
+	if size <= 0 {
+		d, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(d)
+		size = int64(len(d))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, impl.objectURL(key), r)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+
+	if info.ContentType != "" {
+		req.Header.Set("Content-Type", info.ContentType)
+	}
+
+	if info.Name != "" {
+		req.Header.Set(s3MetaNameHeader, url.QueryEscape(info.Name))
+	}
+
+	resp, err := impl.do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+func (impl *s3BlobStoreImpl) Get(ctx context.Context, key string) (io.ReadCloser, *defs.BlobInfo, error) {
+	if !blobKeyRegexp.MatchString(key) {
+		return nil, nil, commerr.ErrInvalidArgument
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, impl.objectURL(key), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := impl.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := &defs.BlobInfo{
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+
+	info.Name, _ = url.QueryUnescape(resp.Header.Get(s3MetaNameHeader))
+
+	return resp.Body, info, nil
+}
+
+func (impl *s3BlobStoreImpl) Delete(ctx context.Context, key string) error {
+	if !blobKeyRegexp.MatchString(key) {
+		return commerr.ErrInvalidArgument
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, impl.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := impl.do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+func (impl *s3BlobStoreImpl) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for idx, segment := range segments {
+		segments[idx] = s3URIEscape(segment)
+	}
+
+	return strings.TrimSuffix(impl.endpoint.String(), "/") + "/" + s3URIEscape(impl.bucket) + "/" + strings.Join(segments, "/")
+}
+
+func (impl *s3BlobStoreImpl) do(req *http.Request) (*http.Response, error) {
+	impl.signer.sign(req, s3UnsignedPayload)
+
+	resp, err := impl.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, commerr.ErrNotFound
+	}
+
+	d, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyMaxSize))
+
+	return nil, fmt.Errorf("s3 %s %s: %d %s", req.Method, req.URL.Path, resp.StatusCode, string(d))
+}
+
+//
+//
+//
+
+type s3Signer struct {
+	accessKey string
+	secretKey string
+	region    string
+	now       func() time.Time
+}
+
+func (signer *s3Signer) sign(req *http.Request, payloadHash string) {
+	now := signer.now().UTC()
+	amzDate := now.Format(s3AmzDateFormat)
+	shortDate := now.Format(s3ShortDateFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders, canonicalHeaders := signer.canonicalHeaders(req)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		signer.canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{shortDate, signer.region, s3Service, "aws4_request"}, "/")
+
+	stringToSign := strings.Join([]string{
+		s3SignAlgorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+signer.secretKey), shortDate)
+	key = hmacSHA256(key, signer.region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SignAlgorithm, signer.accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func (signer *s3Signer) canonicalHeaders(req *http.Request) (signedHeaders, canonicalHeaders string) {
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	if req.ContentLength > 0 {
+		headers["content-length"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var sb strings.Builder
+
+	for _, name := range names {
+		sb.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return strings.Join(names, ";"), sb.String()
+}
+
+func (signer *s3Signer) canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		vs := append([]string(nil), values[key]...)
+		sort.Strings(vs)
+
+		for _, v := range vs {
+			pairs = append(pairs, s3URIEscape(key)+"="+s3URIEscape(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// s3URIEscape escapes all bytes except the unreserved characters, as signature v4 requires.
+func s3URIEscape(s string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+
+	return hex.EncodeToString(h[:])
+}
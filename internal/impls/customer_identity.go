@@ -0,0 +1,160 @@
+package impls
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+// verifiedCustomerUserIDFlag marks the user ids of the verified customers, the anonymous ones are snowflake ids
+// which never set the highest bit.
+const verifiedCustomerUserIDFlag = uint64(1) << 63
+
+type customerIdentityKey struct {
+	alg       config.CustomerIdentityAlg
+	secret    []byte
+	publicKey *rsa.PublicKey
+	required  bool
+}
+
+// NewCustomerIdentityVerifier verifies the identities signed as JWTs by the host apps, the claims are
+// sub (the external user id), name, act, biz and exp, the key is selected by the act claim.
+func NewCustomerIdentityVerifier(cfg map[string]config.CustomerIdentity) (defs.CustomerIdentityVerifier, error) {
+	keys := make(map[string]*customerIdentityKey, len(cfg))
+
+	for actID, identity := range cfg {
+		key := &customerIdentityKey{
+			alg:      identity.Alg,
+			required: identity.Required,
+		}
+
+		switch identity.Alg {
+		case config.CustomerIdentityAlgHS256:
+			if identity.Secret == "" {
+				return nil, errors.New("no secret for the customer identity of " + actID)
+			}
+
+			key.secret = []byte(identity.Secret)
+		case config.CustomerIdentityAlgRS256:
+			publicKey, err := parseRSAPublicKey(identity.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+
+			key.publicKey = publicKey
+		default:
+			return nil, errors.New("invalid customer identity alg of " + actID)
+		}
+
+		keys[actID] = key
+	}
+
+	return &customerIdentityVerifierImpl{
+		keys: keys,
+		now:  time.Now,
+	}, nil
+}
+
+type customerIdentityVerifierImpl struct {
+	keys map[string]*customerIdentityKey
+	now  func() time.Time
+}
+
+type customerIdentityClaims struct {
+	jwt.RegisteredClaims
+
+	Name  string `json:"name"`
+	ActID string `json:"act"`
+	BizID string `json:"biz"`
+}
+
+func (impl *customerIdentityVerifierImpl) Verify(identityToken string) (*defs.CustomerIdentity, error) {
+	var claims customerIdentityClaims
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{string(config.CustomerIdentityAlgHS256),
+		string(config.CustomerIdentityAlgRS256)}), jwt.WithoutClaimsValidation())
+
+	_, err := parser.ParseWithClaims(identityToken, &claims, impl.keyFunc(&claims))
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorMalformed != 0 {
+			return nil, commerr.ErrBadFormat
+		}
+
+		return nil, commerr.ErrUnauthenticated
+	}
+
+	// the expiry is checked here instead of by the parser, which reads the global clock of the jwt package
+	if !claims.VerifyExpiresAt(impl.now(), true) || claims.Subject == "" || claims.BizID == "" {
+		return nil, commerr.ErrUnauthenticated
+	}
+
+	if claims.Name == "" {
+		claims.Name = claims.Subject
+	}
+
+	return &defs.CustomerIdentity{
+		ExternalID: claims.Subject,
+		UserName:   claims.Name,
+		ActID:      claims.ActID,
+		BizID:      claims.BizID,
+	}, nil
+}
+
+func (impl *customerIdentityVerifierImpl) Required(actID string) bool {
+	key, ok := impl.keys[actID]
+
+	return ok && key.required
+}
+
+// keyFunc selects the key by the act claim, which is decoded before the signature is verified,
+// and the token must be signed by the alg of the key.
+func (impl *customerIdentityVerifierImpl) keyFunc(claims *customerIdentityClaims) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		key, ok := impl.keys[claims.ActID]
+		if !ok || token.Method.Alg() != string(key.alg) {
+			return nil, commerr.ErrUnauthenticated
+		}
+
+		if key.alg == config.CustomerIdentityAlgRS256 {
+			return key.publicKey, nil
+		}
+
+		return key.secret, nil
+	}
+}
+
+func parseRSAPublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("invalid customer identity public key")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not a rsa public key")
+	}
+
+	return rsaPublicKey, nil
+}
+
+// verifiedCustomerUserID maps the external user id of the act to a stable user id.
+func verifiedCustomerUserID(actID, externalID string) uint64 {
+	digest := sha256.Sum256([]byte(actID + "\x00" + externalID))
+
+	return binary.BigEndian.Uint64(digest[:8]) | verifiedCustomerUserIDFlag
+}
@@ -0,0 +1,108 @@
+package impls
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+)
+
+func signTestIdentity(t *testing.T, alg config.CustomerIdentityAlg, claims map[string]interface{},
+	sign func(signingInput string) []byte) string {
+	header, err := json.Marshal(map[string]interface{}{"alg": alg, "typ": "JWT"})
+	assert.Nil(t, err)
+
+	payload, err := json.Marshal(claims)
+	assert.Nil(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(signingInput))
+}
+
+func TestCustomerIdentityVerifier(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.Nil(t, err)
+
+	verifier, err := NewCustomerIdentityVerifier(map[string]config.CustomerIdentity{
+		"a1": {Alg: config.CustomerIdentityAlgHS256, Secret: "s1", Required: true},
+		"a2": {Alg: config.CustomerIdentityAlgRS256, PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))},
+	})
+	assert.Nil(t, err)
+	assert.True(t, verifier.Required("a1"))
+	assert.False(t, verifier.Required("a2"))
+	assert.False(t, verifier.Required("a3"))
+
+	hs256 := func(secret string) func(string) []byte {
+		return func(signingInput string) []byte {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(signingInput))
+
+			return mac.Sum(nil)
+		}
+	}
+
+	rs256 := func(signingInput string) []byte {
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+		assert.Nil(t, err)
+
+		return signature
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	identity, err := verifier.Verify(signTestIdentity(t, config.CustomerIdentityAlgHS256,
+		map[string]interface{}{"sub": "u1", "name": "Tom", "act": "a1", "biz": "b1", "exp": exp}, hs256("s1")))
+	assert.Nil(t, err)
+	assert.Equal(t, "u1", identity.ExternalID)
+	assert.Equal(t, "Tom", identity.UserName)
+	assert.Equal(t, "b1", identity.BizID)
+
+	identity, err = verifier.Verify(signTestIdentity(t, config.CustomerIdentityAlgRS256,
+		map[string]interface{}{"sub": "u2", "act": "a2", "biz": "b2", "exp": exp}, rs256))
+	assert.Nil(t, err)
+	assert.Equal(t, "u2", identity.UserName)
+
+	// wrong secret
+	_, err = verifier.Verify(signTestIdentity(t, config.CustomerIdentityAlgHS256,
+		map[string]interface{}{"sub": "u1", "act": "a1", "biz": "b1", "exp": exp}, hs256("s2")))
+	assert.NotNil(t, err)
+
+	// the alg of the act is RS256
+	_, err = verifier.Verify(signTestIdentity(t, config.CustomerIdentityAlgHS256,
+		map[string]interface{}{"sub": "u2", "act": "a2", "biz": "b2", "exp": exp}, hs256("s1")))
+	assert.NotNil(t, err)
+
+	// expired
+	_, err = verifier.Verify(signTestIdentity(t, config.CustomerIdentityAlgHS256,
+		map[string]interface{}{"sub": "u1", "act": "a1", "biz": "b1", "exp": time.Now().Add(-time.Minute).Unix()}, hs256("s1")))
+	assert.NotNil(t, err)
+
+	// tampered payload
+	token := signTestIdentity(t, config.CustomerIdentityAlgRS256,
+		map[string]interface{}{"sub": "u2", "act": "a2", "biz": "b2", "exp": exp}, rs256)
+	other := signTestIdentity(t, config.CustomerIdentityAlgRS256,
+		map[string]interface{}{"sub": "admin", "act": "a2", "biz": "b2", "exp": exp}, rs256)
+	tokenParts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	_, err = verifier.Verify(tokenParts[0] + "." + otherParts[1] + "." + tokenParts[2])
+	assert.NotNil(t, err)
+
+	assert.Equal(t, verifiedCustomerUserID("a1", "u1"), verifiedCustomerUserID("a1", "u1"))
+	assert.NotEqual(t, verifiedCustomerUserID("a1", "u1"), verifiedCustomerUserID("a2", "u1"))
+	assert.NotZero(t, verifiedCustomerUserID("a1", "u1")&verifiedCustomerUserIDFlag)
+}
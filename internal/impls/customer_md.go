@@ -3,6 +3,7 @@ package impls
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/l"
@@ -11,15 +12,22 @@ import (
 	"github.com/zservicer/talkbe/internal/vo"
 )
 
-func NewCustomerMD(mdi defs.CustomerMDI, logger l.Wrapper) defs.CustomerMD {
+const (
+	maxRecentAssignCount      = 20
+	recentAssignWindowSeconds = 3600
+)
+
+func NewCustomerMD(mdi defs.CustomerMDI, slaTracker defs.SLATracker, logger l.Wrapper) defs.CustomerMD {
 	if logger == nil {
 		logger = l.NewNopLoggerWrapper()
 	}
 
 	impl := &customerMDImpl{
-		mdi:       mdi,
-		logger:    logger,
-		customers: make(map[string]map[uint64]defs.Customer),
+		mdi:         mdi,
+		slaTracker:  slaTracker,
+		logger:      logger,
+		customers:   make(map[string]map[uint64]defs.Customer),
+		assignTimes: make(map[string][]int64),
 	}
 
 	mdi.SetCustomerObserver(impl)
@@ -28,11 +36,19 @@ func NewCustomerMD(mdi defs.CustomerMDI, logger l.Wrapper) defs.CustomerMD {
 }
 
 type customerMDImpl struct {
-	mrRunner defs.MainRoutineRunner
-	mdi      defs.CustomerMDI
-	logger   l.Wrapper
+	mrRunner   defs.MainRoutineRunner
+	mdi        defs.CustomerMDI
+	slaTracker defs.SLATracker
+	logger     l.Wrapper
 
-	customers map[string]map[uint64]defs.Customer // talkID - customerN - customer
+	customers   map[string]map[uint64]defs.Customer // talkID - customerN - customer
+	assignTimes map[string][]int64                  // scope - recent attach times
+}
+
+type queuePosition struct {
+	TalkID               string `json:"talkID"`
+	Position             int    `json:"position"`
+	EstimatedWaitSeconds int64  `json:"estimatedWaitSeconds,omitempty"`
 }
 
 //
@@ -56,6 +72,48 @@ func (impl *customerMDImpl) OnTalkClose(talkID string) {
 				Close: &talkpb.TalkClose{},
 			},
 		})
+
+		talkInfo, err := impl.mdi.GetM().GetTalkInfo(context.TODO(), nil, nil, talkID)
+		if err != nil {
+			impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkInfoFailed")
+
+			return
+		}
+
+		impl.sendQueuePositions(context.TODO(), talkInfo.ActID, talkInfo.BizID)
+	})
+}
+
+func (impl *customerMDImpl) OnServicerAttachMessage(talkID string, servicerID uint64) {
+	impl.mrRunner.Post(func() {
+		talkInfo, err := impl.mdi.GetM().GetTalkInfo(context.TODO(), nil, nil, talkID)
+		if err != nil {
+			impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkInfoFailed")
+
+			return
+		}
+
+		impl.recordAssign(talkInfo.ActID, talkInfo.BizID, time.Now().Unix())
+		impl.sendQueuePositions(context.TODO(), talkInfo.ActID, talkInfo.BizID)
+	})
+}
+
+func (impl *customerMDImpl) OnMessageRevision(talkID string, revision *defs.MessageRevision) {
+	impl.mrRunner.Post(func() {
+		impl.sendResponseToCustomers(0, talkID, vo.TalkNotifyResponse(vo.NotifyKindRevision,
+			vo.CustomerMessageRevision(revision)))
+	})
+}
+
+func (impl *customerMDImpl) OnCustomerBan(ban *defs.CustomerBan) {
+	impl.mrRunner.Post(func() {
+		for _, customers := range impl.customers {
+			for _, customer := range customers {
+				if ban.Match(customer.GetUserID(), customer.GetUserName(), customer.GetActID()) {
+					customer.Remove(vo.KickOutReasonBanned)
+				}
+			}
+		}
 	})
 }
 
@@ -108,7 +166,15 @@ func (impl *customerMDImpl) InstallCustomer(ctx context.Context, customer defs.C
 
 	impl.customers[customer.GetTalkID()][customer.GetUniqueID()] = customer
 
+	impl.sendQueuePositions(ctx, customer.GetActID(), customer.GetBizID())
+
 	if customer.CreateTalkFlag() {
+		if impl.slaTracker != nil {
+			if talkInfo, errT := impl.mdi.GetM().GetTalkInfo(ctx, nil, nil, customer.GetTalkID()); errT == nil {
+				impl.slaTracker.TalkCreated(ctx, talkInfo)
+			}
+		}
+
 		impl.mdi.SendTalkCreateMessage(customer.GetTalkID())
 	}
 
@@ -159,7 +225,7 @@ func (impl *customerMDImpl) UninstallCustomer(ctx context.Context, customer defs
 }
 
 func (impl *customerMDImpl) CustomerMessageIncoming(ctx context.Context,
-	customer defs.Customer, seqID uint64, message *talkinters.TalkMessageW) {
+	customer defs.Customer, seqID uint64, messageID string, message *talkinters.TalkMessageW) {
 	if customer == nil {
 		impl.logger.Error("noCustomer")
 
@@ -188,12 +254,24 @@ func (impl *customerMDImpl) CustomerMessageIncoming(ctx context.Context,
 		customer.Remove("sendMessageFailed")
 
 		delete(customersMap, customer.GetUniqueID())
+	} else if messageID != "" {
+		_ = customer.SendMessage(vo.TalkNotifyResponse(vo.NotifyKindConfirmed, &vo.MessageConfirmed{
+			SeqID:     seqID,
+			At:        message.At,
+			MessageID: messageID,
+		}))
 	}
 
 	if len(customersMap) == 0 {
 		delete(impl.customers, customer.GetTalkID())
 	}
 
+	if impl.slaTracker != nil && message.Type == defs.TalkMessageTypeRating {
+		if richMessage, ok := vo.RichMessageFromDB(message); ok && richMessage.Rating != nil {
+			impl.slaTracker.CustomerRated(ctx, customer.GetTalkID(), richMessage.Rating.Score)
+		}
+	}
+
 	impl.mdi.SendMessage(customer.GetUniqueID(), customer.GetTalkID(), message)
 }
 
@@ -211,13 +289,112 @@ func (impl *customerMDImpl) CustomerClose(ctx context.Context, customer defs.Cus
 		return
 	}
 
+	addSystemMessage(ctx, impl.mdi, customer.GetTalkID(), &defs.SystemMessage{
+		Event:    defs.SystemEventClose,
+		ClosedBy: vo.ClosedByCustomer,
+	}, impl.logger)
+
+	if impl.slaTracker != nil {
+		impl.slaTracker.TalkClosed(ctx, customer.GetTalkID(), time.Now().Unix())
+	}
+
 	impl.mdi.SendTalkCloseMessage(customer.GetTalkID())
 }
 
+func (impl *customerMDImpl) MessageRevised(_ context.Context, talkID string, revision *defs.MessageRevision) {
+	if talkID == "" || revision == nil {
+		impl.logger.Error("nilParameters")
+
+		return
+	}
+
+	impl.mdi.SendMessageRevision(talkID, revision)
+}
+
+func (impl *customerMDImpl) RefreshQueuePositions(ctx context.Context) {
+	scopes := make(map[[2]string]struct{})
+
+	for _, talkCustomers := range impl.customers {
+		for _, customer := range talkCustomers {
+			scopes[[2]string{customer.GetActID(), customer.GetBizID()}] = struct{}{}
+
+			break
+		}
+	}
+
+	for scope := range scopes {
+		impl.sendQueuePositions(ctx, scope[0], scope[1])
+	}
+}
+
 //
 //
 //
 
+func (impl *customerMDImpl) assignScopeKey(actID, bizID string) string {
+	return actID + "/" + bizID
+}
+
+func (impl *customerMDImpl) recordAssign(actID, bizID string, at int64) {
+	key := impl.assignScopeKey(actID, bizID)
+
+	assignTimes := append(impl.assignTimes[key], at)
+	if len(assignTimes) > maxRecentAssignCount {
+		assignTimes = assignTimes[len(assignTimes)-maxRecentAssignCount:]
+	}
+
+	impl.assignTimes[key] = assignTimes
+}
+
+// estimateWaitSeconds uses the average interval between the recent assignments of the scope, 0 means unknown
+// until there are two of them.
+func (impl *customerMDImpl) estimateWaitSeconds(actID, bizID string, position int, now int64) int64 {
+	var recentTimes []int64
+
+	for _, at := range impl.assignTimes[impl.assignScopeKey(actID, bizID)] {
+		if now-at <= recentAssignWindowSeconds {
+			recentTimes = append(recentTimes, at)
+		}
+	}
+
+	if len(recentTimes) < 2 {
+		return 0
+	}
+
+	span := recentTimes[len(recentTimes)-1] - recentTimes[0]
+	if span <= 0 {
+		span = 1
+	}
+
+	return int64(position) * span / int64(len(recentTimes)-1)
+}
+
+func (impl *customerMDImpl) sendQueuePositions(ctx context.Context, actID, bizID string) {
+	talkInfos, err := impl.mdi.GetM().GetPendingTalkInfos(ctx, []string{actID}, []string{bizID})
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetPendingTalkInfosFailed")
+
+		return
+	}
+
+	// the positions follow the order of the pending talks
+	now := time.Now().Unix()
+
+	for idx, talkInfo := range talkInfos {
+		if _, ok := impl.customers[talkInfo.TalkID]; !ok {
+			continue
+		}
+
+		position := idx + 1
+
+		impl.sendResponseToCustomers(0, talkInfo.TalkID, vo.TalkNotifyResponse(vo.NotifyKindQueuePosition, &queuePosition{
+			TalkID:               talkInfo.TalkID,
+			Position:             position,
+			EstimatedWaitSeconds: impl.estimateWaitSeconds(actID, bizID, position, now),
+		}))
+	}
+}
+
 func (impl *customerMDImpl) sendResponseToCustomers(excludedUniqueID uint64, talkID string, resp *talkpb.TalkResponse) {
 	customersMap := impl.customers[talkID]
 
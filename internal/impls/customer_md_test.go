@@ -0,0 +1,125 @@
+package impls
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+type testMainRoutineRunner struct{}
+
+func (testMainRoutineRunner) Post(fn func()) {
+	fn()
+}
+
+type testCustomer struct {
+	talkID   string
+	uniqueID uint64
+
+	lock      sync.Mutex
+	positions []queuePosition
+}
+
+func (c *testCustomer) GetActID() string     { return "a1" }
+func (c *testCustomer) GetBizID() string     { return "b1" }
+func (c *testCustomer) GetUniqueID() uint64  { return c.uniqueID }
+func (c *testCustomer) GetTalkID() string    { return c.talkID }
+func (c *testCustomer) GetUserID() uint64    { return c.uniqueID }
+func (c *testCustomer) GetUserName() string  { return "" }
+func (c *testCustomer) Remove(_ string)      {}
+func (c *testCustomer) CreateTalkFlag() bool { return false }
+
+func (c *testCustomer) SendMessage(msg *talkpb.TalkResponse) error {
+	notify := msg.GetNotify()
+	if notify == nil {
+		return nil
+	}
+
+	var d struct {
+		Kind string        `json:"kind"`
+		Data queuePosition `json:"data"`
+	}
+
+	if err := json.Unmarshal([]byte(notify.GetMsg()), &d); err != nil || d.Kind != vo.NotifyKindQueuePosition {
+		return nil
+	}
+
+	c.lock.Lock()
+	c.positions = append(c.positions, d.Data)
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *testCustomer) lastPosition() queuePosition {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.positions) == 0 {
+		return queuePosition{}
+	}
+
+	return c.positions[len(c.positions)-1]
+}
+
+func TestCustomerMDQueuePositions(t *testing.T) {
+	ctx := context.Background()
+	m := NewModelEx(NewMemModel(), NewMemMessageExModel())
+
+	var talkIDs []string
+
+	for idx := 0; idx < 3; idx++ {
+		talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+			Status:          talkinters.TalkStatusOpened,
+			Title:           "title",
+			StartAt:         1000,
+			CreatorID:       uint64(idx + 1),
+			CreatorUserName: "customer",
+			ActID:           "a1",
+			BizID:           "b1",
+		})
+		assert.Nil(t, err)
+
+		talkIDs = append(talkIDs, talkID)
+	}
+
+	md := NewCustomerMD(NewAllInOneMDI(m, nil), nil, nil)
+	md.Setup(testMainRoutineRunner{})
+
+	c2 := &testCustomer{talkID: talkIDs[1], uniqueID: 2}
+	c3 := &testCustomer{talkID: talkIDs[2], uniqueID: 3}
+
+	md.InstallCustomer(ctx, c2)
+	md.InstallCustomer(ctx, c3)
+
+	// no assignment yet, so no estimation
+	assert.Equal(t, queuePosition{TalkID: talkIDs[1], Position: 2}, c2.lastPosition())
+	assert.Equal(t, queuePosition{TalkID: talkIDs[2], Position: 3}, c3.lastPosition())
+
+	assert.Nil(t, m.UpdateTalkServiceID(ctx, nil, nil, talkIDs[0], 100))
+	md.(defs.CustomerObserver).OnServicerAttachMessage(talkIDs[0], 100)
+
+	p2, p3 := c2.lastPosition(), c3.lastPosition()
+	assert.Equal(t, 1, p2.Position)
+	assert.Equal(t, 2, p3.Position)
+	// one assignment gives no rate yet
+	assert.EqualValues(t, 0, p2.EstimatedWaitSeconds)
+	assert.EqualValues(t, 0, p3.EstimatedWaitSeconds)
+
+	// the rate is the span between the recent assignments
+	impl := md.(*customerMDImpl)
+	now := time.Now().Unix()
+	impl.assignTimes[impl.assignScopeKey("a1", "b1")] = []int64{now - 120, now - 60, now}
+
+	assert.EqualValues(t, 60, impl.estimateWaitSeconds("a1", "b1", 1, now))
+	assert.EqualValues(t, 120, impl.estimateWaitSeconds("a1", "b1", 2, now))
+	assert.EqualValues(t, 0, impl.estimateWaitSeconds("a2", "b1", 1, now))
+}
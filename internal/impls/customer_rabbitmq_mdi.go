@@ -77,6 +77,19 @@ func (impl *customerRabbitMQImpl) SendMessage(senderUniqueID uint64, talkID stri
 	_ = impl.rabbitMQ.SendData(d)
 }
 
+func (impl *customerRabbitMQImpl) SendMessageRevision(talkID string, revision *defs.MessageRevision) {
+	d := &mqData{
+		TalkID:          talkID,
+		MessageRevision: revision,
+	}
+
+	if args.RabbitMQUseSharedChannel {
+		d.ChannelID = specialTalkAll
+	}
+
+	_ = impl.rabbitMQ.SendData(d)
+}
+
 func (impl *customerRabbitMQImpl) SetCustomerObserver(ob defs.CustomerObserver) {
 	impl.rabbitMQ.SetCustomerObserver(ob)
 }
@@ -0,0 +1,204 @@
+package impls
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	_ "image/gif" // register gif decoder
+	"image/jpeg"
+	"image/png"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	defaultImageMaxSizeBytes     = 10 * 1024 * 1024
+	defaultImageThumbnailMaxEdge = 320
+	defaultImageMaxPixels        = 40 * 1024 * 1024
+	imageThumbnailJPEGQuality    = 80
+
+	// OriginalImageKeyPrefix prefixes the key of an original image, the key is
+	// "<talkID>/img-<hex sha256 of the thumbnail>", so clients can find it from the thumbnail.
+	OriginalImageKeyPrefix = "img-"
+)
+
+var defaultImageFormats = []string{"jpeg", "png", "gif"}
+
+// NewImageProcessor validates image messages, store could be nil, then the images are kept inline without thumbnails.
+func NewImageProcessor(cfg config.Image, store defs.BlobStore, logger l.Wrapper) defs.ImageProcessor {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	if cfg.MaxSizeBytes <= 0 {
+		cfg.MaxSizeBytes = defaultImageMaxSizeBytes
+	}
+
+	if len(cfg.Formats) == 0 {
+		cfg.Formats = defaultImageFormats
+	}
+
+	if cfg.ThumbnailMaxEdge <= 0 {
+		cfg.ThumbnailMaxEdge = defaultImageThumbnailMaxEdge
+	}
+
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultImageMaxPixels
+	}
+
+	return &imageProcessorImpl{
+		cfg:    cfg,
+		store:  store,
+		logger: logger.WithFields(l.StringField(l.ClsKey, "imageProcessorImpl")),
+	}
+}
+
+type imageProcessorImpl struct {
+	cfg    config.Image
+	store  defs.BlobStore
+	logger l.Wrapper
+}
+
+func (impl *imageProcessorImpl) Process(ctx context.Context, talkID string, message *talkinters.TalkMessageW) error {
+	if message == nil || message.Type != talkinters.TalkMessageTypeImage {
+		return nil
+	}
+
+	if len(message.Data) == 0 {
+		return defs.ErrImageInvalid
+	}
+
+	if int64(len(message.Data)) > impl.cfg.MaxSizeBytes {
+		return defs.ErrImageTooLarge
+	}
+
+	// the header is checked before the image is decoded, a small image could claim a huge canvas
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(message.Data))
+	if err != nil || imageConfig.Width <= 0 || imageConfig.Height <= 0 {
+		return defs.ErrImageInvalid
+	}
+
+	if !slices.Contains(impl.cfg.Formats, format) {
+		return defs.ErrImageFormat
+	}
+
+	if int64(imageConfig.Width)*int64(imageConfig.Height) > impl.cfg.MaxPixels {
+		return defs.ErrImageTooManyPixels
+	}
+
+	if impl.store == nil {
+		return nil
+	}
+
+	thumbnail := message.Data
+
+	if imageConfig.Width > impl.cfg.ThumbnailMaxEdge || imageConfig.Height > impl.cfg.ThumbnailMaxEdge {
+		thumbnail, err = impl.thumbnail(message.Data, format)
+		if err != nil {
+			return err
+		}
+	}
+
+	key := talkID + "/" + OriginalImageKeyPrefix + hexSHA256(thumbnail)
+
+	if err = impl.store.Put(ctx, key, &defs.BlobInfo{
+		ContentType: "image/" + format,
+		Size:        int64(len(message.Data)),
+	}, bytes.NewReader(message.Data)); err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("PutOriginalImageFailed")
+
+		return err
+	}
+
+	message.Text = key
+	message.Data = thumbnail
+
+	return nil
+}
+
+func (impl *imageProcessorImpl) thumbnail(d []byte, format string) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(d))
+	if err != nil {
+		return nil, defs.ErrImageInvalid
+	}
+
+	dst := resizeImage(src, impl.cfg.ThumbnailMaxEdge)
+
+	var buf bytes.Buffer
+
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: imageThumbnailJPEGQuality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resizeImage scales the image down to fit in maxEdge with a box filter.
+func resizeImage(src image.Image, maxEdge int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	tw, th := maxEdge, maxEdge
+	if w >= h {
+		th = h * maxEdge / w
+	} else {
+		tw = w * maxEdge / h
+	}
+
+	if tw < 1 {
+		tw = 1
+	}
+
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+
+	for y := 0; y < th; y++ {
+		sy0, sy1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+
+		for x := 0; x < tw; x++ {
+			sx0, sx1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, n uint64
+
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
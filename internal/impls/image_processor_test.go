@@ -0,0 +1,129 @@
+package impls
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+func TestImageProcessor(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewLocalBlobStore(t.TempDir())
+	assert.Nil(t, err)
+
+	processor := NewImageProcessor(config.Image{
+		ThumbnailMaxEdge: 100,
+	}, store, nil)
+
+	original := testPNG(t, 400, 200)
+
+	message := &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: original,
+	}
+	assert.Nil(t, processor.Process(ctx, "talk1", message))
+	assert.Equal(t, "talk1/"+OriginalImageKeyPrefix+hexSHA256(message.Data), message.Text)
+
+	thumbnailConfig, format, err := image.DecodeConfig(bytes.NewReader(message.Data))
+	assert.Nil(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 100, thumbnailConfig.Width)
+	assert.Equal(t, 50, thumbnailConfig.Height)
+
+	r, info, err := store.Get(ctx, message.Text)
+	assert.Nil(t, err)
+
+	d, _ := io.ReadAll(r)
+	_ = r.Close()
+
+	assert.Equal(t, original, d)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	assert.ErrorIs(t, processor.Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: []byte("not an image"),
+	}), defs.ErrImageInvalid)
+
+	jpegOnly := NewImageProcessor(config.Image{
+		MaxSizeBytes: 1024,
+		Formats:      []string{"jpeg"},
+	}, nil, nil)
+
+	assert.ErrorIs(t, jpegOnly.Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: testPNG(t, 4, 4),
+	}), defs.ErrImageFormat)
+
+	assert.ErrorIs(t, jpegOnly.Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: make([]byte, 2048),
+	}), defs.ErrImageTooLarge)
+
+	small := testPNG(t, 4, 4)
+	message = &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: small,
+	}
+	assert.Nil(t, NewImageProcessor(config.Image{}, nil, nil).Process(ctx, "talk1", message))
+	assert.Equal(t, small, message.Data)
+	assert.Equal(t, "", message.Text)
+}
+
+func TestImageProcessorMaxPixels(t *testing.T) {
+	ctx := context.Background()
+
+	processor := NewImageProcessor(config.Image{
+		MaxPixels: 100,
+	}, nil, nil)
+
+	assert.Nil(t, processor.Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: testPNG(t, 10, 10),
+	}))
+
+	err := processor.Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: testPNG(t, 11, 10),
+	})
+	assert.ErrorIs(t, err, defs.ErrImageTooManyPixels)
+	assert.ErrorIs(t, err, commerr.ErrInvalidArgument)
+
+	// a small file claims a huge canvas in its header, it's rejected before decoding
+	bomb := testPNG(t, 4, 4)
+	binary.BigEndian.PutUint32(bomb[16:20], 1<<20)
+	binary.BigEndian.PutUint32(bomb[20:24], 1<<20)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+
+	assert.ErrorIs(t, NewImageProcessor(config.Image{}, nil, nil).Process(ctx, "talk1", &talkinters.TalkMessageW{
+		Type: talkinters.TalkMessageTypeImage,
+		Data: bomb,
+	}), defs.ErrImageTooManyPixels)
+}
@@ -0,0 +1,201 @@
+package impls
+
+import (
+	"context"
+	"crypto/md5" // nolint: gosec
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+)
+
+// tokenKeyIDSeparator prefixes the tokens by the key ids, the tokens of the legacy key have no prefix.
+const tokenKeyIDSeparator = ":"
+
+// KeyedUserCenter signs the tokens by the active one of the keyed secrets, the keys can be rotated at runtime.
+type KeyedUserCenter interface {
+	userinters.UserCenter
+
+	// UpdateKeys replaces the keys, the tokens signed by the removed or retired keys are rejected since.
+	UpdateKeys(keys config.TokenKeys) error
+}
+
+type keyedUserCenterKey struct {
+	secret  string
+	retired bool
+	center  userinters.UserCenter
+}
+
+// NewKeyedUserCenter creates a user center per key by newUserCenter, the user centers must share
+// the status controller so the logouts apply to all keys.
+func NewKeyedUserCenter(keys config.TokenKeys, newUserCenter func(tokenSecKey string) userinters.UserCenter) (
+	KeyedUserCenter, error) {
+	impl := &keyedUserCenterImpl{
+		newUserCenter: newUserCenter,
+	}
+
+	if err := impl.UpdateKeys(keys); err != nil {
+		return nil, err
+	}
+
+	return impl, nil
+}
+
+type keyedUserCenterImpl struct {
+	newUserCenter func(tokenSecKey string) userinters.UserCenter
+
+	lock        sync.RWMutex
+	activeKeyID string
+	keys        map[string]*keyedUserCenterKey
+}
+
+func (impl *keyedUserCenterImpl) UpdateKeys(keys config.TokenKeys) error {
+	impl.lock.RLock()
+	oldKeys := impl.keys
+	impl.lock.RUnlock()
+
+	newKeys := make(map[string]*keyedUserCenterKey, len(keys.Keys))
+
+	for _, key := range keys.Keys {
+		if strings.Contains(key.ID, tokenKeyIDSeparator) {
+			return errors.New("invalid token key id " + key.ID)
+		}
+
+		if key.Secret == "" && key.ID != "" {
+			return errors.New("no secret for the token key " + key.ID)
+		}
+
+		if _, ok := newKeys[key.ID]; ok {
+			return errors.New("duplicated token key " + key.ID)
+		}
+
+		k := &keyedUserCenterKey{
+			secret:  key.Secret,
+			retired: key.Retired,
+		}
+
+		if oldKey, ok := oldKeys[key.ID]; ok && oldKey.secret == key.Secret {
+			k.center = oldKey.center
+		} else {
+			k.center = impl.newUserCenter(key.Secret)
+		}
+
+		newKeys[key.ID] = k
+	}
+
+	if activeKey, ok := newKeys[keys.ActiveKeyID]; !ok || activeKey.retired {
+		return errors.New("no active token key " + keys.ActiveKeyID)
+	}
+
+	impl.lock.Lock()
+	impl.activeKeyID = keys.ActiveKeyID
+	impl.keys = newKeys
+	impl.lock.Unlock()
+
+	return nil
+}
+
+func (impl *keyedUserCenterImpl) activeKey() (string, *keyedUserCenterKey) {
+	impl.lock.RLock()
+	defer impl.lock.RUnlock()
+
+	return impl.activeKeyID, impl.keys[impl.activeKeyID]
+}
+
+// tokenKey returns the non retired key of the token and the token without the key id.
+func (impl *keyedUserCenterImpl) tokenKey(token string) (*keyedUserCenterKey, string, error) {
+	var keyID string
+
+	if idx := strings.Index(token, tokenKeyIDSeparator); idx >= 0 {
+		keyID, token = token[:idx], token[idx+len(tokenKeyIDSeparator):]
+	}
+
+	impl.lock.RLock()
+	key, ok := impl.keys[keyID]
+	impl.lock.RUnlock()
+
+	if !ok || key.retired {
+		return nil, "", commerr.ErrUnauthenticated
+	}
+
+	return key, token, nil
+}
+
+func (impl *keyedUserCenterImpl) Login(ctx context.Context, request *userinters.LoginRequest) (
+	resp *userinters.LoginResponse, err error) {
+	keyID, key := impl.activeKey()
+
+	resp, err = key.center.Login(ctx, request)
+	if err != nil {
+		return
+	}
+
+	if resp.Token != "" {
+		resp.Token = keyedToken(keyID, resp.Token)
+	}
+
+	return
+}
+
+func (impl *keyedUserCenterImpl) Logout(ctx context.Context, token string) error {
+	key, token, err := impl.tokenKey(token)
+	if err != nil {
+		return err
+	}
+
+	return key.center.Logout(ctx, token)
+}
+
+func (impl *keyedUserCenterImpl) CheckToken(ctx context.Context, token string, renewToken bool) (newToken string,
+	uid uint64, tokenDataList map[string][]byte, err error) {
+	key, token, err := impl.tokenKey(token)
+	if err != nil {
+		return
+	}
+
+	newToken, uid, tokenDataList, err = key.center.CheckToken(ctx, token, renewToken)
+	if err != nil || newToken == "" {
+		return
+	}
+
+	activeKeyID, activeKey := impl.activeKey()
+	if activeKey.center != key.center {
+		// the renewed tokens move to the active key, so the old key can be retired
+		newToken, err = resignToken(newToken, activeKey.secret)
+		if err != nil {
+			return
+		}
+	}
+
+	newToken = keyedToken(activeKeyID, newToken)
+
+	return
+}
+
+func keyedToken(keyID, token string) string {
+	if keyID == "" {
+		return token
+	}
+
+	return keyID + tokenKeyIDSeparator + token
+}
+
+// resignToken signs the claims of a token issued by a userlib user center by another secret, the key is
+// derived from the secret as userlib does.
+func resignToken(token string, tokenSecKey string) (string, error) {
+	var claims userlib.UserClaims
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, &claims); err != nil {
+		return "", err
+	}
+
+	// nolint: gosec
+	h := md5.Sum([]byte(tokenSecKey))
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h[:])
+}
@@ -0,0 +1,97 @@
+package impls
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	"github.com/sbasestarter/userlib/authenticator/anonymous"
+	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
+	"github.com/sbasestarter/userlib/policy/single"
+	memorystatuscontroller "github.com/sbasestarter/userlib/statuscontroller/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+)
+
+func TestKeyedUserCenter(t *testing.T) {
+	ctx := context.Background()
+	statusController := memorystatuscontroller.NewStatusController()
+	authingDataStorage := memoryauthingdatastorage.NewMemoryAuthingDataStorage()
+
+	center, err := NewKeyedUserCenter(config.TokenKeys{}.WithLegacySecret("legacy"), func(tokenSecKey string) userinters.UserCenter {
+		return userlib.NewUserCenter(tokenSecKey, single.NewPolicy(userinters.AuthMethodNameAnonymous),
+			statusController, authingDataStorage, nil)
+	})
+	assert.Nil(t, err)
+
+	login := func() string {
+		resp, err := center.Login(ctx, &userinters.LoginRequest{
+			Authenticators:    []userinters.Authenticator{anonymous.NewAuthenticator(map[string]interface{}{"k": "v"})},
+			TokenLiveDuration: time.Hour,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, userinters.LoginStatusSuccess, resp.Status)
+
+		return resp.Token
+	}
+
+	legacyToken := login()
+	assert.False(t, strings.Contains(legacyToken, tokenKeyIDSeparator))
+
+	// the legacy tokens are accepted after the new key is activated
+	assert.Nil(t, center.UpdateKeys(config.TokenKeys{
+		ActiveKeyID: "k1",
+		Keys:        []config.TokenKey{{ID: "k1", Secret: "s1"}},
+	}.WithLegacySecret("legacy")))
+
+	k1Token := login()
+	assert.True(t, strings.HasPrefix(k1Token, "k1"+tokenKeyIDSeparator))
+
+	_, uid, tokenDataList, err := center.CheckToken(ctx, k1Token, false)
+	assert.Nil(t, err)
+	assert.NotZero(t, uid)
+	assert.Equal(t, `{"k":"v"}`, string(tokenDataList[userinters.AuthMethodNameAnonymous]))
+
+	renewed, legacyUID, tokenDataList, err := center.CheckToken(ctx, legacyToken, true)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"k":"v"}`, string(tokenDataList[userinters.AuthMethodNameAnonymous]))
+	assert.True(t, strings.HasPrefix(renewed, "k1"+tokenKeyIDSeparator))
+
+	// the tokens of the retired keys are rejected, the renewed ones were moved to the active key
+	assert.Nil(t, center.UpdateKeys(config.TokenKeys{
+		ActiveKeyID: "k1",
+		Keys:        []config.TokenKey{{ID: "k1", Secret: "s1"}, {Secret: "legacy", Retired: true}},
+	}))
+
+	_, _, _, err = center.CheckToken(ctx, legacyToken, false)
+	assert.NotNil(t, err)
+
+	_, uid, _, err = center.CheckToken(ctx, renewed, false)
+	assert.Nil(t, err)
+	assert.Equal(t, legacyUID, uid)
+
+	// the tampered key id
+	_, _, _, err = center.CheckToken(ctx, "k2"+strings.TrimPrefix(k1Token, "k1"), false)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, center.Logout(ctx, k1Token))
+
+	_, _, _, err = center.CheckToken(ctx, k1Token, false)
+	assert.NotNil(t, err)
+
+	// the keys are kept if the new ones are invalid
+	assert.NotNil(t, center.UpdateKeys(config.TokenKeys{
+		ActiveKeyID: "k2",
+		Keys:        []config.TokenKey{{ID: "k1", Secret: "s1"}},
+	}))
+	assert.NotNil(t, center.UpdateKeys(config.TokenKeys{
+		ActiveKeyID: "k1",
+		Keys:        []config.TokenKey{{ID: "k1", Secret: "s1", Retired: true}},
+	}))
+
+	_, _, _, err = center.CheckToken(ctx, renewed, false)
+	assert.Nil(t, err)
+}
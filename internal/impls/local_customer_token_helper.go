@@ -13,23 +13,27 @@ import (
 )
 
 const (
-	tokenKeyOnMetadata = "token"
+	tokenKeyOnMetadata    = "token"
+	identityKeyOnMetadata = "identity"
 
-	dKeyUserName = "un"
-	dKeyActID    = "a"
-	dKeyBizID    = "b"
+	dKeyUserName   = "un"
+	dKeyActID      = "a"
+	dKeyBizID      = "b"
+	dKeyExternalID = "x"
 )
 
-func NewLocalCustomerUserTokenHelper(user userinters.UserCenter) defs.CustomerUserTokenHelper {
+func NewLocalCustomerUserTokenHelper(user userinters.UserCenter, verifier defs.CustomerIdentityVerifier) defs.CustomerUserTokenHelper {
 	return &localCustomerUserTokenHelperImpl{
-		user:    user,
-		manager: anonymousmanager.NewManager(),
+		user:     user,
+		manager:  anonymousmanager.NewManager(),
+		verifier: verifier,
 	}
 }
 
 type localCustomerUserTokenHelperImpl struct {
-	user    userinters.UserCenter
-	manager anonymousmanager.Manager
+	user     userinters.UserCenter
+	manager  anonymousmanager.Manager
+	verifier defs.CustomerIdentityVerifier
 }
 
 func (impl *localCustomerUserTokenHelperImpl) NewAnonymousAuthenticator(userName, actID, bizID string) userinters.Authenticator {
@@ -40,6 +44,51 @@ func (impl *localCustomerUserTokenHelperImpl) NewAnonymousAuthenticator(userName
 	})
 }
 
+func (impl *localCustomerUserTokenHelperImpl) NewVerifiedAuthenticator(identity *defs.CustomerIdentity) userinters.Authenticator {
+	return anonymousauthenticator.NewAuthenticator(map[string]interface{}{
+		dKeyUserName:   identity.UserName,
+		dKeyActID:      identity.ActID,
+		dKeyBizID:      identity.BizID,
+		dKeyExternalID: identity.ExternalID,
+	})
+}
+
+func (impl *localCustomerUserTokenHelperImpl) ExtractIdentityTokenFromGRPCContext(ctx context.Context) (identityToken string, err error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		err = commerr.ErrNotFound
+
+		return
+	}
+
+	identityTokens := md.Get(identityKeyOnMetadata)
+	if len(identityTokens) == 0 || identityTokens[0] == "" {
+		err = commerr.ErrNotFound
+
+		return
+	}
+
+	identityToken = identityTokens[0]
+
+	return
+}
+
+func (impl *localCustomerUserTokenHelperImpl) VerifyIdentity(identityToken string) (userID uint64,
+	identity *defs.CustomerIdentity, err error) {
+	identity, err = impl.verifier.Verify(identityToken)
+	if err != nil {
+		return
+	}
+
+	userID = verifiedCustomerUserID(identity.ActID, identity.ExternalID)
+
+	return
+}
+
+func (impl *localCustomerUserTokenHelperImpl) IdentityRequired(actID string) bool {
+	return impl.verifier.Required(actID)
+}
+
 func (impl *localCustomerUserTokenHelperImpl) ExtractTokenFromGRPCContext(ctx context.Context) (token string, err error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -82,6 +131,13 @@ func (impl *localCustomerUserTokenHelperImpl) ExplainToken(ctx context.Context,
 
 	userName, actID, bizID = impl.parseDS(ds)
 
+	// the verified customers share the user id bound to the external id on all devices
+	if externalID := cast.ToString(ds[dKeyExternalID]); externalID != "" {
+		userID = verifiedCustomerUserID(actID, externalID)
+	} else if impl.verifier.Required(actID) {
+		err = commerr.ErrUnauthenticated
+	}
+
 	return
 }
 
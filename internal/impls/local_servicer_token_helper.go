@@ -2,6 +2,15 @@ package impls
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godruoyi/go-snowflake"
 
 	"github.com/sbasestarter/bizinters/userinters"
 	"github.com/sbasestarter/userlib/manager/userpass"
@@ -12,22 +21,32 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+const (
+	sessionTouchIntervalSeconds = 60
+)
+
 const (
 	dKeyPermission   = "permission"
 	dKeyExDataActIDs = "actIDs"
 	dKeyExDataBizIDs = "bizIDs"
+	dKeyExDataRoles  = "roles"
 )
 
-func NewLocalServicerUserTokenHelper(user userinters.UserCenter, manager userpass.Manager) defs.ServicerUserTokenHelper {
+// NewLocalServicerUserTokenHelper explains the tokens of the sessions kept by the status controller, the status
+// controller should be the one of the user center.
+func NewLocalServicerUserTokenHelper(user userinters.UserCenter, manager userpass.Manager,
+	status defs.ServicerStatusController) defs.ServicerUserTokenHelper {
 	return &localServicerUserTokenHelperImpl{
 		user:    user,
 		manager: manager,
+		status:  status,
 	}
 }
 
 type localServicerUserTokenHelperImpl struct {
 	user    userinters.UserCenter
 	manager userpass.Manager
+	status  defs.ServicerStatusController
 }
 
 func (impl *localServicerUserTokenHelperImpl) ExtractTokenFromGRPCContext(ctx context.Context) (token string, err error) {
@@ -51,25 +70,229 @@ func (impl *localServicerUserTokenHelperImpl) ExtractTokenFromGRPCContext(ctx co
 }
 
 func (impl *localServicerUserTokenHelperImpl) ExplainToken(ctx context.Context, token string,
-	renewToken bool) (newToken string, userID uint64, userName string, admin bool, actIDs, bizIDs []string, err error) {
-	newToken, userID, _, err = impl.user.CheckToken(ctx, token, renewToken)
+	renewToken bool) (newToken string, userID uint64, userName string, roles defs.ServicerRoles, actIDs, bizIDs []string, err error) {
+	newToken, user, _, err := impl.explainToken(ctx, token, renewToken, false)
+	if err != nil {
+		return
+	}
+
+	userID = user.ID
+	userName = user.UserName
+	actIDs = parseIDs(user.ExData[dKeyExDataActIDs])
+	bizIDs = parseIDs(user.ExData[dKeyExDataBizIDs])
+	roles = servicerRoles(user.ExData)
+
+	return
+}
+
+// explainToken rejects the tokens of the disabled users, the tokens issued before the password changes and the
+// tokens of the revoked sessions. The tokens of the users who must change the password are rejected too, unless
+// it's for the change.
+func (impl *localServicerUserTokenHelperImpl) explainToken(ctx context.Context, token string, renewToken,
+	passwordChange bool) (
+	newToken string, user *userpass.User, session *defs.ServicerSession, err error) {
+	newToken, userID, tokenDataList, err := impl.user.CheckToken(ctx, token, renewToken)
+	if err != nil {
+		return
+	}
+
+	user, err = impl.manager.GetUser(ctx, userID)
 	if err != nil {
 		return
 	}
 
+	if cast.ToBool(user.ExData[dKeyDisabled]) {
+		err = commerr.ErrPermissionDenied
+
+		return
+	}
+
+	tokenVersion, sessionID := parseServicerTokenData(tokenDataList[userinters.AuthMethodNameUserPassword])
+	if tokenVersion != cast.ToInt64(user.ExData[dKeyTokenVersion]) || sessionID == "" {
+		err = commerr.ErrUnauthenticated
+
+		return
+	}
+
+	session, err = impl.status.GetSession(ctx, sessionID)
+	if errors.Is(err, commerr.ErrNotFound) || (err == nil && session.UserID != userID) {
+		err = commerr.ErrUnauthenticated
+	}
+
+	if err != nil {
+		return
+	}
+
+	if !passwordChange && cast.ToBool(user.ExData[dKeyPasswordChangeRequired]) {
+		err = defs.ErrPasswordChangeRequired
+
+		return
+	}
+
+	now := time.Now().Unix()
+
+	var expireAt int64
+
+	if newToken != "" {
+		expireAt = tokenExpireAt(newToken)
+	}
+
+	if expireAt != 0 || now-session.LastSeenAt >= sessionTouchIntervalSeconds {
+		_ = impl.status.TouchSession(ctx, session.ID, now, expireAt)
+	}
+
+	return
+}
+
+func (impl *localServicerUserTokenHelperImpl) StartSession(ctx context.Context, token, device, ip string) (
+	*defs.ServicerSession, error) {
+	_, userID, tokenDataList, err := impl.user.CheckToken(ctx, token, false)
+	if err != nil {
+		return nil, err
+	}
+
+	_, sessionID := parseServicerTokenData(tokenDataList[userinters.AuthMethodNameUserPassword])
+	if sessionID == "" {
+		return nil, commerr.ErrUnauthenticated
+	}
+
+	now := time.Now().Unix()
+
+	session := &defs.ServicerSession{
+		ID:         sessionID,
+		UserID:     userID,
+		Device:     device,
+		IP:         ip,
+		CreateAt:   now,
+		LastSeenAt: now,
+		ExpireAt:   tokenExpireAt(token),
+	}
+
+	if err = impl.status.AddSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (impl *localServicerUserTokenHelperImpl) ExtractSessionFromGRPCContext(ctx context.Context) (*defs.ServicerSession, error) {
+	token, err := impl.ExtractTokenFromGRPCContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, session, err := impl.explainToken(ctx, token, false, false)
+
+	return session, err
+}
+
+func (impl *localServicerUserTokenHelperImpl) QuerySessions(ctx context.Context, userID uint64) ([]*defs.ServicerSession, error) {
+	return impl.status.QuerySessions(ctx, userID, time.Now().Unix())
+}
+
+func (impl *localServicerUserTokenHelperImpl) RevokeSession(ctx context.Context, id string) error {
+	return impl.status.DeleteSession(ctx, id)
+}
+
+func (impl *localServicerUserTokenHelperImpl) RevokeUserSessions(ctx context.Context, userID uint64) error {
+	return impl.status.DeleteUserSessions(ctx, userID)
+}
+
+// parseServicerTokenData parses the "tokenVersion:sessionID" token data of the password authenticator.
+func parseServicerTokenData(d []byte) (tokenVersion int64, sessionID string) {
+	parts := strings.SplitN(string(d), ":", 2)
+
+	tokenVersion = cast.ToInt64(parts[0])
+
+	if len(parts) == 2 {
+		sessionID = parts[1]
+	}
+
+	return
+}
+
+// tokenExpireAt reads the expiration of a verified token, which may be prefixed by the key id.
+func tokenExpireAt(token string) int64 {
+	if idx := strings.Index(token, tokenKeyIDSeparator); idx >= 0 {
+		token = token[idx+len(tokenKeyIDSeparator):]
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+
+	if err = json.Unmarshal(d, &claims); err != nil {
+		return 0
+	}
+
+	return claims.ExpiresAt
+}
+
+func (impl *localServicerUserTokenHelperImpl) GetServicerRoles(ctx context.Context, userID uint64) (actIDs []string,
+	roles defs.ServicerRoles, err error) {
 	user, err := impl.manager.GetUser(ctx, userID)
 	if err != nil {
 		return
 	}
 
-	userName = user.UserName
 	actIDs = parseIDs(user.ExData[dKeyExDataActIDs])
-	bizIDs = parseIDs(user.ExData[dKeyExDataBizIDs])
-	admin = cast.ToInt64(user.ExData[dKeyPermission]) > 0
+	roles = servicerRoles(user.ExData)
 
 	return
 }
 
+// servicerRoles parses the "actID:role" entries, the servicers without the entries have the role of the legacy
+// permission flag in all their acts: admin if it's set, agent otherwise.
+func servicerRoles(exData map[string]interface{}) defs.ServicerRoles {
+	roles := make(defs.ServicerRoles)
+
+	d, ok := exData[dKeyExDataRoles]
+	if !ok {
+		roles[defs.AllActs] = defs.ServicerRoleAgent
+
+		if cast.ToInt64(exData[dKeyPermission]) > 0 {
+			roles[defs.AllActs] = defs.ServicerRoleAdmin
+		}
+
+		return roles
+	}
+
+	for _, entry := range parseIDs(d) {
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			continue
+		}
+
+		if role := defs.ServicerRole(entry[idx+1:]); role.Valid() {
+			roles[entry[:idx]] = role
+		}
+	}
+
+	return roles
+}
+
+func rolesExData(roles defs.ServicerRoles) []string {
+	entries := make([]string, 0, len(roles))
+
+	for actID, role := range roles {
+		entries = append(entries, actID+":"+string(role))
+	}
+
+	sort.Strings(entries)
+
+	return entries
+}
+
 func parseIDs(d interface{}) (ids []string) {
 	if d == nil {
 		return
@@ -92,13 +315,70 @@ func parseIDs(d interface{}) (ids []string) {
 }
 
 func (impl *localServicerUserTokenHelperImpl) ExtractUserFromGRPCContext(ctx context.Context,
-	renewToken bool) (newToken string, userID uint64, userName string, admin bool, actIDs, bizIDs []string, err error) {
+	renewToken bool) (newToken string, userID uint64, userName string, roles defs.ServicerRoles, actIDs, bizIDs []string, err error) {
 	token, err := impl.ExtractTokenFromGRPCContext(ctx)
 	if err != nil {
 		return
 	}
 
-	newToken, userID, userName, admin, actIDs, bizIDs, err = impl.ExplainToken(ctx, token, renewToken)
+	newToken, userID, userName, roles, actIDs, bizIDs, err = impl.ExplainToken(ctx, token, renewToken)
+
+	return
+}
+
+func (impl *localServicerUserTokenHelperImpl) ExtractUserForPasswordChange(ctx context.Context) (userID uint64,
+	userName string, err error) {
+	token, err := impl.ExtractTokenFromGRPCContext(ctx)
+	if err != nil {
+		return
+	}
+
+	_, user, _, err := impl.explainToken(ctx, token, false, true)
+	if err != nil {
+		return
+	}
+
+	userID = user.ID
+	userName = user.UserName
+
+	return
+}
+
+// NewAuthenticator verifies the user password, the token version of the user and a new session id are kept in the
+// token data, the renewed tokens keep them.
+func (impl *localServicerUserTokenHelperImpl) NewAuthenticator(userName, password string) userinters.Authenticator {
+	return &servicerAuthenticatorImpl{
+		userName: userName,
+		password: password,
+		manager:  impl.manager,
+	}
+}
+
+type servicerAuthenticatorImpl struct {
+	userName string
+	password string
+	manager  userpass.Manager
+}
+
+func (impl *servicerAuthenticatorImpl) GetMethodName() string {
+	return userinters.AuthMethodNameUserPassword
+}
+
+func (impl *servicerAuthenticatorImpl) Verify(ctx context.Context) (uid uint64, tokenData []byte, ok bool, err error) {
+	uid, ok, err = impl.manager.Verify(ctx, impl.userName, impl.password)
+	if err != nil || !ok {
+		return
+	}
+
+	user, err := impl.manager.GetUser(ctx, uid)
+	if err != nil {
+		ok = false
+
+		return
+	}
+
+	tokenData = []byte(strconv.FormatInt(cast.ToInt64(user.ExData[dKeyTokenVersion]), 10) + ":" +
+		strconv.FormatUint(snowflake.ID(), 10))
 
 	return
 }
@@ -110,3 +390,9 @@ func (impl *localServicerUserTokenHelperImpl) GenExData(actIDs, bizIDs []string)
 
 	return m
 }
+
+func (impl *localServicerUserTokenHelperImpl) GenRolesExData(roles defs.ServicerRoles) map[string]interface{} {
+	return map[string]interface{}{
+		dKeyExDataRoles: rolesExData(roles),
+	}
+}
@@ -0,0 +1,197 @@
+package impls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	defaultLoginMaxFailures          = 5
+	defaultLoginIPMaxFailures        = 20
+	defaultLoginDelaySeconds         = 1
+	defaultLoginMaxDelaySeconds      = 30
+	defaultLoginLockoutSeconds       = 900
+	defaultLoginFailureWindowSeconds = 900
+)
+
+// NewLoginGuard tracks the login failures of the user names and the client ips, the lockouts are audited.
+func NewLoginGuard(cfg config.LoginGuard, model defs.LoginFailuresModel, auditM defs.AuditLogModel, logger l.Wrapper) defs.LoginGuard {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	if model == nil || auditM == nil {
+		logger.Fatal("invalid input args")
+	}
+
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultLoginMaxFailures
+	}
+
+	if cfg.IPMaxFailures <= 0 {
+		cfg.IPMaxFailures = defaultLoginIPMaxFailures
+	}
+
+	if cfg.DelaySeconds <= 0 {
+		cfg.DelaySeconds = defaultLoginDelaySeconds
+	}
+
+	if cfg.MaxDelaySeconds <= 0 {
+		cfg.MaxDelaySeconds = defaultLoginMaxDelaySeconds
+	}
+
+	if cfg.LockoutSeconds <= 0 {
+		cfg.LockoutSeconds = defaultLoginLockoutSeconds
+	}
+
+	if cfg.FailureWindowSeconds <= 0 {
+		cfg.FailureWindowSeconds = defaultLoginFailureWindowSeconds
+	}
+
+	return &loginGuardImpl{
+		cfg:    cfg,
+		model:  model,
+		auditM: auditM,
+		logger: logger.WithFields(l.StringField(l.ClsKey, "loginGuardImpl")),
+		now:    time.Now,
+	}
+}
+
+type loginGuardImpl struct {
+	cfg    config.LoginGuard
+	model  defs.LoginFailuresModel
+	auditM defs.AuditLogModel
+	logger l.Wrapper
+	now    func() time.Time
+}
+
+func loginUserKey(userName string) string {
+	return "u:" + userName
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (impl *loginGuardImpl) keys(userName, ip string) (keys []string) {
+	keys = append(keys, loginUserKey(userName))
+
+	if ip != "" {
+		keys = append(keys, loginIPKey(ip))
+	}
+
+	return
+}
+
+func (impl *loginGuardImpl) Check(ctx context.Context, userName, ip string) (retryAfter time.Duration, err error) {
+	now := impl.now().Unix()
+
+	for _, key := range impl.keys(userName, ip) {
+		failures, errGet := impl.getFailures(ctx, key, now)
+		if errGet != nil {
+			err = errGet
+
+			return
+		}
+
+		var until int64
+
+		if failures.LockedUntil > now {
+			until = failures.LockedUntil
+		} else if failures.Count > 0 {
+			until = failures.LastFailedAt + impl.delaySeconds(failures.Count)
+		}
+
+		if d := time.Duration(until-now) * time.Second; d > retryAfter {
+			retryAfter = d
+		}
+	}
+
+	return
+}
+
+func (impl *loginGuardImpl) Fail(ctx context.Context, userName, ip string) error {
+	now := impl.now().Unix()
+
+	for _, key := range impl.keys(userName, ip) {
+		failures, err := impl.getFailures(ctx, key, now)
+		if err != nil {
+			return err
+		}
+
+		maxFailures := impl.cfg.MaxFailures
+		if key != loginUserKey(userName) {
+			maxFailures = impl.cfg.IPMaxFailures
+		}
+
+		failures.Count++
+		failures.LastFailedAt = now
+
+		if failures.Count >= maxFailures {
+			failures.LockedUntil = now + impl.cfg.LockoutSeconds
+
+			impl.audit(ctx, &defs.AuditLog{
+				At:     now,
+				Action: defs.AuditActionServicerLockout,
+				Target: key,
+				Detail: fmt.Sprintf("failures:%d lockedUntil:%d", failures.Count, failures.LockedUntil),
+			})
+		}
+
+		if err = impl.model.SetLoginFailures(ctx, failures); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Succeed forgets the failures of the user name, the failures of the client ip are kept, otherwise a valid
+// account resets the tracking of the ip.
+func (impl *loginGuardImpl) Succeed(ctx context.Context, userName, _ string) error {
+	return impl.model.DeleteLoginFailures(ctx, loginUserKey(userName))
+}
+
+func (impl *loginGuardImpl) Unlock(ctx context.Context, userName string) error {
+	return impl.model.DeleteLoginFailures(ctx, loginUserKey(userName))
+}
+
+// getFailures returns the failures of the key, they are reset after the lockout or the quiet window.
+func (impl *loginGuardImpl) getFailures(ctx context.Context, key string, now int64) (*defs.LoginFailures, error) {
+	failures, err := impl.model.GetLoginFailures(ctx, key)
+	if errors.Is(err, commerr.ErrNotFound) || err == nil && (failures.LockedUntil > 0 && failures.LockedUntil <= now ||
+		failures.LockedUntil == 0 && failures.LastFailedAt+impl.cfg.FailureWindowSeconds <= now) {
+		return &defs.LoginFailures{
+			Key: key,
+		}, nil
+	}
+
+	return failures, err
+}
+
+func (impl *loginGuardImpl) delaySeconds(count int) int64 {
+	delay := impl.cfg.DelaySeconds
+
+	for idx := 1; idx < count && delay < impl.cfg.MaxDelaySeconds; idx++ {
+		delay *= 2
+	}
+
+	if delay > impl.cfg.MaxDelaySeconds {
+		delay = impl.cfg.MaxDelaySeconds
+	}
+
+	return delay
+}
+
+func (impl *loginGuardImpl) audit(ctx context.Context, log *defs.AuditLog) {
+	if err := impl.auditM.AddAuditLog(ctx, log); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("action", log.Action)).Error("AddAuditLogFailed")
+	}
+}
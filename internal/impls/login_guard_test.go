@@ -0,0 +1,77 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+	auditM := NewMemAuditLogModel()
+	guard := NewLoginGuard(config.LoginGuard{
+		MaxFailures:     3,
+		IPMaxFailures:   5,
+		DelaySeconds:    1,
+		MaxDelaySeconds: 2,
+		LockoutSeconds:  60,
+	}, NewMemLoginFailuresModel(), auditM, nil)
+
+	now := time.Unix(1000, 0)
+	guard.(*loginGuardImpl).now = func() time.Time {
+		return now
+	}
+
+	check := func(userName, ip string) time.Duration {
+		retryAfter, err := guard.Check(ctx, userName, ip)
+		assert.Nil(t, err)
+
+		return retryAfter
+	}
+
+	assert.Zero(t, check("alice", "ip1"))
+
+	assert.Nil(t, guard.Fail(ctx, "alice", "ip1"))
+	assert.Equal(t, time.Second, check("alice", "ip1"))
+	assert.Equal(t, time.Second, check("bob", "ip1"))
+	assert.Zero(t, check("bob", "ip2"))
+
+	now = now.Add(time.Second)
+	assert.Zero(t, check("alice", "ip1"))
+
+	assert.Nil(t, guard.Fail(ctx, "alice", "ip1"))
+	assert.Equal(t, 2*time.Second, check("alice", "ip2"))
+
+	now = now.Add(2 * time.Second)
+	assert.Nil(t, guard.Fail(ctx, "alice", "ip2"))
+	assert.Equal(t, time.Minute, check("alice", "ip3"))
+
+	logs, err := auditM.QueryAuditLogs(ctx, nil, 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, defs.AuditActionServicerLockout, logs[0].Action)
+	assert.Equal(t, "u:alice", logs[0].Target)
+
+	assert.Nil(t, guard.Unlock(ctx, "alice"))
+	assert.Zero(t, check("alice", "ip3"))
+
+	// the lockout expires
+	for idx := 0; idx < 3; idx++ {
+		assert.Nil(t, guard.Fail(ctx, "carol", ""))
+	}
+
+	assert.Equal(t, time.Minute, check("carol", ""))
+
+	now = now.Add(time.Minute)
+	assert.Zero(t, check("carol", ""))
+
+	// the failures of the ip are kept after a success
+	assert.Nil(t, guard.Fail(ctx, "dave", "ip4"))
+	assert.Nil(t, guard.Succeed(ctx, "dave", "ip4"))
+	assert.Zero(t, check("dave", "ip5"))
+	assert.Equal(t, time.Second, check("dave", "ip4"))
+}
@@ -0,0 +1,63 @@
+package impls
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemAuditLogModel() defs.AuditLogModel {
+	return &memAuditLogModelImpl{}
+}
+
+type memAuditLogModelImpl struct {
+	logsLock sync.Mutex
+	logs     []*defs.AuditLog
+}
+
+func (impl *memAuditLogModelImpl) AddAuditLog(ctx context.Context, log *defs.AuditLog) error {
+	if log == nil || log.Action == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	if log.ID == "" {
+		log.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	impl.logsLock.Lock()
+	defer impl.logsLock.Unlock()
+
+	logCopy := *log
+	impl.logs = append(impl.logs, &logCopy)
+
+	return nil
+}
+
+func (impl *memAuditLogModelImpl) QueryAuditLogs(ctx context.Context, actIDs []string, startAt, finishAt int64) (logs []*defs.AuditLog, err error) {
+	impl.logsLock.Lock()
+	defer impl.logsLock.Unlock()
+
+	for _, log := range impl.logs {
+		if len(actIDs) > 0 && !slices.Contains(actIDs, log.ActID) {
+			continue
+		}
+
+		if startAt > 0 && log.At < startAt {
+			continue
+		}
+
+		if finishAt > 0 && log.At >= finishAt {
+			continue
+		}
+
+		logCopy := *log
+		logs = append(logs, &logCopy)
+	}
+
+	return
+}
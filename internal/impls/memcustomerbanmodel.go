@@ -0,0 +1,106 @@
+package impls
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemCustomerBanModel() defs.CustomerBanModel {
+	return &memCustomerBanModelImpl{
+		bans: make(map[string]*defs.CustomerBan),
+	}
+}
+
+type memCustomerBanModelImpl struct {
+	bansLock sync.Mutex
+	bans     map[string]*defs.CustomerBan
+}
+
+func (impl *memCustomerBanModelImpl) AddCustomerBan(ctx context.Context, ban *defs.CustomerBan) error {
+	if ban == nil || ban.ActID == "" || (ban.UserID == 0 && ban.UserName == "") {
+		return commerr.ErrInvalidArgument
+	}
+
+	if ban.ID == "" {
+		ban.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	impl.bansLock.Lock()
+	defer impl.bansLock.Unlock()
+
+	banCopy := *ban
+	impl.bans[ban.ID] = &banCopy
+
+	return nil
+}
+
+func (impl *memCustomerBanModelImpl) GetCustomerBan(ctx context.Context, id string) (*defs.CustomerBan, error) {
+	impl.bansLock.Lock()
+	defer impl.bansLock.Unlock()
+
+	ban, ok := impl.bans[id]
+	if !ok {
+		return nil, commerr.ErrNotFound
+	}
+
+	banCopy := *ban
+
+	return &banCopy, nil
+}
+
+func (impl *memCustomerBanModelImpl) RevokeCustomerBan(ctx context.Context, id string, at int64, revokerID uint64) error {
+	impl.bansLock.Lock()
+	defer impl.bansLock.Unlock()
+
+	ban, ok := impl.bans[id]
+	if !ok || ban.RevokedAt != 0 {
+		return commerr.ErrNotFound
+	}
+
+	ban.RevokedAt = at
+	ban.RevokerID = revokerID
+
+	return nil
+}
+
+func (impl *memCustomerBanModelImpl) QueryCustomerBans(ctx context.Context, actIDs []string, activeAt int64) (bans []*defs.CustomerBan, err error) {
+	impl.bansLock.Lock()
+	defer impl.bansLock.Unlock()
+
+	for _, ban := range impl.bans {
+		if len(actIDs) > 0 && !slices.Contains(actIDs, ban.ActID) {
+			continue
+		}
+
+		if activeAt > 0 && !ban.Active(activeAt) {
+			continue
+		}
+
+		banCopy := *ban
+		bans = append(bans, &banCopy)
+	}
+
+	return
+}
+
+func (impl *memCustomerBanModelImpl) FindActiveCustomerBan(ctx context.Context, userID uint64, userName, actID string,
+	now int64) (*defs.CustomerBan, error) {
+	impl.bansLock.Lock()
+	defer impl.bansLock.Unlock()
+
+	for _, ban := range impl.bans {
+		if ban.Active(now) && ban.Match(userID, userName, actID) {
+			banCopy := *ban
+
+			return &banCopy, nil
+		}
+	}
+
+	return nil, commerr.ErrNotFound
+}
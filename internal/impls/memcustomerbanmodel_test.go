@@ -0,0 +1,52 @@
+package impls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestMemCustomerBanModel(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemCustomerBanModel()
+
+	assert.ErrorIs(t, m.AddCustomerBan(ctx, &defs.CustomerBan{ActID: "a1"}), commerr.ErrInvalidArgument)
+
+	userBan := &defs.CustomerBan{ActID: "a1", UserID: 10, CreatedAt: 100, ExpireAt: 200}
+	assert.Nil(t, m.AddCustomerBan(ctx, userBan))
+	assert.NotEmpty(t, userBan.ID)
+
+	nameBan := &defs.CustomerBan{ActID: "a1", UserName: "spammer", CreatedAt: 100}
+	assert.Nil(t, m.AddCustomerBan(ctx, nameBan))
+
+	ban, err := m.FindActiveCustomerBan(ctx, 10, "anyone", "a1", 150)
+	assert.Nil(t, err)
+	assert.Equal(t, userBan.ID, ban.ID)
+
+	_, err = m.FindActiveCustomerBan(ctx, 10, "anyone", "a1", 200)
+	assert.ErrorIs(t, err, commerr.ErrNotFound)
+
+	_, err = m.FindActiveCustomerBan(ctx, 10, "anyone", "a2", 150)
+	assert.ErrorIs(t, err, commerr.ErrNotFound)
+
+	ban, err = m.FindActiveCustomerBan(ctx, 0, "spammer", "a1", 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, nameBan.ID, ban.ID)
+
+	bans, err := m.QueryCustomerBans(ctx, []string{"a1"}, 1000)
+	assert.Nil(t, err)
+	assert.Len(t, bans, 1)
+
+	assert.Nil(t, m.RevokeCustomerBan(ctx, nameBan.ID, 1000, 1))
+	assert.ErrorIs(t, m.RevokeCustomerBan(ctx, nameBan.ID, 1000, 1), commerr.ErrNotFound)
+
+	_, err = m.FindActiveCustomerBan(ctx, 0, "spammer", "a1", 1001)
+	assert.ErrorIs(t, err, commerr.ErrNotFound)
+
+	bans, err = m.QueryCustomerBans(ctx, nil, 0)
+	assert.Nil(t, err)
+	assert.Len(t, bans, 2)
+}
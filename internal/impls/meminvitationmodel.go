@@ -0,0 +1,121 @@
+package impls
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemInvitationModel() defs.ServicerInvitationModel {
+	return &memInvitationModelImpl{
+		invitations: make(map[string]*defs.ServicerInvitation),
+	}
+}
+
+type memInvitationModelImpl struct {
+	invitationsLock sync.Mutex
+	invitations     map[string]*defs.ServicerInvitation
+}
+
+func (impl *memInvitationModelImpl) AddInvitation(ctx context.Context, invitation *defs.ServicerInvitation) error {
+	if invitation == nil || invitation.ID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	if _, ok := impl.invitations[invitation.ID]; ok {
+		return commerr.ErrAlreadyExists
+	}
+
+	invitationCopy := *invitation
+	impl.invitations[invitation.ID] = &invitationCopy
+
+	return nil
+}
+
+func (impl *memInvitationModelImpl) GetInvitation(ctx context.Context, id string) (*defs.ServicerInvitation, error) {
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	invitation, ok := impl.invitations[id]
+	if !ok {
+		return nil, commerr.ErrNotFound
+	}
+
+	invitationCopy := *invitation
+
+	return &invitationCopy, nil
+}
+
+func (impl *memInvitationModelImpl) UseInvitation(ctx context.Context, id, userName string, now int64) (*defs.ServicerInvitation, error) {
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	invitation, ok := impl.invitations[id]
+	if !ok || invitation.UsedAt != 0 || invitation.ExpireAt <= now {
+		return nil, commerr.ErrNotFound
+	}
+
+	invitation.UsedAt = now
+	invitation.UsedBy = userName
+
+	invitationCopy := *invitation
+
+	return &invitationCopy, nil
+}
+
+func (impl *memInvitationModelImpl) ReleaseInvitation(ctx context.Context, id string) error {
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	invitation, ok := impl.invitations[id]
+	if !ok {
+		return commerr.ErrNotFound
+	}
+
+	invitation.UsedAt = 0
+	invitation.UsedBy = ""
+
+	return nil
+}
+
+func (impl *memInvitationModelImpl) QueryInvitations(ctx context.Context, actIDs []string) (invitations []*defs.ServicerInvitation, err error) {
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	for _, invitation := range impl.invitations {
+		if len(actIDs) > 0 && slices.IndexFunc(invitation.ActIDs, func(actID string) bool {
+			return slices.Contains(actIDs, actID)
+		}) < 0 {
+			continue
+		}
+
+		invitationCopy := *invitation
+		invitations = append(invitations, &invitationCopy)
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreateAt > invitations[j].CreateAt
+	})
+
+	return
+}
+
+func (impl *memInvitationModelImpl) DeleteInvitation(ctx context.Context, id string) error {
+	impl.invitationsLock.Lock()
+	defer impl.invitationsLock.Unlock()
+
+	if _, ok := impl.invitations[id]; !ok {
+		return commerr.ErrNotFound
+	}
+
+	delete(impl.invitations, id)
+
+	return nil
+}
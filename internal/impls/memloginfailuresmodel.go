@@ -0,0 +1,57 @@
+package impls
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func NewMemLoginFailuresModel() defs.LoginFailuresModel {
+	return &memLoginFailuresModelImpl{
+		failures: make(map[string]*defs.LoginFailures),
+	}
+}
+
+type memLoginFailuresModelImpl struct {
+	failuresLock sync.Mutex
+	failures     map[string]*defs.LoginFailures
+}
+
+func (impl *memLoginFailuresModelImpl) GetLoginFailures(ctx context.Context, key string) (*defs.LoginFailures, error) {
+	impl.failuresLock.Lock()
+	defer impl.failuresLock.Unlock()
+
+	failures, ok := impl.failures[key]
+	if !ok {
+		return nil, commerr.ErrNotFound
+	}
+
+	failuresCopy := *failures
+
+	return &failuresCopy, nil
+}
+
+func (impl *memLoginFailuresModelImpl) SetLoginFailures(ctx context.Context, failures *defs.LoginFailures) error {
+	if failures == nil || failures.Key == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.failuresLock.Lock()
+	defer impl.failuresLock.Unlock()
+
+	failuresCopy := *failures
+	impl.failures[failures.Key] = &failuresCopy
+
+	return nil
+}
+
+func (impl *memLoginFailuresModelImpl) DeleteLoginFailures(ctx context.Context, key string) error {
+	impl.failuresLock.Lock()
+	defer impl.failuresLock.Unlock()
+
+	delete(impl.failures, key)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package impls
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func NewMemMessageExModel() defs.TalkMessageExModel {
+	return &memMessageExModelImpl{
+		messageExs: make(map[string][]*defs.TalkMessageEx),
+	}
+}
+
+type memMessageExModelImpl struct {
+	messageExsLock sync.Mutex
+	messageExs     map[string][]*defs.TalkMessageEx // talkID - messages
+}
+
+func (impl *memMessageExModelImpl) AddTalkMessageEx(ctx context.Context, messageEx *defs.TalkMessageEx) error {
+	if messageEx == nil || messageEx.TalkID == "" || messageEx.MessageID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	impl.messageExs[messageEx.TalkID] = append(impl.messageExs[messageEx.TalkID], impl.clone(messageEx))
+
+	return nil
+}
+
+func (impl *memMessageExModelImpl) RemoveTalkMessageEx(ctx context.Context, talkID, messageID string) error {
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	for idx, messageEx := range impl.messageExs[talkID] {
+		if messageEx.MessageID == messageID {
+			impl.messageExs[talkID] = append(impl.messageExs[talkID][:idx], impl.messageExs[talkID][idx+1:]...)
+
+			return nil
+		}
+	}
+
+	return commerr.ErrNotFound
+}
+
+func (impl *memMessageExModelImpl) GetTalkMessageEx(ctx context.Context, talkID, messageID string) (*defs.TalkMessageEx, error) {
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	messageEx := impl.find(talkID, messageID)
+	if messageEx == nil {
+		return nil, commerr.ErrNotFound
+	}
+
+	return impl.clone(messageEx), nil
+}
+
+func (impl *memMessageExModelImpl) GetTalkMessageExs(ctx context.Context, talkID string) ([]*defs.TalkMessageEx, error) {
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	messageExs := make([]*defs.TalkMessageEx, 0, len(impl.messageExs[talkID]))
+
+	for _, messageEx := range impl.messageExs[talkID] {
+		messageExs = append(messageExs, impl.clone(messageEx))
+	}
+
+	return messageExs, nil
+}
+
+func (impl *memMessageExModelImpl) RecallTalkMessage(ctx context.Context, talkID, messageID string, at int64) error {
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	messageEx := impl.find(talkID, messageID)
+	if messageEx == nil || messageEx.RecalledAt != 0 {
+		return commerr.ErrNotFound
+	}
+
+	messageEx.RecalledAt = at
+
+	return nil
+}
+
+func (impl *memMessageExModelImpl) AddTalkMessageEdit(ctx context.Context, talkID, messageID string, edit *defs.TalkMessageEdit) error {
+	if edit == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.messageExsLock.Lock()
+	defer impl.messageExsLock.Unlock()
+
+	messageEx := impl.find(talkID, messageID)
+	if messageEx == nil || messageEx.RecalledAt != 0 {
+		return commerr.ErrNotFound
+	}
+
+	editCopy := *edit
+	messageEx.Edits = append(messageEx.Edits, &editCopy)
+
+	return nil
+}
+
+func (impl *memMessageExModelImpl) find(talkID, messageID string) *defs.TalkMessageEx {
+	for _, messageEx := range impl.messageExs[talkID] {
+		if messageEx.MessageID == messageID {
+			return messageEx
+		}
+	}
+
+	return nil
+}
+
+func (impl *memMessageExModelImpl) clone(messageEx *defs.TalkMessageEx) *defs.TalkMessageEx {
+	c := *messageEx
+	c.Edits = make([]*defs.TalkMessageEdit, 0, len(messageEx.Edits))
+
+	for _, edit := range messageEx.Edits {
+		editCopy := *edit
+		c.Edits = append(c.Edits, &editCopy)
+	}
+
+	return &c
+}
@@ -2,12 +2,14 @@ package impls
 
 import (
 	"context"
+	"sort"
 	"strconv"
 	"sync"
 
 	"github.com/godruoyi/go-snowflake"
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/commerr"
+	"github.com/spf13/cast"
 	"golang.org/x/exp/slices"
 )
 
@@ -116,9 +118,24 @@ func (impl *memModelImpl) GetTalkMessages(ctx context.Context, talkID string, of
 		return
 	}
 
-	messages = make([]*talkinters.TalkMessageR, 0, len(talk.messages))
+	talkMessages := talk.messages
 
-	for _, message := range talk.messages {
+	// paged like the mongo model, the offset is applied only with a count
+	if count > 0 {
+		if offset >= int64(len(talkMessages)) {
+			talkMessages = nil
+		} else {
+			talkMessages = talkMessages[offset:]
+		}
+
+		if count < int64(len(talkMessages)) {
+			talkMessages = talkMessages[:count]
+		}
+	}
+
+	messages = make([]*talkinters.TalkMessageR, 0, len(talkMessages))
+
+	for _, message := range talkMessages {
 		messages = append(messages, &talkinters.TalkMessageR{
 			MessageID:    message.MessageID,
 			TalkMessageW: message.TalkMessageW,
@@ -215,6 +232,11 @@ func (impl *memModelImpl) GetPendingTalkInfos(ctx context.Context, actIDs, bizID
 		})
 	}
 
+	// in the creation order as the mongo model returns, the ids are snowflake ids
+	sort.Slice(talks, func(i, j int) bool {
+		return cast.ToUint64(talks[i].TalkID) < cast.ToUint64(talks[j].TalkID)
+	})
+
 	return
 }
 
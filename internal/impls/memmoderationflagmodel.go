@@ -0,0 +1,68 @@
+package impls
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemModerationFlagModel() defs.ModerationFlagModel {
+	return &memModerationFlagModelImpl{}
+}
+
+type memModerationFlagModelImpl struct {
+	flagsLock sync.Mutex
+	flags     []*defs.ModerationFlag
+}
+
+func (impl *memModerationFlagModelImpl) AddModerationFlag(ctx context.Context, flag *defs.ModerationFlag) error {
+	if flag == nil || flag.TalkID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	if flag.ID == "" {
+		flag.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	impl.flagsLock.Lock()
+	defer impl.flagsLock.Unlock()
+
+	flagCopy := *flag
+	impl.flags = append(impl.flags, &flagCopy)
+
+	return nil
+}
+
+func (impl *memModerationFlagModelImpl) QueryModerationFlags(ctx context.Context, actIDs, bizIDs []string,
+	startAt, finishAt int64) (flags []*defs.ModerationFlag, err error) {
+	impl.flagsLock.Lock()
+	defer impl.flagsLock.Unlock()
+
+	for _, flag := range impl.flags {
+		if len(actIDs) > 0 && !slices.Contains(actIDs, flag.ActID) {
+			continue
+		}
+
+		if len(bizIDs) > 0 && !slices.Contains(bizIDs, flag.BizID) {
+			continue
+		}
+
+		if startAt > 0 && flag.At < startAt {
+			continue
+		}
+
+		if finishAt > 0 && flag.At >= finishAt {
+			continue
+		}
+
+		flagCopy := *flag
+		flags = append(flags, &flagCopy)
+	}
+
+	return
+}
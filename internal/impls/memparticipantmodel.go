@@ -0,0 +1,76 @@
+package impls
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemParticipantModel() defs.TalkParticipantModel {
+	return &memParticipantModelImpl{
+		participants: make(map[string][]uint64),
+	}
+}
+
+type memParticipantModelImpl struct {
+	participantsLock sync.Mutex
+	participants     map[string][]uint64
+}
+
+func (impl *memParticipantModelImpl) AddTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error {
+	if talkID == "" || servicerID == 0 {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.participantsLock.Lock()
+	defer impl.participantsLock.Unlock()
+
+	if slices.Contains(impl.participants[talkID], servicerID) {
+		return commerr.ErrAlreadyExists
+	}
+
+	impl.participants[talkID] = append(impl.participants[talkID], servicerID)
+
+	return nil
+}
+
+func (impl *memParticipantModelImpl) RemoveTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error {
+	impl.participantsLock.Lock()
+	defer impl.participantsLock.Unlock()
+
+	idx := slices.Index(impl.participants[talkID], servicerID)
+	if idx < 0 {
+		return commerr.ErrNotFound
+	}
+
+	impl.participants[talkID] = slices.Delete(impl.participants[talkID], idx, idx+1)
+
+	if len(impl.participants[talkID]) == 0 {
+		delete(impl.participants, talkID)
+	}
+
+	return nil
+}
+
+func (impl *memParticipantModelImpl) GetTalkParticipants(ctx context.Context, talkID string) ([]uint64, error) {
+	impl.participantsLock.Lock()
+	defer impl.participantsLock.Unlock()
+
+	return slices.Clone(impl.participants[talkID]), nil
+}
+
+func (impl *memParticipantModelImpl) GetServicerParticipantTalkIDs(ctx context.Context, servicerID uint64) (talkIDs []string, err error) {
+	impl.participantsLock.Lock()
+	defer impl.participantsLock.Unlock()
+
+	for talkID, servicerIDs := range impl.participants {
+		if slices.Contains(servicerIDs, servicerID) {
+			talkIDs = append(talkIDs, talkID)
+		}
+	}
+
+	return
+}
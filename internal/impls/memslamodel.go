@@ -0,0 +1,202 @@
+package impls
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+func NewMemSLAModel() defs.TalkSLAModel {
+	return &memSLAModelImpl{
+		slas: make(map[string]*defs.TalkSLA),
+	}
+}
+
+type memSLAModelImpl struct {
+	slasLock sync.Mutex
+	slas     map[string]*defs.TalkSLA
+}
+
+func (impl *memSLAModelImpl) GetTalkSLA(ctx context.Context, talkID string) (*defs.TalkSLA, error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok {
+		return nil, commerr.ErrNotFound
+	}
+
+	slaCopy := *sla
+
+	return &slaCopy, nil
+}
+
+func (impl *memSLAModelImpl) UpdateTalkSLA(ctx context.Context, sla *defs.TalkSLA) error {
+	if sla == nil || sla.TalkID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	slaCopy := *sla
+	slaCopy.FirstResponseBreached, slaCopy.HandleBreached = false, false
+
+	if old, ok := impl.slas[sla.TalkID]; ok {
+		slaCopy.FirstResponseBreached, slaCopy.HandleBreached = old.FirstResponseBreached, old.HandleBreached
+	}
+
+	impl.slas[sla.TalkID] = &slaCopy
+
+	return nil
+}
+
+func (impl *memSLAModelImpl) SetTalkSLAServicer(ctx context.Context, talkID string, servicerID uint64, at int64) error {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok {
+		return commerr.ErrNotFound
+	}
+
+	sla.ServicerID = servicerID
+
+	if sla.AttachedAt == 0 {
+		sla.AttachedAt = at
+	}
+
+	return nil
+}
+
+func (impl *memSLAModelImpl) SetTalkSLAFirstResponse(ctx context.Context, talkID string, at int64) (bool, error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok || sla.FirstResponseAt > 0 {
+		return false, nil
+	}
+
+	sla.FirstResponseAt = at
+	sla.FirstResponseSeconds = at - sla.StartAt
+
+	return true, nil
+}
+
+func (impl *memSLAModelImpl) SetTalkSLARating(ctx context.Context, talkID string, rating int) error {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok {
+		return commerr.ErrNotFound
+	}
+
+	sla.Rating = rating
+
+	return nil
+}
+
+func (impl *memSLAModelImpl) SetTalkSLAClosed(ctx context.Context, talkID string, at int64) (bool, error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok || sla.ClosedAt > 0 {
+		return false, nil
+	}
+
+	sla.ClosedAt = at
+
+	if sla.AttachedAt > 0 {
+		sla.HandleSeconds = at - sla.AttachedAt
+	}
+
+	return true, nil
+}
+
+func (impl *memSLAModelImpl) MarkTalkSLABreached(ctx context.Context, talkID string, metric defs.SLAMetric) (bool, error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	sla, ok := impl.slas[talkID]
+	if !ok {
+		return false, commerr.ErrNotFound
+	}
+
+	var breached *bool
+
+	switch metric {
+	case defs.SLAMetricFirstResponse:
+		breached = &sla.FirstResponseBreached
+	case defs.SLAMetricHandle:
+		breached = &sla.HandleBreached
+	default:
+		return false, commerr.ErrInvalidArgument
+	}
+
+	if *breached {
+		return false, nil
+	}
+
+	*breached = true
+
+	return true, nil
+}
+
+func (impl *memSLAModelImpl) QueryTalkSLAs(ctx context.Context, actIDs, bizIDs []string, startAt, finishAt int64) (slas []*defs.TalkSLA, err error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	for _, sla := range impl.slas {
+		if len(actIDs) > 0 && !slices.Contains(actIDs, sla.ActID) {
+			continue
+		}
+
+		if len(bizIDs) > 0 && !slices.Contains(bizIDs, sla.BizID) {
+			continue
+		}
+
+		if startAt > 0 && sla.StartAt < startAt {
+			continue
+		}
+
+		if finishAt > 0 && sla.StartAt >= finishAt {
+			continue
+		}
+
+		slaCopy := *sla
+		slas = append(slas, &slaCopy)
+	}
+
+	return
+}
+
+func (impl *memSLAModelImpl) QueryOpenTalkSLAs(ctx context.Context, afterTalkID string, limit int64) (slas []*defs.TalkSLA, err error) {
+	impl.slasLock.Lock()
+	defer impl.slasLock.Unlock()
+
+	for _, sla := range impl.slas {
+		if sla.ClosedAt > 0 || sla.TalkID <= afterTalkID {
+			continue
+		}
+
+		slaCopy := *sla
+		slas = append(slas, &slaCopy)
+	}
+
+	sort.Slice(slas, func(i, j int) bool {
+		return slas[i].TalkID < slas[j].TalkID
+	})
+
+	if limit > 0 && int64(len(slas)) > limit {
+		slas = slas[:limit]
+	}
+
+	return
+}
@@ -0,0 +1,156 @@
+package impls
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func NewMemServicerStatusController() defs.ServicerStatusController {
+	return &memServicerStatusControllerImpl{
+		bannedTokens: make(map[uint64]int64),
+		sessions:     make(map[string]*defs.ServicerSession),
+	}
+}
+
+type memServicerStatusControllerImpl struct {
+	bannedTokensLock sync.Mutex
+	bannedTokens     map[uint64]int64
+
+	sessionsLock sync.Mutex
+	sessions     map[string]*defs.ServicerSession
+}
+
+func (impl *memServicerStatusControllerImpl) IsTokenBanned(ctx context.Context, id uint64) (bool, error) {
+	impl.bannedTokensLock.Lock()
+	defer impl.bannedTokensLock.Unlock()
+
+	expireAt, ok := impl.bannedTokens[id]
+	if !ok {
+		return false, nil
+	}
+
+	if expireAt != 0 && expireAt <= time.Now().Unix() {
+		delete(impl.bannedTokens, id)
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (impl *memServicerStatusControllerImpl) BanToken(ctx context.Context, id uint64, expireAt int64) error {
+	if expireAt != 0 && expireAt <= time.Now().Unix() {
+		return nil
+	}
+
+	impl.bannedTokensLock.Lock()
+	defer impl.bannedTokensLock.Unlock()
+
+	impl.bannedTokens[id] = expireAt
+
+	return nil
+}
+
+func (impl *memServicerStatusControllerImpl) AddSession(ctx context.Context, session *defs.ServicerSession) error {
+	if session == nil || session.ID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	if _, ok := impl.sessions[session.ID]; ok {
+		return commerr.ErrAlreadyExists
+	}
+
+	s := *session
+	impl.sessions[session.ID] = &s
+
+	return nil
+}
+
+func (impl *memServicerStatusControllerImpl) GetSession(ctx context.Context, id string) (*defs.ServicerSession, error) {
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	session, ok := impl.sessions[id]
+	if !ok {
+		return nil, commerr.ErrNotFound
+	}
+
+	s := *session
+
+	return &s, nil
+}
+
+func (impl *memServicerStatusControllerImpl) TouchSession(ctx context.Context, id string, lastSeenAt, expireAt int64) error {
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	session, ok := impl.sessions[id]
+	if !ok {
+		return commerr.ErrNotFound
+	}
+
+	session.LastSeenAt = lastSeenAt
+
+	if expireAt != 0 {
+		session.ExpireAt = expireAt
+	}
+
+	return nil
+}
+
+func (impl *memServicerStatusControllerImpl) QuerySessions(ctx context.Context, userID uint64, now int64) (
+	[]*defs.ServicerSession, error) {
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	sessions := make([]*defs.ServicerSession, 0)
+
+	for id, session := range impl.sessions {
+		if session.ExpireAt <= now {
+			delete(impl.sessions, id)
+
+			continue
+		}
+
+		if session.UserID == userID {
+			s := *session
+			sessions = append(sessions, &s)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+	})
+
+	return sessions, nil
+}
+
+func (impl *memServicerStatusControllerImpl) DeleteSession(ctx context.Context, id string) error {
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	delete(impl.sessions, id)
+
+	return nil
+}
+
+func (impl *memServicerStatusControllerImpl) DeleteUserSessions(ctx context.Context, userID uint64) error {
+	impl.sessionsLock.Lock()
+	defer impl.sessionsLock.Unlock()
+
+	for id, session := range impl.sessions {
+		if session.UserID == userID {
+			delete(impl.sessions, id)
+		}
+	}
+
+	return nil
+}
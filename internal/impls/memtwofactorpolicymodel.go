@@ -0,0 +1,50 @@
+package impls
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func NewMemTwoFactorPolicyModel() defs.TwoFactorPolicyModel {
+	return &memTwoFactorPolicyModelImpl{
+		requiredActIDs: make(map[string]bool),
+	}
+}
+
+type memTwoFactorPolicyModelImpl struct {
+	requiredActIDsLock sync.Mutex
+	requiredActIDs     map[string]bool
+}
+
+func (impl *memTwoFactorPolicyModelImpl) IsTwoFactorRequired(ctx context.Context, actIDs []string) (bool, error) {
+	impl.requiredActIDsLock.Lock()
+	defer impl.requiredActIDsLock.Unlock()
+
+	for _, actID := range actIDs {
+		if impl.requiredActIDs[actID] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (impl *memTwoFactorPolicyModelImpl) SetTwoFactorRequired(ctx context.Context, actID string, required bool) error {
+	if actID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	impl.requiredActIDsLock.Lock()
+	defer impl.requiredActIDsLock.Unlock()
+
+	if required {
+		impl.requiredActIDs[actID] = true
+	} else {
+		delete(impl.requiredActIDs, actID)
+	}
+
+	return nil
+}
@@ -2,15 +2,19 @@ package impls
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/godruoyi/go-snowflake"
 	"github.com/sbasestarter/bizinters/userinters/userpass"
 	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
 )
 
-func NewMemUserPassModel() userpass.UserPasswordModel {
+func NewMemUserPassModel() defs.ServicerUserModel {
 	return &memUserPassModelImpl{
 		users: make(map[uint64]*userpass.User),
 	}
@@ -154,3 +158,56 @@ func (impl *memUserPassModelImpl) UpdateUserAllExData(ctx context.Context, userI
 
 	return
 }
+
+func (impl *memUserPassModelImpl) UpdateUserPassword(ctx context.Context, userID uint64, password string) (err error) {
+	impl.usersLock.Lock()
+	defer impl.usersLock.Unlock()
+
+	u, ok := impl.users[userID]
+	if !ok {
+		err = commerr.ErrNotFound
+
+		return
+	}
+
+	u.Password = password
+
+	return
+}
+
+func (impl *memUserPassModelImpl) QueryUsers(ctx context.Context, actIDs []string, keyword string, offset,
+	limit int64) (users []*userpass.User, total int64, err error) {
+	impl.usersLock.Lock()
+	defer impl.usersLock.Unlock()
+
+	keyword = strings.ToLower(keyword)
+
+	var matched []*userpass.User
+
+	for _, u := range impl.users {
+		if keyword != "" && !strings.Contains(strings.ToLower(u.UserName), keyword) {
+			continue
+		}
+
+		if len(actIDs) > 0 && slices.IndexFunc(parseIDs(u.ExData[dKeyExDataActIDs]), func(actID string) bool {
+			return slices.Contains(actIDs, actID)
+		}) < 0 {
+			continue
+		}
+
+		matched = append(matched, u)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+
+	total = int64(len(matched))
+
+	for idx := offset; idx < total && (limit <= 0 || idx < offset+limit); idx++ {
+		u := *matched[idx]
+		users = append(users, &u)
+	}
+
+	return
+}
@@ -0,0 +1,146 @@
+package impls
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	defRecallWindowSeconds = 120
+	defEditWindowSeconds   = 600
+	maxEditTextLen         = 4096
+)
+
+func NewMessageReviser(m defs.ModelEx, cfg config.MessageRevision, redactor defs.Redactor) defs.MessageReviser {
+	if cfg.RecallWindowSeconds == 0 {
+		cfg.RecallWindowSeconds = defRecallWindowSeconds
+	}
+
+	if cfg.EditWindowSeconds == 0 {
+		cfg.EditWindowSeconds = defEditWindowSeconds
+	}
+
+	return &messageReviserImpl{
+		m:        m,
+		cfg:      cfg,
+		redactor: redactor,
+		now: func() int64 {
+			return time.Now().Unix()
+		},
+	}
+}
+
+type messageReviserImpl struct {
+	m        defs.ModelEx
+	cfg      config.MessageRevision
+	redactor defs.Redactor
+	now      func() int64
+}
+
+func (impl *messageReviserImpl) Revise(ctx context.Context, talkID string, senderID uint64, customerMessage bool,
+	command *defs.MessageCommand) (*defs.MessageRevision, error) {
+	if command == nil || talkID == "" {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	messageEx, err := impl.findMessage(ctx, talkID, senderID, customerMessage, command)
+	if err != nil {
+		return nil, err
+	}
+
+	if messageEx.SenderID != senderID || messageEx.CustomerMessage != customerMessage {
+		return nil, commerr.ErrPermissionDenied
+	}
+
+	now := impl.now()
+
+	revision := &defs.MessageRevision{
+		TalkID:          talkID,
+		MessageID:       messageEx.MessageID,
+		At:              messageEx.At,
+		SenderID:        messageEx.SenderID,
+		CustomerMessage: messageEx.CustomerMessage,
+	}
+
+	switch command.Command {
+	case defs.MessageCommandRecall:
+		if !impl.inWindow(impl.cfg.RecallWindowSeconds, messageEx.At, now) {
+			return nil, commerr.ErrTimeout
+		}
+
+		if err = impl.m.RecallTalkMessage(ctx, talkID, messageEx.MessageID, now); err != nil {
+			return nil, err
+		}
+
+		revision.RecalledAt = now
+	case defs.MessageCommandEdit:
+		text := strings.TrimSpace(command.Text)
+		if text == "" || len(text) > maxEditTextLen || messageEx.Type != talkinters.TalkMessageTypeText {
+			return nil, commerr.ErrInvalidArgument
+		}
+
+		if !impl.inWindow(impl.cfg.EditWindowSeconds, messageEx.At, now) {
+			return nil, commerr.ErrTimeout
+		}
+
+		message := &talkinters.TalkMessageW{
+			Type: talkinters.TalkMessageTypeText,
+			Text: text,
+		}
+
+		if err = impl.redactor.Redact(ctx, talkID, message); err != nil {
+			return nil, err
+		}
+
+		revision.Edit = &defs.TalkMessageEdit{
+			At:   now,
+			Text: message.Text,
+			Data: message.Data,
+		}
+
+		if err = impl.m.AddTalkMessageEdit(ctx, talkID, messageEx.MessageID, revision.Edit); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	return revision, nil
+}
+
+func (impl *messageReviserImpl) findMessage(ctx context.Context, talkID string, senderID uint64, customerMessage bool,
+	command *defs.MessageCommand) (*defs.TalkMessageEx, error) {
+	if command.MessageID != "" {
+		return impl.m.GetTalkMessageEx(ctx, talkID, command.MessageID)
+	}
+
+	if command.At == 0 {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	messageExs, err := impl.m.GetTalkMessageExs(ctx, talkID)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := len(messageExs) - 1; idx >= 0; idx-- {
+		messageEx := messageExs[idx]
+
+		if messageEx.At == command.At && messageEx.SenderID == senderID && messageEx.CustomerMessage == customerMessage &&
+			messageEx.RecalledAt == 0 {
+			return messageEx, nil
+		}
+	}
+
+	return nil, commerr.ErrNotFound
+}
+
+func (impl *messageReviserImpl) inWindow(windowSeconds, at, now int64) bool {
+	return windowSeconds > 0 && now-at <= windowSeconds
+}
@@ -0,0 +1,110 @@
+package impls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+func TestMessageReviser(t *testing.T) {
+	ctx := context.Background()
+	m := NewModelEx(NewMemModel(), NewMemMessageExModel())
+
+	talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+		Status:          talkinters.TalkStatusOpened,
+		Title:           "title",
+		StartAt:         1000,
+		CreatorID:       1,
+		CreatorUserName: "customer",
+		ActID:           "act",
+		BizID:           "biz",
+	})
+	assert.Nil(t, err)
+
+	addMessage := func(at int64, text string) string {
+		messageID, errA := m.AddTalkMessageWithID(ctx, talkID, &talkinters.TalkMessageW{
+			At:              at,
+			CustomerMessage: true,
+			Type:            talkinters.TalkMessageTypeText,
+			SenderID:        1,
+			Text:            text,
+		})
+		assert.Nil(t, errA)
+		assert.NotEmpty(t, messageID)
+
+		return messageID
+	}
+
+	messageID1 := addMessage(1000, "hello")
+	messageID2 := addMessage(1000, "hello")
+	messageID3 := addMessage(1010, "bye")
+
+	redactor, err := NewRedactor(config.Redaction{
+		Default: config.RedactionPolicy{Mode: config.RedactionModeServicer},
+	}, m, nil)
+	assert.Nil(t, err)
+
+	reviser := NewMessageReviser(m, config.MessageRevision{RecallWindowSeconds: 60}, redactor)
+	now := int64(1050)
+	reviser.(*messageReviserImpl).now = func() int64 {
+		return now
+	}
+
+	_, err = reviser.Revise(ctx, talkID, 2, true, &defs.MessageCommand{Command: defs.MessageCommandRecall, MessageID: messageID1})
+	assert.Equal(t, commerr.ErrPermissionDenied, err)
+
+	_, err = reviser.Revise(ctx, talkID, 1, false, &defs.MessageCommand{Command: defs.MessageCommandRecall, MessageID: messageID1})
+	assert.Equal(t, commerr.ErrPermissionDenied, err)
+
+	revision, err := reviser.Revise(ctx, talkID, 1, true, &defs.MessageCommand{Command: defs.MessageCommandRecall, MessageID: messageID2})
+	assert.Nil(t, err)
+	assert.Equal(t, talkID, revision.TalkID)
+	assert.Equal(t, now, revision.RecalledAt)
+
+	_, err = reviser.Revise(ctx, talkID, 1, true, &defs.MessageCommand{Command: defs.MessageCommandEdit, MessageID: messageID2, Text: "x"})
+	assert.Equal(t, commerr.ErrNotFound, err)
+
+	revision, err = reviser.Revise(ctx, talkID, 1, true, &defs.MessageCommand{Command: defs.MessageCommandEdit, At: 1010, Text: " see you "})
+	assert.Nil(t, err)
+	assert.Equal(t, messageID3, revision.MessageID)
+	assert.Equal(t, "see you", revision.Edit.Text)
+
+	now = 1100
+
+	_, err = reviser.Revise(ctx, talkID, 1, true, &defs.MessageCommand{Command: defs.MessageCommandRecall, MessageID: messageID1})
+	assert.Equal(t, commerr.ErrTimeout, err)
+
+	messages, err := m.GetTalkMessages(ctx, talkID, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(messages))
+
+	assert.Equal(t, messageID1, messages[0].MessageID)
+	assert.Equal(t, "hello", messages[0].Text)
+	assert.Equal(t, messageID2, messages[1].MessageID)
+	assert.Equal(t, defs.TalkMessageTypeRecalled, messages[1].Type)
+	assert.Empty(t, messages[1].Text)
+	assert.Equal(t, messageID3, messages[2].MessageID)
+	assert.Equal(t, "see you", messages[2].Text)
+
+	// the edits are redacted as the new messages, the servicers see them masked
+	revision, err = reviser.Revise(ctx, talkID, 1, true, &defs.MessageCommand{Command: defs.MessageCommandEdit,
+		MessageID: messageID1, Text: "call 13812345678"})
+	assert.Nil(t, err)
+	assert.Equal(t, "call *******5678", vo.ServicerMessageRevision(revision).Edit.Text)
+	assert.Equal(t, "call 13812345678", vo.CustomerMessageRevision(revision).Edit.Text)
+	assert.Nil(t, vo.CustomerMessageRevision(revision).Edit.Data)
+
+	messages, err = m.GetTalkMessages(ctx, talkID, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "call *******5678", vo.TalkMessageDB2Pb4Servicer(&messages[0].TalkMessageW).GetText())
+
+	original, err := redactor.Reveal(&messages[0].TalkMessageW)
+	assert.Nil(t, err)
+	assert.Equal(t, "call 13812345678", original)
+}
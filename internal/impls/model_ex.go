@@ -2,20 +2,26 @@ package impls
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
+	"github.com/godruoyi/go-snowflake"
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/commerr"
 	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
 )
 
-func NewModelEx(m talkinters.Model) defs.ModelEx {
+func NewModelEx(m talkinters.Model, messageExM defs.TalkMessageExModel) defs.ModelEx {
 	return &modelExImpl{
-		m: m,
+		m:          m,
+		messageExM: messageExM,
 	}
 }
 
 type modelExImpl struct {
-	m talkinters.Model
+	m          talkinters.Model
+	messageExM defs.TalkMessageExModel
 }
 
 func (impl *modelExImpl) CreateTalk(ctx context.Context, talkInfo *talkinters.TalkInfoW) (talkID string, err error) {
@@ -37,11 +43,129 @@ func (impl *modelExImpl) CloseTalk(ctx context.Context, actIDs, bizIDs []string,
 }
 
 func (impl *modelExImpl) AddTalkMessage(ctx context.Context, talkID string, message *talkinters.TalkMessageW) (err error) {
-	return impl.m.AddTalkMessage(ctx, talkID, message)
+	_, err = impl.AddTalkMessageWithID(ctx, talkID, message)
+
+	return
 }
 
+// GetTalkMessages returns the messages with their ids, the edits and recalls are applied. The messages with the
+// same digest are matched to the records in order, so the earlier ones of a page are counted for the duplicates.
 func (impl *modelExImpl) GetTalkMessages(ctx context.Context, talkID string, offset, count int64) (messages []*talkinters.TalkMessageR, err error) {
-	return impl.m.GetTalkMessages(ctx, talkID, offset, count)
+	messages, err = impl.m.GetTalkMessages(ctx, talkID, offset, count)
+	if err != nil || len(messages) == 0 {
+		return
+	}
+
+	messageExs, err := impl.messageExM.GetTalkMessageExs(ctx, talkID)
+	if err != nil {
+		return
+	}
+
+	messageExMap := make(map[string][]*defs.TalkMessageEx)
+
+	for _, messageEx := range messageExs {
+		messageExMap[messageEx.Digest] = append(messageExMap[messageEx.Digest], messageEx)
+	}
+
+	digests := make([]string, 0, len(messages))
+
+	var duplicated bool
+
+	for _, message := range messages {
+		digest := messageDigest(&message.TalkMessageW)
+		digests = append(digests, digest)
+
+		if len(messageExMap[digest]) > 1 {
+			duplicated = true
+		}
+	}
+
+	if duplicated && count > 0 && offset > 0 {
+		if err = impl.skipEarlierMessageExs(ctx, talkID, offset, messageExMap); err != nil {
+			return
+		}
+	}
+
+	for idx, message := range messages {
+		candidates := messageExMap[digests[idx]]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		messageEx := candidates[0]
+		messageExMap[digests[idx]] = candidates[1:]
+
+		message.MessageID = messageEx.MessageID
+
+		if messageEx.RecalledAt != 0 {
+			message.Type = defs.TalkMessageTypeRecalled
+			message.Text = ""
+			message.Data = nil
+		} else if len(messageEx.Edits) > 0 {
+			edit := messageEx.Edits[len(messageEx.Edits)-1]
+
+			message.Text = edit.Text
+			message.Data = edit.Data // the redaction of the edit replaces the original's
+		}
+	}
+
+	return
+}
+
+// AddTalkMessageWithID stores the record before the message and removes it if the message isn't stored,
+// so a failed call can be retried without duplicating the message.
+func (impl *modelExImpl) AddTalkMessageWithID(ctx context.Context, talkID string, message *talkinters.TalkMessageW) (messageID string, err error) {
+	if message == nil {
+		err = commerr.ErrInvalidArgument
+
+		return
+	}
+
+	messageEx := &defs.TalkMessageEx{
+		MessageID:       strconv.FormatUint(snowflake.ID(), 10),
+		TalkID:          talkID,
+		At:              message.At,
+		SenderID:        message.SenderID,
+		CustomerMessage: message.CustomerMessage,
+		Type:            message.Type,
+		Digest:          messageDigest(message),
+		Edits:           []*defs.TalkMessageEdit{},
+		Snippet:         vo.MessageSnippet(message),
+	}
+
+	if richMessage, ok := vo.RichMessageFromDB(message); ok {
+		messageEx.Buttons = richMessage.Buttons()
+	}
+
+	if err = impl.messageExM.AddTalkMessageEx(ctx, messageEx); err != nil {
+		return
+	}
+
+	if err = impl.m.AddTalkMessage(ctx, talkID, message); err != nil {
+		_ = impl.messageExM.RemoveTalkMessageEx(ctx, talkID, messageEx.MessageID)
+
+		return
+	}
+
+	messageID = messageEx.MessageID
+
+	return
+}
+
+func (impl *modelExImpl) GetTalkMessageEx(ctx context.Context, talkID, messageID string) (*defs.TalkMessageEx, error) {
+	return impl.messageExM.GetTalkMessageEx(ctx, talkID, messageID)
+}
+
+func (impl *modelExImpl) GetTalkMessageExs(ctx context.Context, talkID string) ([]*defs.TalkMessageEx, error) {
+	return impl.messageExM.GetTalkMessageExs(ctx, talkID)
+}
+
+func (impl *modelExImpl) RecallTalkMessage(ctx context.Context, talkID, messageID string, at int64) error {
+	return impl.messageExM.RecallTalkMessage(ctx, talkID, messageID, at)
+}
+
+func (impl *modelExImpl) AddTalkMessageEdit(ctx context.Context, talkID, messageID string, edit *defs.TalkMessageEdit) error {
+	return impl.messageExM.AddTalkMessageEdit(ctx, talkID, messageID, edit)
 }
 
 func (impl *modelExImpl) TalkExists(ctx context.Context, actIDs, bizIDs []string, talkID string) (exists bool, err error) {
@@ -108,3 +232,28 @@ func (impl *modelExImpl) GetTalkServicerID(ctx context.Context, actIDs, bizIDs [
 
 	return
 }
+
+// skipEarlierMessageExs drops the records matched by the messages before the offset.
+func (impl *modelExImpl) skipEarlierMessageExs(ctx context.Context, talkID string, offset int64,
+	messageExMap map[string][]*defs.TalkMessageEx) error {
+	messages, err := impl.m.GetTalkMessages(ctx, talkID, 0, offset)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		digest := messageDigest(&message.TalkMessageW)
+
+		if candidates := messageExMap[digest]; len(candidates) > 0 {
+			messageExMap[digest] = candidates[1:]
+		}
+	}
+
+	return nil
+}
+
+// messageDigest identifies a stored message, the talk model doesn't return the id of a added message.
+func messageDigest(message *talkinters.TalkMessageW) string {
+	return hexSHA256(append([]byte(fmt.Sprintf("%d|%d|%t|%d|%s|", message.At, message.SenderID,
+		message.CustomerMessage, message.Type, message.Text)), message.Data...))
+}
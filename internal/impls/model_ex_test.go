@@ -0,0 +1,102 @@
+package impls
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+type failingAddModel struct {
+	talkinters.Model
+
+	fail bool
+}
+
+func (m *failingAddModel) AddTalkMessage(ctx context.Context, talkID string, message *talkinters.TalkMessageW) error {
+	if m.fail {
+		return errors.New("add failed")
+	}
+
+	return m.Model.AddTalkMessage(ctx, talkID, message)
+}
+
+func TestModelExDuplicatedMessagesPaging(t *testing.T) {
+	ctx := context.Background()
+	baseM := &failingAddModel{Model: NewMemModel()}
+	messageExM := NewMemMessageExModel()
+	m := NewModelEx(baseM, messageExM)
+
+	talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+		Status:          talkinters.TalkStatusOpened,
+		Title:           "title",
+		StartAt:         1000,
+		CreatorID:       1,
+		CreatorUserName: "customer",
+		ActID:           "act",
+		BizID:           "biz",
+	})
+	assert.Nil(t, err)
+
+	newMessage := func(text string) *talkinters.TalkMessageW {
+		return &talkinters.TalkMessageW{
+			At:              1000,
+			CustomerMessage: true,
+			Type:            talkinters.TalkMessageTypeText,
+			SenderID:        1,
+			Text:            text,
+		}
+	}
+
+	// a failed add leaves nothing behind, so the retry doesn't duplicate the message
+	baseM.fail = true
+	_, err = m.AddTalkMessageWithID(ctx, talkID, newMessage("ok"))
+	assert.NotNil(t, err)
+
+	messageExs, err := messageExM.GetTalkMessageExs(ctx, talkID)
+	assert.Nil(t, err)
+	assert.Empty(t, messageExs)
+
+	baseM.fail = false
+
+	var messageIDs []string
+
+	for _, text := range []string{"ok", "ok", "hi", "ok"} {
+		messageID, errA := m.AddTalkMessageWithID(ctx, talkID, newMessage(text))
+		assert.Nil(t, errA)
+
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	assert.Nil(t, m.RecallTalkMessage(ctx, talkID, messageIDs[1], 1010))
+	assert.Nil(t, m.AddTalkMessageEdit(ctx, talkID, messageIDs[3], &defs.TalkMessageEdit{At: 1020, Text: "okay"}))
+
+	messages, err := m.GetTalkMessages(ctx, talkID, 0, 0)
+	assert.Nil(t, err)
+	assert.Len(t, messages, 4)
+
+	for idx, message := range messages {
+		assert.Equal(t, messageIDs[idx], message.MessageID)
+	}
+
+	// each page is matched as in the whole talk
+	for offset := int64(0); offset < 4; offset++ {
+		page, errG := m.GetTalkMessages(ctx, talkID, offset, 2)
+		assert.Nil(t, errG)
+
+		for idx, message := range page {
+			expected := messages[offset+int64(idx)]
+
+			assert.Equal(t, expected.MessageID, message.MessageID)
+			assert.Equal(t, expected.Type, message.Type)
+			assert.Equal(t, expected.Text, message.Text)
+		}
+	}
+
+	assert.Equal(t, defs.TalkMessageTypeRecalled, messages[1].Type)
+	assert.Equal(t, "okay", messages[3].Text)
+	assert.Equal(t, "ok", messages[0].Text)
+}
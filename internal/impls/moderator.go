@@ -0,0 +1,197 @@
+package impls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	defaultModerationTimeout = time.Second * 3
+	moderationMaskRune       = '*'
+)
+
+func NewModerator(cfg config.Moderation, logger l.Wrapper) (defs.Moderator, error) {
+	switch cfg.Type {
+	case "", config.ModerationTypeWordList:
+		return NewWordListModerator(cfg.RejectWords, cfg.FlagWords, cfg.MaskWords), nil
+	case config.ModerationTypeHTTP:
+		return NewHTTPModerator(cfg.URL, time.Duration(cfg.TimeoutSeconds)*time.Second, cfg.FailOpen, logger)
+	}
+
+	return nil, errors.New("unknown moderation type")
+}
+
+// NewWordListModerator matches the words case-insensitively, reject words take precedence over flag words,
+// mask words are masked in the stored text.
+func NewWordListModerator(rejectWords, flagWords, maskWords []string) defs.Moderator {
+	return &wordListModeratorImpl{
+		rejectRegexp: wordListRegexp(rejectWords),
+		flagRegexp:   wordListRegexp(flagWords),
+		maskRegexp:   wordListRegexp(maskWords),
+	}
+}
+
+type wordListModeratorImpl struct {
+	rejectRegexp *regexp.Regexp
+	flagRegexp   *regexp.Regexp
+	maskRegexp   *regexp.Regexp
+}
+
+func (impl *wordListModeratorImpl) Moderate(ctx context.Context, request *defs.ModerationRequest) (*defs.ModerationResult, error) {
+	if request == nil {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	if impl.rejectRegexp != nil && impl.rejectRegexp.MatchString(request.Text) {
+		return &defs.ModerationResult{
+			Action: defs.ModerationActionReject,
+			Reason: "forbiddenWord",
+		}, nil
+	}
+
+	result := &defs.ModerationResult{
+		Action: defs.ModerationActionAllow,
+	}
+
+	if impl.maskRegexp != nil && impl.maskRegexp.MatchString(request.Text) {
+		result.Action = defs.ModerationActionMask
+		result.Text = impl.maskRegexp.ReplaceAllStringFunc(request.Text, func(s string) string {
+			return strings.Repeat(string(moderationMaskRune), len([]rune(s)))
+		})
+	}
+
+	if impl.flagRegexp != nil {
+		if word := impl.flagRegexp.FindString(request.Text); word != "" {
+			result.Action = defs.ModerationActionFlag
+			result.Reason = "word:" + word
+		}
+	}
+
+	return result, nil
+}
+
+func wordListRegexp(words []string) *regexp.Regexp {
+	quotedWords := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+
+		quotedWords = append(quotedWords, regexp.QuoteMeta(word))
+	}
+
+	if len(quotedWords) == 0 {
+		return nil
+	}
+
+	return regexp.MustCompile("(?i)" + strings.Join(quotedWords, "|"))
+}
+
+// NewHTTPModerator posts the moderation request to the url, the messages are allowed if it fails and failOpen is set.
+func NewHTTPModerator(url string, timeout time.Duration, failOpen bool, logger l.Wrapper) (defs.Moderator, error) {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	if url == "" {
+		return nil, errors.New("no moderation url")
+	}
+
+	if timeout <= 0 {
+		timeout = defaultModerationTimeout
+	}
+
+	return &httpModeratorImpl{
+		url:      url,
+		failOpen: failOpen,
+		logger:   logger.WithFields(l.StringField(l.ClsKey, "httpModeratorImpl")),
+		httpCli: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+type httpModeratorImpl struct {
+	url      string
+	failOpen bool
+	logger   l.Wrapper
+	httpCli  *http.Client
+}
+
+func (impl *httpModeratorImpl) Moderate(ctx context.Context, request *defs.ModerationRequest) (*defs.ModerationResult, error) {
+	if request == nil {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	result, err := impl.post(ctx, request)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", request.TalkID)).Error("ModerateFailed")
+
+		if impl.failOpen {
+			return &defs.ModerationResult{
+				Action: defs.ModerationActionAllow,
+			}, nil
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (impl *httpModeratorImpl) post(ctx context.Context, request *defs.ModerationRequest) (*defs.ModerationResult, error) {
+	d, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, impl.url, bytes.NewReader(d))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := impl.httpCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, commerr.ErrUnavailable
+	}
+
+	var result defs.ModerationResult
+
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	switch result.Action {
+	case defs.ModerationActionAllow, defs.ModerationActionReject, defs.ModerationActionFlag:
+	case defs.ModerationActionMask:
+		if result.Text == "" {
+			return nil, commerr.ErrBadFormat
+		}
+	default:
+		return nil, commerr.ErrBadFormat
+	}
+
+	return &result, nil
+}
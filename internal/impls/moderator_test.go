@@ -0,0 +1,92 @@
+package impls
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestWordListModerator(t *testing.T) {
+	ctx := context.Background()
+	moderator := NewWordListModerator([]string{"scam"}, []string{"refund"}, []string{"damn", "坏蛋"})
+
+	moderate := func(text string) *defs.ModerationResult {
+		result, err := moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: text})
+		assert.Nil(t, err)
+
+		return result
+	}
+
+	assert.Equal(t, defs.ModerationActionAllow, moderate("hello").Action)
+	assert.Equal(t, defs.ModerationActionReject, moderate("it's a SCAM, damn").Action)
+
+	result := moderate("Damn it, 坏蛋")
+	assert.Equal(t, defs.ModerationActionMask, result.Action)
+	assert.Equal(t, "**** it, **", result.Text)
+
+	result = moderate("damn, I want a Refund")
+	assert.Equal(t, defs.ModerationActionFlag, result.Action)
+	assert.Equal(t, "****, I want a Refund", result.Text)
+	assert.Equal(t, "word:Refund", result.Reason)
+
+	result, err := NewWordListModerator(nil, nil, nil).Moderate(ctx, &defs.ModerationRequest{Text: "scam"})
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ModerationActionAllow, result.Action)
+}
+
+func TestHTTPModerator(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request defs.ModerationRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		switch request.Text {
+		case "bad":
+			_ = json.NewEncoder(w).Encode(&defs.ModerationResult{Action: defs.ModerationActionReject, Reason: "bad"})
+		case "broken":
+			_ = json.NewEncoder(w).Encode(&defs.ModerationResult{Action: "unknown"})
+		case "down":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_ = json.NewEncoder(w).Encode(&defs.ModerationResult{Action: defs.ModerationActionAllow})
+		}
+	}))
+	defer server.Close()
+
+	moderator, err := NewHTTPModerator(server.URL, time.Second, false, nil)
+	assert.Nil(t, err)
+
+	result, err := moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: "hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ModerationActionAllow, result.Action)
+
+	result, err = moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: "bad"})
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ModerationActionReject, result.Action)
+	assert.Equal(t, "bad", result.Reason)
+
+	_, err = moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: "broken"})
+	assert.NotNil(t, err)
+
+	_, err = moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: "down"})
+	assert.NotNil(t, err)
+
+	moderator, err = NewHTTPModerator(server.URL, time.Second, true, nil)
+	assert.Nil(t, err)
+
+	result, err = moderator.Moderate(ctx, &defs.ModerationRequest{TalkID: "t1", Text: "down"})
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ModerationActionAllow, result.Action)
+}
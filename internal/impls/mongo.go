@@ -0,0 +1,51 @@
+package impls
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbasestarter/bizmongolib/mongolib"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	mongoPingTimeout = time.Second * 10
+)
+
+func newMongoCollection(dsn, collectionName string) (collection *mongo.Collection, err error) {
+	client, clientOptions, err := mongolib.InitMongo(dsn)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mongoPingTimeout)
+	defer cancel()
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	collection = client.Database(clientOptions.Auth.AuthSource).Collection(collectionName)
+
+	return
+}
+
+func mongoScopeFilter(actIDs, bizIDs []string) bson.M {
+	filter := bson.M{}
+
+	if len(actIDs) > 0 {
+		filter["ActID"] = bson.M{
+			"$in": actIDs,
+		}
+	}
+
+	if len(bizIDs) > 0 {
+		filter["BizID"] = bson.M{
+			"$in": bizIDs,
+		}
+	}
+
+	return filter
+}
@@ -0,0 +1,73 @@
+package impls
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionAuditLogs = "audit_logs"
+)
+
+func NewMongoAuditLogModel(dsn string) (defs.AuditLogModel, error) {
+	collection, err := newMongoCollection(dsn, collectionAuditLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoAuditLogModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoAuditLogModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoAuditLogModelImpl) AddAuditLog(ctx context.Context, log *defs.AuditLog) (err error) {
+	if log == nil || log.Action == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	if log.ID == "" {
+		log.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	_, err = impl.collection.InsertOne(ctx, log)
+
+	return
+}
+
+func (impl *mongoAuditLogModelImpl) QueryAuditLogs(ctx context.Context, actIDs []string, startAt, finishAt int64) (logs []*defs.AuditLog, err error) {
+	filter := mongoScopeFilter(actIDs, nil)
+
+	atFilter := bson.M{}
+
+	if startAt > 0 {
+		atFilter["$gte"] = startAt
+	}
+
+	if finishAt > 0 {
+		atFilter["$lt"] = finishAt
+	}
+
+	if len(atFilter) > 0 {
+		filter["At"] = atFilter
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"At": 1}))
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &logs)
+
+	return
+}
@@ -0,0 +1,140 @@
+package impls
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	collectionCustomerBans = "customer_bans"
+)
+
+func NewMongoCustomerBanModel(dsn string) (defs.CustomerBanModel, error) {
+	collection, err := newMongoCollection(dsn, collectionCustomerBans)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoCustomerBanModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoCustomerBanModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoCustomerBanModelImpl) AddCustomerBan(ctx context.Context, ban *defs.CustomerBan) (err error) {
+	if ban == nil || ban.ActID == "" || (ban.UserID == 0 && ban.UserName == "") {
+		return commerr.ErrInvalidArgument
+	}
+
+	if ban.ID == "" {
+		ban.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	_, err = impl.collection.InsertOne(ctx, ban)
+
+	return
+}
+
+func (impl *mongoCustomerBanModelImpl) GetCustomerBan(ctx context.Context, id string) (ban *defs.CustomerBan, err error) {
+	ban = &defs.CustomerBan{}
+
+	err = impl.collection.FindOne(ctx, bson.M{"_id": id}).Decode(ban)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		ban = nil
+	}
+
+	return
+}
+
+func (impl *mongoCustomerBanModelImpl) RevokeCustomerBan(ctx context.Context, id string, at int64, revokerID uint64) error {
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": id, "RevokedAt": 0}, bson.M{
+		"$set": bson.M{
+			"RevokedAt": at,
+			"RevokerID": revokerID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoCustomerBanModelImpl) QueryCustomerBans(ctx context.Context, actIDs []string, activeAt int64) (bans []*defs.CustomerBan, err error) {
+	filter := mongoScopeFilter(actIDs, nil)
+
+	if activeAt > 0 {
+		mongoActiveBanFilter(filter, activeAt)
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &bans)
+
+	return
+}
+
+func (impl *mongoCustomerBanModelImpl) FindActiveCustomerBan(ctx context.Context, userID uint64, userName, actID string,
+	now int64) (ban *defs.CustomerBan, err error) {
+	filter := bson.M{
+		"ActID":    actID,
+		"UserID":   0,
+		"UserName": userName,
+	}
+
+	if userID != 0 {
+		filter = bson.M{
+			"ActID": actID,
+			"$or": bson.A{
+				bson.M{"UserID": userID},
+				bson.M{"UserID": 0, "UserName": userName},
+			},
+		}
+	}
+
+	mongoActiveBanFilter(filter, now)
+
+	ban = &defs.CustomerBan{}
+
+	err = impl.collection.FindOne(ctx, filter).Decode(ban)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		ban = nil
+	}
+
+	return
+}
+
+func mongoActiveBanFilter(filter bson.M, now int64) {
+	filter["RevokedAt"] = 0
+	filter["$and"] = bson.A{
+		bson.M{"$or": bson.A{
+			bson.M{"ExpireAt": 0},
+			bson.M{"ExpireAt": bson.M{"$gt": now}},
+		}},
+	}
+}
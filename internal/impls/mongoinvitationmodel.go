@@ -0,0 +1,132 @@
+package impls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionServicerInvitations = "servicer_invitations"
+)
+
+func NewMongoInvitationModel(dsn string) (defs.ServicerInvitationModel, error) {
+	collection, err := newMongoCollection(dsn, collectionServicerInvitations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoInvitationModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoInvitationModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoInvitationModelImpl) AddInvitation(ctx context.Context, invitation *defs.ServicerInvitation) error {
+	if invitation == nil || invitation.ID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err := impl.collection.InsertOne(ctx, invitation)
+	if mongo.IsDuplicateKeyError(err) {
+		err = commerr.ErrAlreadyExists
+	}
+
+	return err
+}
+
+func (impl *mongoInvitationModelImpl) GetInvitation(ctx context.Context, id string) (invitation *defs.ServicerInvitation, err error) {
+	invitation = &defs.ServicerInvitation{}
+
+	err = impl.collection.FindOne(ctx, bson.M{"_id": id}).Decode(invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		invitation = nil
+	}
+
+	return
+}
+
+func (impl *mongoInvitationModelImpl) UseInvitation(ctx context.Context, id, userName string, now int64) (
+	invitation *defs.ServicerInvitation, err error) {
+	invitation = &defs.ServicerInvitation{}
+
+	err = impl.collection.FindOneAndUpdate(ctx, bson.M{
+		"_id":      id,
+		"UsedAt":   0,
+		"ExpireAt": bson.M{"$gt": now},
+	}, bson.M{
+		"$set": bson.M{
+			"UsedAt": now,
+			"UsedBy": userName,
+		},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		invitation = nil
+	}
+
+	return
+}
+
+func (impl *mongoInvitationModelImpl) ReleaseInvitation(ctx context.Context, id string) error {
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"UsedAt": 0,
+			"UsedBy": "",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoInvitationModelImpl) QueryInvitations(ctx context.Context, actIDs []string) (invitations []*defs.ServicerInvitation, err error) {
+	filter := bson.M{}
+
+	if len(actIDs) > 0 {
+		filter["ActIDs"] = bson.M{"$in": actIDs}
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"CreateAt": -1}))
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &invitations)
+
+	return
+}
+
+func (impl *mongoInvitationModelImpl) DeleteInvitation(ctx context.Context, id string) error {
+	r, err := impl.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package impls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionLoginFailures = "login_failures"
+)
+
+func NewMongoLoginFailuresModel(dsn string) (defs.LoginFailuresModel, error) {
+	collection, err := newMongoCollection(dsn, collectionLoginFailures)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoLoginFailuresModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoLoginFailuresModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoLoginFailuresModelImpl) GetLoginFailures(ctx context.Context, key string) (failures *defs.LoginFailures, err error) {
+	failures = &defs.LoginFailures{}
+
+	err = impl.collection.FindOne(ctx, bson.M{
+		"_id": key,
+	}).Decode(failures)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		failures = nil
+	}
+
+	return
+}
+
+func (impl *mongoLoginFailuresModelImpl) SetLoginFailures(ctx context.Context, failures *defs.LoginFailures) error {
+	if failures == nil || failures.Key == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err := impl.collection.ReplaceOne(ctx, bson.M{
+		"_id": failures.Key,
+	}, failures, options.Replace().SetUpsert(true))
+
+	return err
+}
+
+func (impl *mongoLoginFailuresModelImpl) DeleteLoginFailures(ctx context.Context, key string) error {
+	_, err := impl.collection.DeleteOne(ctx, bson.M{
+		"_id": key,
+	})
+
+	return err
+}
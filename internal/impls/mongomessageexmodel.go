@@ -0,0 +1,120 @@
+package impls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionTalkMessageExs = "talk_message_exs"
+)
+
+func NewMongoMessageExModel(dsn string) (defs.TalkMessageExModel, error) {
+	collection, err := newMongoCollection(dsn, collectionTalkMessageExs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoMessageExModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoMessageExModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoMessageExModelImpl) AddTalkMessageEx(ctx context.Context, messageEx *defs.TalkMessageEx) error {
+	if messageEx == nil || messageEx.TalkID == "" || messageEx.MessageID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err := impl.collection.InsertOne(ctx, messageEx)
+
+	return err
+}
+
+func (impl *mongoMessageExModelImpl) RemoveTalkMessageEx(ctx context.Context, talkID, messageID string) error {
+	r, err := impl.collection.DeleteOne(ctx, bson.M{"_id": messageID, "TalkID": talkID})
+	if err != nil {
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoMessageExModelImpl) GetTalkMessageEx(ctx context.Context, talkID, messageID string) (*defs.TalkMessageEx, error) {
+	var messageEx defs.TalkMessageEx
+
+	err := impl.collection.FindOne(ctx, bson.M{"_id": messageID, "TalkID": talkID}).Decode(&messageEx)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, commerr.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &messageEx, nil
+}
+
+func (impl *mongoMessageExModelImpl) GetTalkMessageExs(ctx context.Context, talkID string) (messageExs []*defs.TalkMessageEx, err error) {
+	// the ids are increasing, they order the records of the same second
+	cursor, err := impl.collection.Find(ctx, bson.M{"TalkID": talkID},
+		options.Find().SetSort(bson.D{{Key: "At", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &messageExs)
+
+	return
+}
+
+func (impl *mongoMessageExModelImpl) RecallTalkMessage(ctx context.Context, talkID, messageID string, at int64) error {
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": messageID, "TalkID": talkID, "RecalledAt": 0}, bson.M{
+		"$set": bson.M{
+			"RecalledAt": at,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoMessageExModelImpl) AddTalkMessageEdit(ctx context.Context, talkID, messageID string, edit *defs.TalkMessageEdit) error {
+	if edit == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": messageID, "TalkID": talkID, "RecalledAt": 0}, bson.M{
+		"$push": bson.M{
+			"Edits": edit,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
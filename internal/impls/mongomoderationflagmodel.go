@@ -0,0 +1,74 @@
+package impls
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/godruoyi/go-snowflake"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionTalkModerationFlags = "talk_moderation_flags"
+)
+
+func NewMongoModerationFlagModel(dsn string) (defs.ModerationFlagModel, error) {
+	collection, err := newMongoCollection(dsn, collectionTalkModerationFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoModerationFlagModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoModerationFlagModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoModerationFlagModelImpl) AddModerationFlag(ctx context.Context, flag *defs.ModerationFlag) (err error) {
+	if flag == nil || flag.TalkID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	if flag.ID == "" {
+		flag.ID = strconv.FormatUint(snowflake.ID(), 10)
+	}
+
+	_, err = impl.collection.InsertOne(ctx, flag)
+
+	return
+}
+
+func (impl *mongoModerationFlagModelImpl) QueryModerationFlags(ctx context.Context, actIDs, bizIDs []string,
+	startAt, finishAt int64) (flags []*defs.ModerationFlag, err error) {
+	filter := mongoScopeFilter(actIDs, bizIDs)
+
+	atFilter := bson.M{}
+
+	if startAt > 0 {
+		atFilter["$gte"] = startAt
+	}
+
+	if finishAt > 0 {
+		atFilter["$lt"] = finishAt
+	}
+
+	if len(atFilter) > 0 {
+		filter["At"] = atFilter
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"At": 1}))
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &flags)
+
+	return
+}
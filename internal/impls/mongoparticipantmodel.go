@@ -0,0 +1,103 @@
+package impls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionTalkParticipants = "talk_participants"
+)
+
+func NewMongoParticipantModel(dsn string) (defs.TalkParticipantModel, error) {
+	collection, err := newMongoCollection(dsn, collectionTalkParticipants)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoParticipantModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoParticipantModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoParticipantModelImpl) AddTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error {
+	if talkID == "" || servicerID == 0 {
+		return commerr.ErrInvalidArgument
+	}
+
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID}, bson.M{
+		"$addToSet": bson.M{
+			"ServicerIDs": servicerID,
+		},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	if r.ModifiedCount == 0 && r.UpsertedCount == 0 {
+		return commerr.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (impl *mongoParticipantModelImpl) RemoveTalkParticipant(ctx context.Context, talkID string, servicerID uint64) error {
+	r, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID}, bson.M{
+		"$pull": bson.M{
+			"ServicerIDs": servicerID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.ModifiedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoParticipantModelImpl) GetTalkParticipants(ctx context.Context, talkID string) ([]uint64, error) {
+	var participants defs.TalkParticipants
+
+	err := impl.collection.FindOne(ctx, bson.M{"_id": talkID}).Decode(&participants)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return participants.ServicerIDs, nil
+}
+
+func (impl *mongoParticipantModelImpl) GetServicerParticipantTalkIDs(ctx context.Context, servicerID uint64) (talkIDs []string, err error) {
+	cursor, err := impl.collection.Find(ctx, bson.M{"ServicerIDs": servicerID})
+	if err != nil {
+		return
+	}
+
+	var participantsList []*defs.TalkParticipants
+
+	if err = cursor.All(ctx, &participantsList); err != nil {
+		return
+	}
+
+	for _, participants := range participantsList {
+		talkIDs = append(talkIDs, participants.TalkID)
+	}
+
+	return
+}
@@ -0,0 +1,110 @@
+package impls
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/sbasestarter/bizinters/userinters/userpass"
+	userpassauthenticator "github.com/sbasestarter/bizmongolib/user/authenticator/userpass"
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoServicerUserModel shares the collection with the user password model of bizmongolib.
+func NewMongoServicerUserModel(mongoCli *mongo.Client, dbName, collectionName string, logger l.Wrapper) defs.ServicerUserModel {
+	return &mongoServicerUserModelImpl{
+		UserPasswordModel: userpassauthenticator.NewMongoUserPasswordModel(mongoCli, dbName, collectionName, logger),
+		collection:        mongoCli.Database(dbName).Collection(collectionName),
+	}
+}
+
+type mongoServicerUserModelImpl struct {
+	userpass.UserPasswordModel
+
+	collection *mongo.Collection
+}
+
+// GetUserByUserName returns commerr.ErrNotFound for the unknown user names, as the memory model does.
+func (impl *mongoServicerUserModelImpl) GetUserByUserName(ctx context.Context, userName string) (*userpass.User, error) {
+	user, err := impl.UserPasswordModel.GetUserByUserName(ctx, userName)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	return user, err
+}
+
+func (impl *mongoServicerUserModelImpl) UpdateUserPassword(ctx context.Context, userID uint64, password string) error {
+	r, err := impl.collection.UpdateOne(ctx, bson.M{
+		"_id": userID,
+	}, bson.M{
+		"$set": bson.M{
+			"password": password,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoServicerUserModelImpl) QueryUsers(ctx context.Context, actIDs []string, keyword string, offset,
+	limit int64) (users []*userpass.User, total int64, err error) {
+	filter := bson.M{}
+
+	if len(actIDs) > 0 {
+		filter["ex_data."+dKeyExDataActIDs] = bson.M{
+			"$in": actIDs,
+		}
+	}
+
+	if keyword != "" {
+		filter["user_name"] = bson.M{
+			"$regex":   regexp.QuoteMeta(keyword),
+			"$options": "i",
+		}
+	}
+
+	total, err = impl.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetSkip(offset)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return
+	}
+
+	var us []*userpassauthenticator.User
+
+	if err = cursor.All(ctx, &us); err != nil {
+		return
+	}
+
+	for _, u := range us {
+		users = append(users, &userpass.User{
+			ID:       u.ID,
+			UserName: u.UserName,
+			Password: u.Password,
+			CreateAt: u.CreateAt,
+			ExData:   u.ExData,
+		})
+	}
+
+	return
+}
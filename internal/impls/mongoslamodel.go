@@ -0,0 +1,186 @@
+package impls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionTalkSLA = "talk_sla"
+)
+
+func NewMongoSLAModel(dsn string) (defs.TalkSLAModel, error) {
+	collection, err := newMongoCollection(dsn, collectionTalkSLA)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoSLAModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoSLAModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoSLAModelImpl) GetTalkSLA(ctx context.Context, talkID string) (sla *defs.TalkSLA, err error) {
+	sla = &defs.TalkSLA{}
+
+	err = impl.collection.FindOne(ctx, bson.M{"_id": talkID}).Decode(sla)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		sla = nil
+	}
+
+	return
+}
+
+func (impl *mongoSLAModelImpl) UpdateTalkSLA(ctx context.Context, sla *defs.TalkSLA) (err error) {
+	if sla == nil || sla.TalkID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err = impl.collection.UpdateOne(ctx, bson.M{"_id": sla.TalkID}, bson.M{
+		"$set": bson.M{
+			"ActID":                sla.ActID,
+			"BizID":                sla.BizID,
+			"ServicerID":           sla.ServicerID,
+			"Rating":               sla.Rating,
+			"StartAt":              sla.StartAt,
+			"FirstResponseAt":      sla.FirstResponseAt,
+			"AttachedAt":           sla.AttachedAt,
+			"ClosedAt":             sla.ClosedAt,
+			"FirstResponseSeconds": sla.FirstResponseSeconds,
+			"HandleSeconds":        sla.HandleSeconds,
+		},
+		"$setOnInsert": bson.M{
+			"FirstResponseBreached": false,
+			"HandleBreached":        false,
+		},
+	}, options.Update().SetUpsert(true))
+
+	return
+}
+
+func (impl *mongoSLAModelImpl) SetTalkSLAServicer(ctx context.Context, talkID string, servicerID uint64, at int64) error {
+	_, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"ServicerID": servicerID,
+			"AttachedAt": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$AttachedAt", 0}}, at, "$AttachedAt"}},
+		}}},
+	})
+
+	return err
+}
+
+func (impl *mongoSLAModelImpl) SetTalkSLAFirstResponse(ctx context.Context, talkID string, at int64) (bool, error) {
+	result, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID, "FirstResponseAt": 0}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"FirstResponseAt":      at,
+			"FirstResponseSeconds": bson.M{"$subtract": bson.A{at, "$StartAt"}},
+		}}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (impl *mongoSLAModelImpl) SetTalkSLARating(ctx context.Context, talkID string, rating int) error {
+	_, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID}, bson.M{
+		"$set": bson.M{"Rating": rating},
+	})
+
+	return err
+}
+
+func (impl *mongoSLAModelImpl) SetTalkSLAClosed(ctx context.Context, talkID string, at int64) (bool, error) {
+	result, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID, "ClosedAt": 0}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"ClosedAt": at,
+			"HandleSeconds": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$AttachedAt", 0}},
+				bson.M{"$subtract": bson.A{at, "$AttachedAt"}}, "$HandleSeconds"}},
+		}}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (impl *mongoSLAModelImpl) MarkTalkSLABreached(ctx context.Context, talkID string, metric defs.SLAMetric) (bool, error) {
+	var key string
+
+	switch metric {
+	case defs.SLAMetricFirstResponse:
+		key = "FirstResponseBreached"
+	case defs.SLAMetricHandle:
+		key = "HandleBreached"
+	default:
+		return false, commerr.ErrInvalidArgument
+	}
+
+	result, err := impl.collection.UpdateOne(ctx, bson.M{"_id": talkID, key: false}, bson.M{
+		"$set": bson.M{key: true},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (impl *mongoSLAModelImpl) QueryTalkSLAs(ctx context.Context, actIDs, bizIDs []string, startAt, finishAt int64) (slas []*defs.TalkSLA, err error) {
+	filter := mongoScopeFilter(actIDs, bizIDs)
+
+	startAtFilter := bson.M{}
+
+	if startAt > 0 {
+		startAtFilter["$gte"] = startAt
+	}
+
+	if finishAt > 0 {
+		startAtFilter["$lt"] = finishAt
+	}
+
+	if len(startAtFilter) > 0 {
+		filter["StartAt"] = startAtFilter
+	}
+
+	cursor, err := impl.collection.Find(ctx, filter)
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &slas)
+
+	return
+}
+
+func (impl *mongoSLAModelImpl) QueryOpenTalkSLAs(ctx context.Context, afterTalkID string, limit int64) (slas []*defs.TalkSLA, err error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+
+	cursor, err := impl.collection.Find(ctx, bson.M{"ClosedAt": 0, "_id": bson.M{"$gt": afterTalkID}}, findOptions)
+	if err != nil {
+		return
+	}
+
+	err = cursor.All(ctx, &slas)
+
+	return
+}
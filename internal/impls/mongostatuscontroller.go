@@ -0,0 +1,159 @@
+package impls
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionBannedTokens     = "servicer_banned_tokens"
+	collectionServicerSessions = "servicer_sessions"
+)
+
+func NewMongoServicerStatusController(dsn string) (defs.ServicerStatusController, error) {
+	bannedTokens, err := newMongoCollection(dsn, collectionBannedTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoServicerStatusControllerImpl{
+		bannedTokens: bannedTokens,
+		sessions:     bannedTokens.Database().Collection(collectionServicerSessions),
+	}, nil
+}
+
+type mongoServicerStatusControllerImpl struct {
+	bannedTokens *mongo.Collection
+	sessions     *mongo.Collection
+}
+
+func (impl *mongoServicerStatusControllerImpl) IsTokenBanned(ctx context.Context, id uint64) (bool, error) {
+	cnt, err := impl.bannedTokens.CountDocuments(ctx, bson.M{
+		"_id": strconv.FormatUint(id, 10),
+		"$or": bson.A{
+			bson.M{"ExpireAt": 0},
+			bson.M{"ExpireAt": bson.M{"$gt": time.Now().Unix()}},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+}
+
+func (impl *mongoServicerStatusControllerImpl) BanToken(ctx context.Context, id uint64, expireAt int64) error {
+	if expireAt != 0 && expireAt <= time.Now().Unix() {
+		return nil
+	}
+
+	_, err := impl.bannedTokens.UpdateOne(ctx, bson.M{
+		"_id": strconv.FormatUint(id, 10),
+	}, bson.M{
+		"$set": bson.M{
+			"ExpireAt": expireAt,
+		},
+	}, options.Update().SetUpsert(true))
+
+	return err
+}
+
+func (impl *mongoServicerStatusControllerImpl) AddSession(ctx context.Context, session *defs.ServicerSession) error {
+	if session == nil || session.ID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err := impl.sessions.InsertOne(ctx, session)
+	if mongo.IsDuplicateKeyError(err) {
+		err = commerr.ErrAlreadyExists
+	}
+
+	return err
+}
+
+func (impl *mongoServicerStatusControllerImpl) GetSession(ctx context.Context, id string) (*defs.ServicerSession, error) {
+	var session defs.ServicerSession
+
+	err := impl.sessions.FindOne(ctx, bson.M{
+		"_id": id,
+	}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = commerr.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (impl *mongoServicerStatusControllerImpl) TouchSession(ctx context.Context, id string, lastSeenAt, expireAt int64) error {
+	set := bson.M{
+		"LastSeenAt": lastSeenAt,
+	}
+
+	if expireAt != 0 {
+		set["ExpireAt"] = expireAt
+	}
+
+	r, err := impl.sessions.UpdateOne(ctx, bson.M{
+		"_id": id,
+	}, bson.M{
+		"$set": set,
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return commerr.ErrNotFound
+	}
+
+	return nil
+}
+
+func (impl *mongoServicerStatusControllerImpl) QuerySessions(ctx context.Context, userID uint64, now int64) (
+	sessions []*defs.ServicerSession, err error) {
+	// the expired sessions of all users are cleaned up on the way
+	_, _ = impl.sessions.DeleteMany(ctx, bson.M{
+		"ExpireAt": bson.M{"$lte": now},
+	})
+
+	cursor, err := impl.sessions.Find(ctx, bson.M{
+		"UserID":   userID,
+		"ExpireAt": bson.M{"$gt": now},
+	}, options.Find().SetSort(bson.M{"LastSeenAt": -1}))
+	if err != nil {
+		return
+	}
+
+	sessions = make([]*defs.ServicerSession, 0)
+	err = cursor.All(ctx, &sessions)
+
+	return
+}
+
+func (impl *mongoServicerStatusControllerImpl) DeleteSession(ctx context.Context, id string) error {
+	_, err := impl.sessions.DeleteOne(ctx, bson.M{
+		"_id": id,
+	})
+
+	return err
+}
+
+func (impl *mongoServicerStatusControllerImpl) DeleteUserSessions(ctx context.Context, userID uint64) error {
+	_, err := impl.sessions.DeleteMany(ctx, bson.M{
+		"UserID": userID,
+	})
+
+	return err
+}
@@ -0,0 +1,64 @@
+package impls
+
+import (
+	"context"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionTwoFactorPolicies = "two_factor_policies"
+)
+
+func NewMongoTwoFactorPolicyModel(dsn string) (defs.TwoFactorPolicyModel, error) {
+	collection, err := newMongoCollection(dsn, collectionTwoFactorPolicies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongoTwoFactorPolicyModelImpl{
+		collection: collection,
+	}, nil
+}
+
+type mongoTwoFactorPolicyModelImpl struct {
+	collection *mongo.Collection
+}
+
+func (impl *mongoTwoFactorPolicyModelImpl) IsTwoFactorRequired(ctx context.Context, actIDs []string) (bool, error) {
+	if len(actIDs) == 0 {
+		return false, nil
+	}
+
+	cnt, err := impl.collection.CountDocuments(ctx, bson.M{
+		"_id": bson.M{
+			"$in": actIDs,
+		},
+		"Required": true,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+}
+
+func (impl *mongoTwoFactorPolicyModelImpl) SetTwoFactorRequired(ctx context.Context, actID string, required bool) error {
+	if actID == "" {
+		return commerr.ErrInvalidArgument
+	}
+
+	_, err := impl.collection.UpdateOne(ctx, bson.M{
+		"_id": actID,
+	}, bson.M{
+		"$set": bson.M{
+			"Required": required,
+		},
+	}, options.Update().SetUpsert(true))
+
+	return err
+}
@@ -0,0 +1,57 @@
+package impls
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func NewPasswordPolicy(cfg config.PasswordPolicy) defs.PasswordPolicy {
+	return &passwordPolicyImpl{
+		cfg: cfg,
+	}
+}
+
+type passwordPolicyImpl struct {
+	cfg config.PasswordPolicy
+}
+
+func (impl *passwordPolicyImpl) Check(password string) error {
+	if password == "" || utf8.RuneCountInString(password) < impl.cfg.MinLength {
+		return defs.ErrPasswordTooShort
+	}
+
+	var upper, lower, digit, symbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case impl.cfg.RequireUpper && !upper:
+		return defs.ErrPasswordNeedsUpper
+	case impl.cfg.RequireLower && !lower:
+		return defs.ErrPasswordNeedsLower
+	case impl.cfg.RequireDigit && !digit:
+		return defs.ErrPasswordNeedsDigit
+	case impl.cfg.RequireSymbol && !symbol:
+		return defs.ErrPasswordNeedsSymbol
+	}
+
+	return nil
+}
+
+func (impl *passwordPolicyImpl) HistorySize() int {
+	return impl.cfg.HistorySize
+}
@@ -0,0 +1,29 @@
+package impls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(config.PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	})
+
+	assert.ErrorIs(t, policy.Check("Ab1!"), defs.ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.Check("abcdef1!"), defs.ErrPasswordNeedsUpper)
+	assert.ErrorIs(t, policy.Check("ABCDEF1!"), defs.ErrPasswordNeedsLower)
+	assert.ErrorIs(t, policy.Check("Abcdefg!"), defs.ErrPasswordNeedsDigit)
+	assert.ErrorIs(t, policy.Check("Abcdefg1"), defs.ErrPasswordNeedsSymbol)
+	assert.Nil(t, policy.Check("Abcdef1!"))
+
+	assert.ErrorIs(t, NewPasswordPolicy(config.PasswordPolicy{}).Check(""), defs.ErrPasswordTooShort)
+	assert.Nil(t, NewPasswordPolicy(config.PasswordPolicy{}).Check("1"))
+}
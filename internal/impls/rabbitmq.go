@@ -86,6 +86,22 @@ type mqDataServicerDetach struct {
 	ServicerID uint64
 }
 
+type mqDataServicerWatch struct {
+	ServicerID uint64
+	Watch      bool
+}
+
+type mqDataServicerParticipant struct {
+	ServicerID uint64
+	Join       bool
+}
+
+type mqDataServicerKick struct {
+	ServicerID uint64
+	SessionID  string `json:"SessionID,omitempty"`
+	Reason     string
+}
+
 type mqData struct {
 	TalkID         string                `json:"TalkID,omitempty"`
 	ChannelID      string                `json:"ChannelID"` // empty channel id equal talk id
@@ -94,6 +110,14 @@ type mqData struct {
 	TalkClose      *mqDataTalkClose      `json:"TalkClose,omitempty"`
 	ServicerAttach *mqDataServicerAttach `json:"ServicerAttach,omitempty"`
 	ServicerDetach *mqDataServicerDetach `json:"ServicerDetach,omitempty"`
+	ServicerWatch  *mqDataServicerWatch  `json:"ServicerWatch,omitempty"`
+	Whisper        *defs.WhisperMessage  `json:"Whisper,omitempty"`
+
+	ServicerParticipant *mqDataServicerParticipant `json:"ServicerParticipant,omitempty"`
+	MessageRevision     *defs.MessageRevision      `json:"MessageRevision,omitempty"`
+	CustomerBan         *defs.CustomerBan          `json:"CustomerBan,omitempty"`
+	ServicerKick        *mqDataServicerKick        `json:"ServicerKick,omitempty"`
+	SLABreach           *defs.SLABreach            `json:"SLABreach,omitempty"`
 }
 
 type talkTrackStartedEventData struct {
@@ -533,6 +557,10 @@ func (impl *rabbitMQImpl) processMQDelivery(d amqp.Delivery, logger l.Wrapper) {
 			impl.servicerOb.OnTalkCreate(obj.TalkID)
 		}
 	} else if obj.ServicerAttach != nil {
+		if impl.customerOb != nil {
+			impl.customerOb.OnServicerAttachMessage(obj.TalkID, obj.ServicerAttach.ServicerID)
+		}
+
 		if impl.servicerOb != nil {
 			impl.servicerOb.OnServicerAttachMessage(obj.TalkID, obj.ServicerAttach.ServicerID)
 		}
@@ -540,6 +568,40 @@ func (impl *rabbitMQImpl) processMQDelivery(d amqp.Delivery, logger l.Wrapper) {
 		if impl.servicerOb != nil {
 			impl.servicerOb.OnServicerDetachMessage(obj.TalkID, obj.ServicerDetach.ServicerID)
 		}
+	} else if obj.ServicerWatch != nil {
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnServicerWatchMessage(obj.TalkID, obj.ServicerWatch.ServicerID, obj.ServicerWatch.Watch)
+		}
+	} else if obj.Whisper != nil {
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnWhisperMessage(obj.TalkID, obj.Whisper)
+		}
+	} else if obj.ServicerParticipant != nil {
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnServicerParticipantMessage(obj.TalkID, obj.ServicerParticipant.ServicerID,
+				obj.ServicerParticipant.Join)
+		}
+	} else if obj.MessageRevision != nil {
+		if impl.customerOb != nil {
+			impl.customerOb.OnMessageRevision(obj.TalkID, obj.MessageRevision)
+		}
+
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnMessageRevision(obj.TalkID, obj.MessageRevision)
+		}
+	} else if obj.CustomerBan != nil {
+		if impl.customerOb != nil {
+			impl.customerOb.OnCustomerBan(obj.CustomerBan)
+		}
+	} else if obj.ServicerKick != nil {
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnServicerKick(obj.ServicerKick.ServicerID, obj.ServicerKick.SessionID,
+				obj.ServicerKick.Reason)
+		}
+	} else if obj.SLABreach != nil {
+		if impl.servicerOb != nil {
+			impl.servicerOb.OnSLABreach(obj.SLABreach)
+		}
 	} else {
 		logger.Error("UnknownMqData")
 	}
@@ -0,0 +1,216 @@
+package impls
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	defaultRateLimitUserPerSecond          = 5
+	defaultRateLimitUserBurst              = 10
+	defaultRateLimitTalkPerSecond          = 10
+	defaultRateLimitTalkBurst              = 20
+	defaultRateLimitKickViolations         = 20
+	defaultRateLimitViolationWindowSeconds = 60
+	rateLimitCleanupIntervalSeconds        = 60
+)
+
+// NewRateLimiter limits the incoming messages by token buckets, the actID of a talk is looked up only if
+// there are per actID policies.
+func NewRateLimiter(cfg config.RateLimit, m defs.ModelEx, logger l.Wrapper) defs.RateLimiter {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	if cfg.KickViolations == 0 {
+		cfg.KickViolations = defaultRateLimitKickViolations
+	}
+
+	if cfg.ViolationWindowSeconds <= 0 {
+		cfg.ViolationWindowSeconds = defaultRateLimitViolationWindowSeconds
+	}
+
+	return &rateLimiterImpl{
+		cfg:        cfg,
+		m:          m,
+		logger:     logger.WithFields(l.StringField(l.ClsKey, "rateLimiterImpl")),
+		now:        time.Now,
+		buckets:    make(map[string]*tokenBucket),
+		violations: make(map[string]*rateLimitViolations),
+		talkActIDs: make(map[string]string),
+	}
+}
+
+type tokenBucket struct {
+	perSecond float64
+	burst     float64
+	tokens    float64
+	at        time.Time
+}
+
+func (bucket *tokenBucket) take(now time.Time) bool {
+	bucket.refill(now)
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
+
+func (bucket *tokenBucket) refill(now time.Time) {
+	bucket.tokens = math.Min(bucket.burst, bucket.tokens+now.Sub(bucket.at).Seconds()*bucket.perSecond)
+	bucket.at = now
+}
+
+type rateLimitViolations struct {
+	count   int
+	startAt time.Time
+}
+
+type rateLimiterImpl struct {
+	cfg    config.RateLimit
+	m      defs.ModelEx
+	logger l.Wrapper
+	now    func() time.Time
+
+	lock       sync.Mutex
+	buckets    map[string]*tokenBucket
+	violations map[string]*rateLimitViolations
+	talkActIDs map[string]string
+	cleanupAt  time.Time
+}
+
+func (impl *rateLimiterImpl) Take(ctx context.Context, customer bool, userID uint64, talkID string) defs.RateLimitDecision {
+	actID := impl.talkActID(ctx, talkID)
+	policy := impl.cfg.GetPolicy(actID)
+
+	side := "s"
+	if customer {
+		side = "c"
+	}
+
+	userKey := fmt.Sprintf("%s:%d:%s", side, userID, actID)
+	talkKey := fmt.Sprintf("%s:%s", side, talkID)
+
+	impl.lock.Lock()
+	defer impl.lock.Unlock()
+
+	now := impl.now()
+
+	impl.cleanup(now)
+
+	if impl.takeBucket(userKey, policy.User, defaultRateLimitUserPerSecond, defaultRateLimitUserBurst, now) &&
+		impl.takeBucket(talkKey, policy.Talk, defaultRateLimitTalkPerSecond, defaultRateLimitTalkBurst, now) {
+		return defs.RateLimitAllow
+	}
+
+	if impl.cfg.KickViolations < 0 {
+		return defs.RateLimitReject
+	}
+
+	violations, ok := impl.violations[userKey]
+	if !ok || now.Sub(violations.startAt) > time.Duration(impl.cfg.ViolationWindowSeconds)*time.Second {
+		violations = &rateLimitViolations{
+			startAt: now,
+		}
+		impl.violations[userKey] = violations
+	}
+
+	violations.count++
+
+	if violations.count < impl.cfg.KickViolations {
+		return defs.RateLimitReject
+	}
+
+	delete(impl.violations, userKey)
+
+	return defs.RateLimitKick
+}
+
+func (impl *rateLimiterImpl) takeBucket(key string, cfg config.RateLimitBucket, defPerSecond float64, defBurst int,
+	now time.Time) bool {
+	if cfg.PerSecond < 0 {
+		return true
+	}
+
+	bucket, ok := impl.buckets[key]
+	if !ok {
+		if cfg.PerSecond == 0 {
+			cfg.PerSecond = defPerSecond
+		}
+
+		if cfg.Burst <= 0 {
+			cfg.Burst = defBurst
+		}
+
+		bucket = &tokenBucket{
+			perSecond: cfg.PerSecond,
+			burst:     float64(cfg.Burst),
+			tokens:    float64(cfg.Burst),
+			at:        now,
+		}
+		impl.buckets[key] = bucket
+	}
+
+	return bucket.take(now)
+}
+
+// cleanup removes the full buckets and the expired violations, they are the same as new ones.
+func (impl *rateLimiterImpl) cleanup(now time.Time) {
+	if now.Sub(impl.cleanupAt) < rateLimitCleanupIntervalSeconds*time.Second {
+		return
+	}
+
+	impl.cleanupAt = now
+
+	for key, bucket := range impl.buckets {
+		if bucket.refill(now); bucket.tokens >= bucket.burst {
+			delete(impl.buckets, key)
+		}
+	}
+
+	for key, violations := range impl.violations {
+		if now.Sub(violations.startAt) > time.Duration(impl.cfg.ViolationWindowSeconds)*time.Second {
+			delete(impl.violations, key)
+		}
+	}
+
+	impl.talkActIDs = make(map[string]string)
+}
+
+func (impl *rateLimiterImpl) talkActID(ctx context.Context, talkID string) string {
+	if len(impl.cfg.ActIDs) == 0 {
+		return ""
+	}
+
+	impl.lock.Lock()
+	actID, ok := impl.talkActIDs[talkID]
+	impl.lock.Unlock()
+
+	if ok {
+		return actID
+	}
+
+	talkInfo, err := impl.m.GetTalkInfo(ctx, nil, nil, talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("GetTalkInfoFailed")
+
+		return ""
+	}
+
+	impl.lock.Lock()
+	impl.talkActIDs[talkID] = talkInfo.ActID
+	impl.lock.Unlock()
+
+	return talkInfo.ActID
+}
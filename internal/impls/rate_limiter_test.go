@@ -0,0 +1,68 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	m := NewModelEx(NewMemModel(), NewMemMessageExModel())
+
+	talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+		Status:          talkinters.TalkStatusOpened,
+		Title:           "title",
+		CreatorID:       1,
+		CreatorUserName: "customer",
+		ActID:           "vip",
+		BizID:           "biz",
+	})
+	assert.Nil(t, err)
+
+	limiter := NewRateLimiter(config.RateLimit{
+		Default: config.RateLimitPolicy{
+			User: config.RateLimitBucket{PerSecond: 1, Burst: 2},
+			Talk: config.RateLimitBucket{PerSecond: -1},
+		},
+		ActIDs: map[string]config.RateLimitPolicy{
+			"vip": {
+				User: config.RateLimitBucket{PerSecond: 1, Burst: 3},
+				Talk: config.RateLimitBucket{PerSecond: -1},
+			},
+		},
+		KickViolations: 3,
+	}, m, nil)
+
+	now := time.Unix(1000, 0)
+	limiter.(*rateLimiterImpl).now = func() time.Time {
+		return now
+	}
+
+	for idx := 0; idx < 3; idx++ {
+		assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, true, 1, talkID))
+	}
+
+	assert.Equal(t, defs.RateLimitReject, limiter.Take(ctx, true, 1, talkID))
+
+	// the servicer side has its own buckets
+	assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, false, 1, talkID))
+
+	// the default policy for the unknown talk
+	assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, true, 2, "unknown"))
+	assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, true, 2, "unknown"))
+	assert.Equal(t, defs.RateLimitReject, limiter.Take(ctx, true, 2, "unknown"))
+
+	now = now.Add(time.Second)
+	assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, true, 1, talkID))
+	assert.Equal(t, defs.RateLimitReject, limiter.Take(ctx, true, 1, talkID))
+	assert.Equal(t, defs.RateLimitKick, limiter.Take(ctx, true, 1, talkID))
+
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, defs.RateLimitAllow, limiter.Take(ctx, true, 1, talkID))
+}
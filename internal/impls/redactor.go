@@ -0,0 +1,345 @@
+package impls
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	redactionMaskRune     = '*'
+	redactionKeptTailLen  = 4
+	redactionCardMinDigit = 13
+	redactionCardMaxDigit = 19
+)
+
+var defRedactionRules = []config.RedactionRule{
+	{Name: "card", Pattern: `\b\d(?:[ -]?\d){12,18}\b`, Luhn: true},
+	{Name: "phone", Pattern: `\b1[3-9]\d{9}\b`},
+	{Name: "id", Pattern: `\b\d{17}[\dXx]\b`},
+}
+
+type redactionRule struct {
+	name   string
+	regexp *regexp.Regexp
+	luhn   bool
+}
+
+func NewRedactor(cfg config.Redaction, m defs.ModelEx, logger l.Wrapper) (defs.Redactor, error) {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	cfgRules := cfg.Rules
+	if len(cfgRules) == 0 {
+		cfgRules = defRedactionRules
+	}
+
+	rules := make([]*redactionRule, 0, len(cfgRules))
+
+	for _, cfgRule := range cfgRules {
+		r, err := regexp.Compile(cfgRule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, &redactionRule{
+			name:   cfgRule.Name,
+			regexp: r,
+			luhn:   cfgRule.Luhn,
+		})
+	}
+
+	impl := &redactorImpl{
+		cfg:    cfg,
+		m:      m,
+		logger: logger.WithFields(l.StringField(l.ClsKey, "redactorImpl")),
+		rules:  rules,
+	}
+
+	if cfg.EncryptKey != "" {
+		key, err := hex.DecodeString(cfg.EncryptKey)
+		if err != nil {
+			return nil, err
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if impl.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+
+	policies := []config.RedactionPolicy{cfg.Default}
+	for _, policy := range cfg.ActIDs {
+		policies = append(policies, policy)
+	}
+
+	for _, policy := range policies {
+		if policy.Mode == config.RedactionModeEncrypt && impl.aead == nil {
+			return nil, errors.New("no encrypt key for the redaction")
+		}
+	}
+
+	return impl, nil
+}
+
+type redactorImpl struct {
+	cfg    config.Redaction
+	m      defs.ModelEx
+	logger l.Wrapper
+	rules  []*redactionRule
+	aead   cipher.AEAD
+}
+
+func (impl *redactorImpl) Redact(ctx context.Context, talkID string, message *talkinters.TalkMessageW) error {
+	if message == nil || (message.Type != talkinters.TalkMessageTypeText && !vo.IsRichMessageType(message.Type)) {
+		return nil
+	}
+
+	policy := impl.policy(ctx, talkID)
+	if policy.Mode == "" {
+		return nil
+	}
+
+	if message.Type != talkinters.TalkMessageTypeText {
+		return impl.redactRichMessage(policy, message)
+	}
+
+	masked, ok := impl.mask(policy, message.Text)
+	if !ok {
+		return nil
+	}
+
+	var redactedText defs.RedactedText
+
+	switch policy.Mode {
+	case config.RedactionModeStorage:
+		message.Text = masked
+
+		return nil
+	case config.RedactionModeServicer:
+		redactedText.Masked = masked
+	case config.RedactionModeEncrypt:
+		encrypted, err := impl.encrypt(message.Text)
+		if err != nil {
+			return err
+		}
+
+		redactedText.Encrypted = encrypted
+		message.Text = masked
+	default:
+		return commerr.ErrInvalidArgument
+	}
+
+	d, err := json.Marshal(&redactedText)
+	if err != nil {
+		return err
+	}
+
+	message.Data = d
+
+	return nil
+}
+
+// redactRichMessage masks the free texts of a rich message in storage by any mode, the payload has no room
+// for the original.
+func (impl *redactorImpl) redactRichMessage(policy config.RedactionPolicy, message *talkinters.TalkMessageW) error {
+	richMessage, ok := vo.RichMessageFromDB(message)
+	if !ok {
+		return nil
+	}
+
+	var texts []*string
+
+	if richMessage.File != nil {
+		texts = append(texts, &richMessage.File.Name)
+	}
+
+	if richMessage.Location != nil {
+		texts = append(texts, &richMessage.Location.Name, &richMessage.Location.Address)
+	}
+
+	if richMessage.Card != nil {
+		texts = append(texts, &richMessage.Card.Title, &richMessage.Card.Description)
+	}
+
+	if richMessage.QuickReply != nil {
+		texts = append(texts, &richMessage.QuickReply.Text)
+	}
+
+	if richMessage.Quote != nil {
+		texts = append(texts, &richMessage.Quote.Text)
+	}
+
+	redacted := false
+
+	for _, text := range texts {
+		if masked, okM := impl.mask(policy, *text); okM {
+			*text = masked
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return nil
+	}
+
+	return vo.UpdateRichMessage(message, richMessage)
+}
+
+func (impl *redactorImpl) RedactText(ctx context.Context, talkID, text string) string {
+	policy := impl.policy(ctx, talkID)
+	if policy.Mode == "" {
+		return text
+	}
+
+	if masked, ok := impl.mask(policy, text); ok {
+		return masked
+	}
+
+	return text
+}
+
+func (impl *redactorImpl) Reveal(message *talkinters.TalkMessageW) (string, error) {
+	if message == nil || message.Type != talkinters.TalkMessageTypeText {
+		return "", commerr.ErrInvalidArgument
+	}
+
+	redactedText, ok := vo.RedactedTextFromDB(message)
+	if !ok || len(redactedText.Encrypted) == 0 {
+		return message.Text, nil
+	}
+
+	if impl.aead == nil {
+		return "", commerr.ErrUnavailable
+	}
+
+	nonceSize := impl.aead.NonceSize()
+	if len(redactedText.Encrypted) < nonceSize {
+		return "", commerr.ErrBadFormat
+	}
+
+	d, err := impl.aead.Open(nil, redactedText.Encrypted[:nonceSize], redactedText.Encrypted[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(d), nil
+}
+
+func (impl *redactorImpl) policy(ctx context.Context, talkID string) config.RedactionPolicy {
+	if len(impl.cfg.ActIDs) == 0 {
+		return impl.cfg.Default
+	}
+
+	talkInfo, err := impl.m.GetTalkInfo(ctx, nil, nil, talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("GetTalkInfoFailed")
+
+		return impl.cfg.Default
+	}
+
+	return impl.cfg.GetPolicy(talkInfo.ActID)
+}
+
+func (impl *redactorImpl) mask(policy config.RedactionPolicy, text string) (masked string, ok bool) {
+	masked = text
+
+	for _, rule := range impl.rules {
+		if len(policy.Rules) > 0 && !slices.Contains(policy.Rules, rule.name) {
+			continue
+		}
+
+		masked = rule.regexp.ReplaceAllStringFunc(masked, func(s string) string {
+			if rule.luhn && !luhnValid(s) {
+				return s
+			}
+
+			ok = true
+
+			return maskSensitive(s)
+		})
+	}
+
+	return
+}
+
+func (impl *redactorImpl) encrypt(text string) ([]byte, error) {
+	nonce := make([]byte, impl.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return impl.aead.Seal(nonce, nonce, []byte(text), nil), nil
+}
+
+// maskSensitive masks the digits and letters but the last few ones, separators are kept.
+func maskSensitive(s string) string {
+	runes := []rune(s)
+	kept := 0
+
+	for idx := len(runes) - 1; idx >= 0; idx-- {
+		if runes[idx] == ' ' || runes[idx] == '-' {
+			continue
+		}
+
+		if kept < redactionKeptTailLen {
+			kept++
+
+			continue
+		}
+
+		runes[idx] = redactionMaskRune
+	}
+
+	return string(runes)
+}
+
+func luhnValid(s string) bool {
+	var digits []int
+
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+
+	if len(digits) < redactionCardMinDigit || len(digits) > redactionCardMaxDigit {
+		return false
+	}
+
+	sum := 0
+
+	for idx := len(digits) - 1; idx >= 0; idx-- {
+		digit := digits[idx]
+
+		if (len(digits)-1-idx)%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
@@ -0,0 +1,84 @@
+package impls
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+func TestRedactor(t *testing.T) {
+	ctx := context.Background()
+	m := NewModelEx(NewMemModel(), NewMemMessageExModel())
+
+	newTalk := func(actID string) string {
+		talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+			Status:          talkinters.TalkStatusOpened,
+			Title:           "title",
+			CreatorID:       1,
+			CreatorUserName: "customer",
+			ActID:           actID,
+			BizID:           "biz",
+		})
+		assert.Nil(t, err)
+
+		return talkID
+	}
+
+	redactor, err := NewRedactor(config.Redaction{
+		Default: config.RedactionPolicy{Mode: config.RedactionModeStorage},
+		ActIDs: map[string]config.RedactionPolicy{
+			"servicer": {Mode: config.RedactionModeServicer},
+			"encrypt":  {Mode: config.RedactionModeEncrypt, Rules: []string{"card"}},
+		},
+		EncryptKey: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	}, m, nil)
+	assert.Nil(t, err)
+
+	const text = "card 4111 1111 1111 1111, not 4111 1111 1111 1112, phone 13812345678"
+
+	newMessage := func() *talkinters.TalkMessageW {
+		return &talkinters.TalkMessageW{Type: talkinters.TalkMessageTypeText, Text: text}
+	}
+
+	message := newMessage()
+	assert.Nil(t, redactor.Redact(ctx, newTalk("storage"), message))
+	assert.Equal(t, "card **** **** **** 1111, not 4111 1111 1111 1112, phone *******5678", message.Text)
+	assert.Nil(t, message.Data)
+
+	message = newMessage()
+	assert.Nil(t, redactor.Redact(ctx, newTalk("servicer"), message))
+	assert.Equal(t, text, message.Text)
+	assert.Equal(t, "card **** **** **** 1111, not 4111 1111 1111 1112, phone *******5678",
+		vo.TalkMessageDB2Pb4Servicer(message).GetText())
+	assert.Equal(t, text, vo.TalkMessageDB2Pb4Customer(message).GetText())
+
+	message = newMessage()
+	assert.Nil(t, redactor.Redact(ctx, newTalk("encrypt"), message))
+	assert.Equal(t, "card **** **** **** 1111, not 4111 1111 1111 1112, phone 13812345678", message.Text)
+
+	original, err := redactor.Reveal(message)
+	assert.Nil(t, err)
+	assert.Equal(t, text, original)
+
+	// the free texts of the rich messages are masked in storage by any mode
+	for _, actID := range []string{"storage", "servicer"} {
+		message = &talkinters.TalkMessageW{Type: defs.TalkMessageTypeLocation}
+		assert.Nil(t, vo.UpdateRichMessage(message, &defs.RichMessage{Kind: defs.RichMessageKindLocation,
+			Location: &defs.LocationMessage{Name: "home", Address: "call 13812345678"}}))
+		assert.Nil(t, redactor.Redact(ctx, newTalk(actID), message))
+
+		richMessage, ok := vo.RichMessageFromDB(message)
+		assert.True(t, ok)
+		assert.Equal(t, "home", richMessage.Location.Name)
+		assert.Equal(t, "call *******5678", richMessage.Location.Address)
+		assert.NotContains(t, vo.TalkMessageDB2Pb4Customer(message).GetText(), "13812345678")
+	}
+
+	_, err = NewRedactor(config.Redaction{Default: config.RedactionPolicy{Mode: config.RedactionModeEncrypt}}, m, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,268 @@
+package impls
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"golang.org/x/exp/slices"
+)
+
+// maxReportDuration caps the time range of a report, the talks started in the range or in the same duration before
+// it are loaded at once, the earlier ones aren't counted as closed in the range.
+const maxReportDuration = 400 * 24 * time.Hour
+
+// NewReporter computes the reports from the talks and their SLA records, they're created with the talks and updated
+// on attaching, responding, rating and closing. The messages are loaded only for the talks without records.
+func NewReporter(m defs.ModelEx, slaM defs.TalkSLAModel) defs.Reporter {
+	return &reporterImpl{
+		m:    m,
+		slaM: slaM,
+	}
+}
+
+type reporterImpl struct {
+	m    defs.ModelEx
+	slaM defs.TalkSLAModel
+}
+
+type reportRowAcc struct {
+	row *defs.ReportRow
+
+	firstResponseSum   int64
+	firstResponseCount int64
+	handleSum          int64
+	handleCount        int64
+	ratingSum          int64
+	servicedTalks      int64
+	servicers          map[uint64]struct{}
+}
+
+// Report counts a talk created in the range by its start day, and closed in the range by its close day.
+func (impl *reporterImpl) Report(ctx context.Context, request *defs.ReportRequest) (*defs.Report, error) {
+	if request == nil || request.StartAt <= 0 || (request.FinishAt > 0 && request.FinishAt <= request.StartAt) {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	finishAt := request.FinishAt
+	if finishAt == 0 {
+		finishAt = time.Now().Unix()
+	}
+
+	if time.Duration(finishAt-request.StartAt)*time.Second > maxReportDuration {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	slas, err := impl.talkSLAs(ctx, request, finishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	inRange := func(at int64) bool {
+		return at >= request.StartAt && at < finishAt
+	}
+
+	accMap := make(map[string]*reportRowAcc)
+
+	for _, sla := range slas {
+		if inRange(sla.StartAt) {
+			acc := impl.getRowAcc(accMap, request.GroupBy, sla, sla.StartAt)
+
+			acc.row.TalksCreated++
+
+			if sla.ServicerID > 0 {
+				acc.servicedTalks++
+				acc.servicers[sla.ServicerID] = struct{}{}
+			}
+
+			if sla.FirstResponseAt > 0 {
+				acc.firstResponseSum += sla.FirstResponseSeconds
+				acc.firstResponseCount++
+			}
+
+			if sla.Rating > 0 {
+				acc.ratingSum += int64(sla.Rating)
+				acc.row.RatedTalks++
+			}
+		}
+
+		if sla.ClosedAt > 0 && inRange(sla.ClosedAt) {
+			acc := impl.getRowAcc(accMap, request.GroupBy, sla, sla.ClosedAt)
+
+			acc.row.TalksClosed++
+
+			if sla.AttachedAt > 0 {
+				acc.handleSum += sla.HandleSeconds
+				acc.handleCount++
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(accMap))
+	for key := range accMap {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	report := &defs.Report{
+		StartAt:  request.StartAt,
+		FinishAt: finishAt,
+		Rows:     make([]*defs.ReportRow, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		acc := accMap[key]
+
+		if acc.firstResponseCount > 0 {
+			acc.row.AvgFirstResponseSeconds = float64(acc.firstResponseSum) / float64(acc.firstResponseCount)
+		}
+
+		if acc.handleCount > 0 {
+			acc.row.AvgHandleSeconds = float64(acc.handleSum) / float64(acc.handleCount)
+		}
+
+		if acc.row.RatedTalks > 0 {
+			acc.row.AvgCSAT = float64(acc.ratingSum) / float64(acc.row.RatedTalks)
+		}
+
+		if len(acc.servicers) > 0 {
+			acc.row.TalksPerServicer = float64(acc.servicedTalks) / float64(len(acc.servicers))
+		}
+
+		report.Rows = append(report.Rows, acc.row)
+	}
+
+	return report, nil
+}
+
+//
+//
+//
+
+// talkSLAs returns the SLA records of the talks started in the range or in maxReportDuration before it,
+// the records of the talks without ones are made from their messages.
+func (impl *reporterImpl) talkSLAs(ctx context.Context, request *defs.ReportRequest, finishAt int64) ([]*defs.TalkSLA, error) {
+	startAt := request.StartAt - int64(maxReportDuration/time.Second)
+
+	talkInfos, err := impl.m.QueryTalks(ctx, request.ActIDs, request.BizIDs, 0, 0, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := impl.slaM.QueryTalkSLAs(ctx, request.ActIDs, request.BizIDs, startAt, finishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	recordMap := make(map[string]*defs.TalkSLA, len(records))
+	for _, record := range records {
+		recordMap[record.TalkID] = record
+	}
+
+	slas := make([]*defs.TalkSLA, 0, len(talkInfos))
+
+	for _, talkInfo := range talkInfos {
+		if talkInfo.StartAt < startAt || talkInfo.StartAt >= finishAt {
+			continue
+		}
+
+		if sla, ok := recordMap[talkInfo.TalkID]; ok {
+			slas = append(slas, sla)
+
+			continue
+		}
+
+		messages, errG := impl.m.GetTalkMessages(ctx, talkInfo.TalkID, 0, 0)
+		if errG != nil {
+			return nil, errG
+		}
+
+		slas = append(slas, talkSLAFromMessages(talkInfo, messages))
+	}
+
+	return slas, nil
+}
+
+// talkSLAFromMessages makes the SLA record of a talk from its system, servicer and rating messages.
+func talkSLAFromMessages(talkInfo *talkinters.TalkInfoR, messages []*talkinters.TalkMessageR) *defs.TalkSLA {
+	sla := &defs.TalkSLA{
+		TalkID:     talkInfo.TalkID,
+		ActID:      talkInfo.ActID,
+		BizID:      talkInfo.BizID,
+		ServicerID: talkInfo.ServiceID,
+		StartAt:    talkInfo.StartAt,
+	}
+
+	for _, message := range messages {
+		richMessage, _ := vo.RichMessageFromDB(&message.TalkMessageW)
+
+		switch {
+		case message.Type == defs.TalkMessageTypeSystem && richMessage != nil && richMessage.System != nil:
+			switch richMessage.System.Event {
+			case defs.SystemEventAttach, defs.SystemEventTransfer:
+				sla.AttachedAt = message.At
+			case defs.SystemEventClose:
+				sla.ClosedAt = message.At
+			}
+		case message.Type == defs.TalkMessageTypeRating && richMessage != nil && richMessage.Rating != nil:
+			sla.Rating = richMessage.Rating.Score
+		case !message.CustomerMessage && message.Type != defs.TalkMessageTypeSystem && sla.FirstResponseAt == 0:
+			sla.FirstResponseAt = message.At
+			sla.FirstResponseSeconds = message.At - sla.StartAt
+		}
+	}
+
+	if talkInfo.Status != talkinters.TalkStatusClosed {
+		sla.ClosedAt = 0
+	}
+
+	if sla.AttachedAt > 0 && sla.ClosedAt > 0 {
+		sla.HandleSeconds = sla.ClosedAt - sla.AttachedAt
+	}
+
+	return sla
+}
+
+func (impl *reporterImpl) getRowAcc(accMap map[string]*reportRowAcc, groupBy []defs.ReportGroupKey,
+	sla *defs.TalkSLA, at int64) *reportRowAcc {
+	row := &defs.ReportRow{}
+
+	if slices.Contains(groupBy, defs.ReportGroupKeyServicer) {
+		row.ServicerID = sla.ServicerID
+	}
+
+	if slices.Contains(groupBy, defs.ReportGroupKeyBizID) {
+		row.BizID = sla.BizID
+	}
+
+	startAt := time.Unix(at, 0)
+
+	if slices.Contains(groupBy, defs.ReportGroupKeyDay) {
+		row.Period = startAt.Format("2006-01-02")
+	} else if slices.Contains(groupBy, defs.ReportGroupKeyWeek) {
+		year, week := startAt.ISOWeek()
+		row.Period = fmt.Sprintf("%04d-W%02d", year, week)
+	} else if slices.Contains(groupBy, defs.ReportGroupKeyMonth) {
+		row.Period = startAt.Format("2006-01")
+	}
+
+	key := fmt.Sprintf("%s|%s|%020d", row.Period, row.BizID, row.ServicerID)
+
+	acc, ok := accMap[key]
+	if !ok {
+		acc = &reportRowAcc{
+			row:       row,
+			servicers: make(map[uint64]struct{}),
+		}
+
+		accMap[key] = acc
+	}
+
+	return acc
+}
@@ -0,0 +1,150 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+func TestReporter(t *testing.T) {
+	ctx := context.Background()
+	m := NewModelEx(NewMemModel(), NewMemMessageExModel())
+	slaM := NewMemSLAModel()
+
+	startAt := time.Date(2022, 11, 1, 10, 0, 0, 0, time.Local).Unix()
+
+	newTalk := func(bizID string, startAt int64, servicerID uint64, closed bool) string {
+		talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+			Status:          talkinters.TalkStatusOpened,
+			Title:           "title",
+			StartAt:         startAt,
+			CreatorID:       1,
+			CreatorUserName: "customer",
+			ActID:           "act",
+			BizID:           bizID,
+		})
+		assert.Nil(t, err)
+
+		if servicerID > 0 {
+			assert.Nil(t, m.UpdateTalkServiceID(ctx, nil, nil, talkID, servicerID))
+		}
+
+		if closed {
+			assert.Nil(t, m.CloseTalk(ctx, nil, nil, talkID))
+		}
+
+		return talkID
+	}
+
+	newSLA := func(bizID string, startAt int64, servicerID uint64, closed bool, rating int) {
+		sla := &defs.TalkSLA{
+			TalkID:  newTalk(bizID, startAt, servicerID, closed),
+			ActID:   "act",
+			BizID:   bizID,
+			StartAt: startAt,
+			Rating:  rating,
+		}
+
+		if servicerID > 0 {
+			sla.ServicerID = servicerID
+			sla.AttachedAt = startAt + 10
+			sla.FirstResponseAt = startAt + 30
+			sla.FirstResponseSeconds = 30
+		}
+
+		if closed {
+			sla.ClosedAt = startAt + 110
+			sla.HandleSeconds = 100
+		}
+
+		assert.Nil(t, slaM.UpdateTalkSLA(ctx, sla))
+	}
+
+	newSLA("biz1", startAt, 100, true, 5)
+	newSLA("biz1", startAt+60, 101, false, 2)
+	newSLA("biz2", startAt+24*3600, 0, false, 0)
+	newSLA("biz2", startAt-24*3600, 0, false, 0)
+
+	// the talk without a record is read from its messages, it's closed on the next day
+	talkID := newTalk("biz1", startAt+120, 100, true)
+
+	for _, system := range []*defs.SystemMessage{
+		{Event: defs.SystemEventAttach, ServicerID: 100},
+		{Event: defs.SystemEventClose, ServicerID: 100},
+	} {
+		at := startAt + 140
+		if system.Event == defs.SystemEventClose {
+			at = startAt + 24*3600 + 140
+		}
+
+		message, err := vo.NewSystemMessage(at, system)
+		assert.Nil(t, err)
+
+		_, err = m.AddTalkMessageWithID(ctx, talkID, message)
+		assert.Nil(t, err)
+
+		if system.Event == defs.SystemEventAttach {
+			_, err = m.AddTalkMessageWithID(ctx, talkID, &talkinters.TalkMessageW{At: startAt + 180,
+				Type: talkinters.TalkMessageTypeText, SenderID: 100, Text: "hello"})
+			assert.Nil(t, err)
+		}
+	}
+
+	reporter := NewReporter(m, slaM)
+
+	report, err := reporter.Report(ctx, &defs.ReportRequest{
+		StartAt:  startAt,
+		FinishAt: startAt + 7*24*3600,
+		GroupBy:  []defs.ReportGroupKey{defs.ReportGroupKeyDay},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, startAt+7*24*3600, report.FinishAt)
+	assert.Len(t, report.Rows, 2)
+	assert.EqualValues(t, 3, report.Rows[0].TalksCreated)
+	assert.EqualValues(t, 1, report.Rows[0].TalksClosed)
+	assert.EqualValues(t, 40, report.Rows[0].AvgFirstResponseSeconds)
+	assert.EqualValues(t, 100, report.Rows[0].AvgHandleSeconds)
+	assert.EqualValues(t, 1.5, report.Rows[0].TalksPerServicer)
+	assert.EqualValues(t, 2, report.Rows[0].RatedTalks)
+	assert.EqualValues(t, 3.5, report.Rows[0].AvgCSAT)
+	assert.EqualValues(t, 1, report.Rows[1].TalksCreated)
+	assert.EqualValues(t, 1, report.Rows[1].TalksClosed)
+	assert.EqualValues(t, 24*3600, report.Rows[1].AvgHandleSeconds)
+	assert.EqualValues(t, 0, report.Rows[1].RatedTalks)
+
+	report, err = reporter.Report(ctx, &defs.ReportRequest{
+		StartAt:  startAt,
+		FinishAt: startAt + 7*24*3600,
+		GroupBy:  []defs.ReportGroupKey{defs.ReportGroupKeyServicer},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, report.Rows, 3)
+
+	// the talk started before the range is counted as closed in it
+	report, err = reporter.Report(ctx, &defs.ReportRequest{
+		StartAt:  startAt + 24*3600,
+		FinishAt: startAt + 2*24*3600,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, report.Rows, 1)
+	assert.EqualValues(t, 1, report.Rows[0].TalksCreated)
+	assert.EqualValues(t, 1, report.Rows[0].TalksClosed)
+
+	// the time range is capped
+	_, err = reporter.Report(ctx, &defs.ReportRequest{
+		StartAt:  startAt,
+		FinishAt: startAt + 500*24*3600,
+	})
+	assert.NotNil(t, err)
+
+	_, err = reporter.Report(ctx, &defs.ReportRequest{
+		StartAt:  startAt,
+		FinishAt: startAt - 1,
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,176 @@
+package impls
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+
+	"github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	bootstrapPasswordLength = 20
+	bootstrapPasswordUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	bootstrapPasswordLower  = "abcdefghijkmnopqrstuvwxyz"
+	bootstrapPasswordDigit  = "23456789"
+	bootstrapPasswordSymbol = "!#%+-=?@_"
+)
+
+// BootstrapServicerOwner creates the initial owner in all the acts on the first run, nothing is done if any
+// servicer exists, so it's safe to call on every start.
+func BootstrapServicerOwner(ctx context.Context, cfg config.Bootstrap, model defs.ServicerUserModel,
+	manager userpass.Manager, policy defs.PasswordPolicy, logger l.Wrapper) error {
+	_, total, err := model.QueryUsers(ctx, nil, "", 0, 1)
+	if err != nil {
+		return err
+	}
+
+	if total > 0 {
+		return nil
+	}
+
+	if cfg.UserName == "" {
+		logger.Warn("NoServicerAndNoBootstrapUserName")
+
+		return nil
+	}
+
+	if len(cfg.ActIDs) == 0 || len(cfg.BizIDs) == 0 {
+		return errors.New("no acts or bizs for the bootstrap servicer " + cfg.UserName)
+	}
+
+	// the stderr is kept by the container logs, the generated password is only written to the file
+	if cfg.Password == "" && cfg.PasswordFile == "" {
+		return errors.New("no password file for the generated password of the bootstrap servicer " + cfg.UserName)
+	}
+
+	password, generated := cfg.Password, false
+
+	if password == "" {
+		password, err = generateBootstrapPassword(policy)
+		if err != nil {
+			return err
+		}
+
+		generated = true
+	} else if err = policy.Check(password); err != nil {
+		return err
+	}
+
+	userID, err := manager.Register(ctx, cfg.UserName, password)
+	if err != nil {
+		if u, _ := model.GetUserByUserName(ctx, cfg.UserName); u != nil {
+			// another instance bootstrapped at the same time
+			return nil
+		}
+
+		return err
+	}
+
+	err = manager.UpdateUserAllExData(ctx, userID, map[string]interface{}{
+		dKeyExDataActIDs:           cfg.ActIDs,
+		dKeyExDataBizIDs:           cfg.BizIDs,
+		dKeyExDataRoles:            rolesExData(defs.ServicerRoles{defs.AllActs: defs.ServicerRoleOwner}),
+		dKeyPasswordChangeRequired: true,
+	})
+	if err != nil {
+		_ = model.DeleteUser(ctx, userID)
+
+		return err
+	}
+
+	if generated {
+		// the only chance to know the password, it's never logged
+		if err = writeBootstrapPassword(cfg, password); err != nil {
+			_ = model.DeleteUser(ctx, userID)
+
+			return err
+		}
+	}
+
+	logger.WithFields(l.UInt64Field("userID", userID), l.StringField("userName", cfg.UserName),
+		l.AnyField("actIDs", cfg.ActIDs), l.AnyField("bizIDs", cfg.BizIDs), l.BoolField("generated", generated),
+		l.StringField("passwordFile", cfg.PasswordFile)).Warn("ServicerOwnerBootstrapped")
+
+	return nil
+}
+
+// writeBootstrapPassword writes the generated password to the password file only readable by the owner.
+func writeBootstrapPassword(cfg config.Bootstrap, password string) error {
+	// a stale file may be readable by others, it's replaced
+	if err := os.Remove(cfg.PasswordFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	f, err := os.OpenFile(cfg.PasswordFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(password + "\n"); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// generateBootstrapPassword generates a password with all the character classes, longer than the policy requires.
+func generateBootstrapPassword(policy defs.PasswordPolicy) (string, error) {
+	classes := []string{bootstrapPasswordUpper, bootstrapPasswordLower, bootstrapPasswordDigit, bootstrapPasswordSymbol}
+
+	var all string
+
+	for _, class := range classes {
+		all += class
+	}
+
+	// longer passwords for the longer min lengths
+	for length := bootstrapPasswordLength; length <= bootstrapPasswordLength*8; length *= 2 {
+		d := make([]byte, 0, length)
+
+		for idx := 0; idx < length; idx++ {
+			chars := all
+			if idx < len(classes) {
+				chars = classes[idx]
+			}
+
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+			if err != nil {
+				return "", err
+			}
+
+			d = append(d, chars[n.Int64()])
+		}
+
+		if err := shuffleBytes(d); err != nil {
+			return "", err
+		}
+
+		if policy.Check(string(d)) == nil {
+			return string(d), nil
+		}
+	}
+
+	return "", errors.New("the password policy can't be satisfied by the bootstrap password")
+}
+
+func shuffleBytes(d []byte) error {
+	for i := len(d) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+
+		j := n.Int64()
+		d[i], d[j] = d[j], d[i]
+	}
+
+	return nil
+}
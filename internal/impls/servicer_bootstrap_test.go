@@ -0,0 +1,168 @@
+package impls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
+	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/sbasestarter/userlib/policy/single"
+	"github.com/sgostarter/i/l"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestBootstrapServicerOwner(t *testing.T) {
+	ctx := context.Background()
+	model := NewMemUserPassModel()
+	manager := userpassmanager.NewManager("secret", model)
+	policy := NewPasswordPolicy(config.PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	})
+	admin := NewServicerUserAdmin("secret", policy, model)
+	logger := l.NewNopLoggerWrapper()
+
+	// nothing without the user name
+	assert.Nil(t, BootstrapServicerOwner(ctx, config.Bootstrap{}, model, manager, policy, logger))
+
+	_, total, err := model.QueryUsers(ctx, nil, "", 0, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, total)
+
+	assert.NotNil(t, BootstrapServicerOwner(ctx, config.Bootstrap{UserName: "owner"}, model, manager, policy, logger))
+	assert.NotNil(t, BootstrapServicerOwner(ctx, config.Bootstrap{
+		UserName: "owner",
+		Password: "weak",
+		ActIDs:   []string{"a1"},
+		BizIDs:   []string{"b1"},
+	}, model, manager, policy, logger))
+
+	cfg := config.Bootstrap{
+		UserName: "owner",
+		Password: "Owner#2024",
+		ActIDs:   []string{"a1"},
+		BizIDs:   []string{"b1"},
+	}
+	assert.Nil(t, BootstrapServicerOwner(ctx, cfg, model, manager, policy, logger))
+
+	user, err := admin.VerifyServicerUserPassword(ctx, "owner", "Owner#2024")
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ServicerRoles{defs.AllActs: defs.ServicerRoleOwner}, user.Roles)
+	assert.Equal(t, []string{"a1"}, user.ActIDs)
+	assert.Equal(t, []string{"b1"}, user.BizIDs)
+	assert.True(t, user.PasswordChangeRequired)
+
+	// only on the first run
+	cfg.UserName = "other"
+	assert.Nil(t, BootstrapServicerOwner(ctx, cfg, model, manager, policy, logger))
+
+	_, total, err = model.QueryUsers(ctx, nil, "", 0, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, total)
+
+	password, err := generateBootstrapPassword(NewPasswordPolicy(config.PasswordPolicy{
+		MinLength:     30,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}))
+	assert.Nil(t, err)
+	assert.True(t, len(password) >= 30)
+}
+
+func TestBootstrapServicerOwnerGeneratedPassword(t *testing.T) {
+	ctx := context.Background()
+	policy := NewPasswordPolicy(config.PasswordPolicy{MinLength: 8})
+	logger := l.NewNopLoggerWrapper()
+
+	bootstrap := func(passwordFile string) (defs.ServicerUserModel, userpassmanager.Manager, error) {
+		model := NewMemUserPassModel()
+		manager := userpassmanager.NewManager("secret", model)
+
+		err := BootstrapServicerOwner(ctx, config.Bootstrap{
+			UserName:     "owner",
+			PasswordFile: passwordFile,
+			ActIDs:       []string{"a1"},
+			BizIDs:       []string{"b1"},
+		}, model, manager, policy, logger)
+
+		return model, manager, err
+	}
+
+	// refused without a password file
+	model, _, err := bootstrap("")
+	assert.NotNil(t, err)
+
+	_, total, err := model.QueryUsers(ctx, nil, "", 0, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, total)
+
+	// only readable by the owner of the file, a stale file is replaced
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	assert.Nil(t, os.WriteFile(passwordFile, []byte("stale"), 0o644))
+
+	model, manager, err := bootstrap(passwordFile)
+	assert.Nil(t, err)
+
+	info, err := os.Stat(passwordFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	d, err := os.ReadFile(passwordFile)
+	assert.Nil(t, err)
+
+	password := strings.TrimSpace(string(d))
+	assert.Nil(t, policy.Check(password))
+
+	// the token is only for changing the password until it's changed
+	admin := NewServicerUserAdmin("secret", policy, model)
+	status := NewMemServicerStatusController()
+	user := userlib.NewUserCenter("tokenSecret", single.NewPolicy(userinters.AuthMethodNameUserPassword),
+		status, memoryauthingdatastorage.NewMemoryAuthingDataStorage(), nil)
+	tokenHelper := NewLocalServicerUserTokenHelper(user, manager, status)
+
+	login := func(password string) context.Context {
+		resp, errL := user.Login(ctx, &userinters.LoginRequest{
+			Authenticators:    []userinters.Authenticator{tokenHelper.NewAuthenticator("owner", password)},
+			TokenLiveDuration: time.Hour,
+		})
+		assert.Nil(t, errL)
+
+		_, errL = tokenHelper.StartSession(ctx, resp.Token, "test", "127.0.0.1")
+		assert.Nil(t, errL)
+
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(tokenKeyOnMetadata, resp.Token))
+	}
+
+	tokenCtx := login(password)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExtractUserFromGRPCContext(tokenCtx, false)
+	assert.ErrorIs(t, err, defs.ErrPasswordChangeRequired)
+
+	userID, userName, err := tokenHelper.ExtractUserForPasswordChange(tokenCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, "owner", userName)
+
+	assert.Nil(t, admin.ChangeServicerUserPassword(ctx, userID, password, "Changed#2024"))
+
+	servicerUser, err := admin.GetServicerUser(ctx, userID)
+	assert.Nil(t, err)
+	assert.False(t, servicerUser.PasswordChangeRequired)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExtractUserFromGRPCContext(login("Changed#2024"), false)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,115 @@
+package impls
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	defaultInvitationTTLSeconds = 7 * 24 * 3600
+	invitationCodeSize          = 16
+)
+
+// NewServicerInvitations keeps the hashes of the invitation codes only, a leaked model doesn't leak the codes.
+func NewServicerInvitations(cfg config.Registration, model defs.ServicerInvitationModel) defs.ServicerInvitations {
+	if cfg.InvitationTTLSeconds <= 0 {
+		cfg.InvitationTTLSeconds = defaultInvitationTTLSeconds
+	}
+
+	return &servicerInvitationsImpl{
+		cfg:   cfg,
+		model: model,
+		now:   time.Now,
+	}
+}
+
+type servicerInvitationsImpl struct {
+	cfg   config.Registration
+	model defs.ServicerInvitationModel
+	now   func() time.Time
+}
+
+func (impl *servicerInvitationsImpl) CreateInvitation(ctx context.Context, invitation *defs.ServicerInvitation,
+	ttl time.Duration) (code string, err error) {
+	if invitation == nil || (invitation.Role != "" && !invitation.Role.Valid()) {
+		err = commerr.ErrInvalidArgument
+
+		return
+	}
+
+	if invitation.Role == "" {
+		invitation.Role = defs.ServicerRoleAgent
+	}
+
+	if maxTTL := time.Duration(impl.cfg.InvitationTTLSeconds) * time.Second; ttl <= 0 || ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	d := make([]byte, invitationCodeSize)
+	if _, err = io.ReadFull(rand.Reader, d); err != nil {
+		return
+	}
+
+	code = hex.EncodeToString(d)
+	now := impl.now()
+
+	invitation.ID = invitationID(code)
+	invitation.CreateAt = now.Unix()
+	invitation.ExpireAt = now.Add(ttl).Unix()
+	invitation.UsedAt = 0
+	invitation.UsedBy = ""
+
+	if err = impl.model.AddInvitation(ctx, invitation); err != nil {
+		code = ""
+	}
+
+	return
+}
+
+func (impl *servicerInvitationsImpl) UseInvitation(ctx context.Context, code, userName string) (*defs.ServicerInvitation, error) {
+	if code == "" {
+		return nil, defs.ErrInvitationInvalid
+	}
+
+	invitation, err := impl.model.UseInvitation(ctx, invitationID(code), userName, impl.now().Unix())
+	if errors.Is(err, commerr.ErrNotFound) {
+		err = defs.ErrInvitationInvalid
+	}
+
+	return invitation, err
+}
+
+func (impl *servicerInvitationsImpl) ReleaseInvitation(ctx context.Context, invitation *defs.ServicerInvitation) error {
+	return impl.model.ReleaseInvitation(ctx, invitation.ID)
+}
+
+func (impl *servicerInvitationsImpl) GetInvitation(ctx context.Context, id string) (*defs.ServicerInvitation, error) {
+	return impl.model.GetInvitation(ctx, id)
+}
+
+func (impl *servicerInvitationsImpl) QueryInvitations(ctx context.Context, actIDs []string) ([]*defs.ServicerInvitation, error) {
+	return impl.model.QueryInvitations(ctx, actIDs)
+}
+
+func (impl *servicerInvitationsImpl) RevokeInvitation(ctx context.Context, id string) error {
+	return impl.model.DeleteInvitation(ctx, id)
+}
+
+func (impl *servicerInvitationsImpl) OpenRegistration() bool {
+	return !impl.cfg.DisableOpen
+}
+
+func invitationID(code string) string {
+	h := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,69 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestServicerInvitations(t *testing.T) {
+	ctx := context.Background()
+	invitations := NewServicerInvitations(config.Registration{
+		DisableOpen:          true,
+		InvitationTTLSeconds: 3600,
+	}, NewMemInvitationModel())
+	assert.False(t, invitations.OpenRegistration())
+
+	now := time.Unix(1000, 0)
+	invitations.(*servicerInvitationsImpl).now = func() time.Time {
+		return now
+	}
+
+	invitation := &defs.ServicerInvitation{
+		ActIDs: []string{"a1"},
+		BizIDs: []string{"b1"},
+		Role:   defs.ServicerRoleSupervisor,
+	}
+
+	code, err := invitations.CreateInvitation(ctx, invitation, time.Hour*24)
+	assert.Nil(t, err)
+	assert.NotEqual(t, code, invitation.ID)
+	assert.EqualValues(t, 1000+3600, invitation.ExpireAt)
+
+	_, err = invitations.UseInvitation(ctx, "unknown", "alice")
+	assert.ErrorIs(t, err, defs.ErrInvitationInvalid)
+
+	used, err := invitations.UseInvitation(ctx, code, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a1"}, used.ActIDs)
+	assert.Equal(t, defs.ServicerRoleSupervisor, used.Role)
+	assert.Equal(t, "alice", used.UsedBy)
+
+	_, err = invitations.UseInvitation(ctx, code, "bob")
+	assert.ErrorIs(t, err, defs.ErrInvitationInvalid)
+
+	// a failed registration releases the invitation
+	assert.Nil(t, invitations.ReleaseInvitation(ctx, used))
+
+	now = now.Add(time.Hour)
+
+	_, err = invitations.UseInvitation(ctx, code, "bob")
+	assert.ErrorIs(t, err, defs.ErrInvitationInvalid)
+
+	list, err := invitations.QueryInvitations(ctx, []string{"a2"})
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	list, err = invitations.QueryInvitations(ctx, []string{"a1", "a2"})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	assert.Nil(t, invitations.RevokeInvitation(ctx, invitation.ID))
+
+	_, err = invitations.GetInvitation(ctx, invitation.ID)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,72 @@
+package impls
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+// NewServicerLoginPolicy requires the user password, and then a TOTP code if the user enabled the two-factor
+// authentication. The users who must but didn't enroll it are rejected with defs.ErrTOTPEnrollmentRequired.
+func NewServicerLoginPolicy(twoFactor defs.TwoFactorAuth) userinters.Policy {
+	return &servicerLoginPolicyImpl{
+		twoFactor: twoFactor,
+	}
+}
+
+type servicerLoginPolicyImpl struct {
+	twoFactor defs.TwoFactorAuth
+}
+
+func (impl *servicerLoginPolicyImpl) RequireAuthMethod(ctx context.Context, d *userinters.AuthForUserPolicy) (
+	requiredOrMethods []string, err error) {
+	if d == nil {
+		err = commerr.ErrInvalidArgument
+
+		return
+	}
+
+	verified := func(methodName string) bool {
+		for _, method := range d.VerifiedMethods {
+			if method.MethodName == methodName {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !verified(userinters.AuthMethodNameUserPassword) {
+		requiredOrMethods = append(requiredOrMethods, userinters.AuthMethodNameUserPassword)
+
+		return
+	}
+
+	if verified(defs.AuthMethodNameTOTP) {
+		return
+	}
+
+	enabled, err := impl.twoFactor.Enabled(ctx, d.UserID)
+	if err != nil {
+		return
+	}
+
+	if enabled {
+		requiredOrMethods = append(requiredOrMethods, defs.AuthMethodNameTOTP)
+
+		return
+	}
+
+	required, err := impl.twoFactor.Required(ctx, d.UserID)
+	if err != nil {
+		return
+	}
+
+	if required {
+		err = defs.ErrTOTPEnrollmentRequired
+	}
+
+	return
+}
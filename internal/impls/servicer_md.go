@@ -2,6 +2,8 @@ package impls
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/sgostarter/i/l"
@@ -11,15 +13,19 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-func NewServicerMD(mdi defs.ServicerMDI, logger l.Wrapper) defs.ServicerMD {
+func NewServicerMD(mdi defs.ServicerMDI, participantM defs.TalkParticipantModel, slaTracker defs.SLATracker,
+	logger l.Wrapper) defs.ServicerMD {
 	if logger == nil {
 		logger = l.NewNopLoggerWrapper()
 	}
 
 	impl := &servicerMDImpl{
-		mdi:       mdi,
-		logger:    logger,
-		servicers: make(map[uint64]map[uint64]defs.Servicer),
+		mdi:          mdi,
+		participantM: participantM,
+		slaTracker:   slaTracker,
+		logger:       logger,
+		servicers:    make(map[uint64]map[uint64]defs.Servicer),
+		watchers:     make(map[string]map[uint64]struct{}),
 	}
 
 	mdi.SetServicerObserver(impl)
@@ -28,11 +34,28 @@ func NewServicerMD(mdi defs.ServicerMDI, logger l.Wrapper) defs.ServicerMD {
 }
 
 type servicerMDImpl struct {
-	mrRunner defs.MainRoutineRunner
-	mdi      defs.ServicerMDI
-	logger   l.Wrapper
+	mrRunner     defs.MainRoutineRunner
+	mdi          defs.ServicerMDI
+	participantM defs.TalkParticipantModel
+	slaTracker   defs.SLATracker
+	logger       l.Wrapper
+
+	slaCheckLock sync.Mutex
 
 	servicers map[uint64]map[uint64]defs.Servicer // servicerID - servicerN - servicer
+	watchers  map[string]map[uint64]struct{}      // talkID - servicerIDs
+}
+
+type participantsNotify struct {
+	TalkID      string   `json:"talkID"`
+	ServicerID  uint64   `json:"servicerID"`
+	Join        bool     `json:"join"`
+	ServicerIDs []uint64 `json:"servicerIDs"`
+}
+
+type whisperNotify struct {
+	TalkID string `json:"talkID"`
+	*defs.WhisperMessage
 }
 
 //
@@ -137,6 +160,10 @@ func (impl *servicerMDImpl) OnServicerAttachMessage(talkID string, servicerID ui
 		impl.send4AllOneServicer(servicerID, func(servicer defs.Servicer) error {
 			return servicer.SendMessage(resp)
 		})
+
+		impl.send4TalkWatchers(talkID, []uint64{servicerID}, func(servicer defs.Servicer) error {
+			return servicer.SendMessage(resp)
+		})
 	})
 }
 
@@ -163,6 +190,131 @@ func (impl *servicerMDImpl) OnServicerDetachMessage(talkID string, servicerID ui
 	})
 }
 
+func (impl *servicerMDImpl) OnServicerWatchMessage(talkID string, servicerID uint64, watch bool) {
+	impl.mrRunner.Post(func() {
+		if !watch {
+			if watchers, ok := impl.watchers[talkID]; ok {
+				delete(watchers, servicerID)
+
+				if len(watchers) == 0 {
+					delete(impl.watchers, talkID)
+				}
+			}
+
+			impl.releaseTrackTalk(context.TODO(), talkID)
+
+			return
+		}
+
+		if _, ok := impl.watchers[talkID]; !ok {
+			impl.watchers[talkID] = make(map[uint64]struct{})
+		}
+
+		impl.watchers[talkID][servicerID] = struct{}{}
+
+		if _, ok := impl.servicers[servicerID]; !ok {
+			return
+		}
+
+		_ = impl.mdi.AddTrackTalk(context.TODO(), talkID)
+
+		impl.sendTalkReload(context.TODO(), talkID, servicerID)
+	})
+}
+
+func (impl *servicerMDImpl) OnWhisperMessage(talkID string, whisper *defs.WhisperMessage) {
+	impl.mrRunner.Post(func() {
+		servicerID, err := impl.mdi.GetM().GetTalkServicerID(context.TODO(), nil, nil, talkID)
+		if err != nil {
+			impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkServicerIDFailed")
+
+			return
+		}
+
+		resp := vo.ServiceNotifyResponse(vo.NotifyKindWhisper, &whisperNotify{
+			TalkID:         talkID,
+			WhisperMessage: whisper,
+		})
+
+		if servicerID > 0 {
+			impl.send4AllOneServicer(servicerID, func(servicer defs.Servicer) error {
+				return servicer.SendMessage(resp)
+			})
+		}
+
+		if whisper.SenderID != servicerID {
+			impl.send4AllOneServicer(whisper.SenderID, func(servicer defs.Servicer) error {
+				return servicer.SendMessage(resp)
+			})
+		}
+	})
+}
+
+func (impl *servicerMDImpl) OnServicerParticipantMessage(talkID string, servicerID uint64, join bool) {
+	impl.mrRunner.Post(func() {
+		participants, err := impl.participantM.GetTalkParticipants(context.TODO(), talkID)
+		if err != nil {
+			impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkParticipantsFailed")
+
+			return
+		}
+
+		resp := vo.ServiceNotifyResponse(vo.NotifyKindParticipants, &participantsNotify{
+			TalkID:      talkID,
+			ServicerID:  servicerID,
+			Join:        join,
+			ServicerIDs: participants,
+		})
+
+		impl.sendResponseToServicersForTalk(0, talkID, resp)
+
+		if !join {
+			impl.send4AllOneServicer(servicerID, func(servicer defs.Servicer) error {
+				return servicer.SendMessage(resp)
+			})
+
+			impl.releaseTrackTalk(context.TODO(), talkID)
+
+			return
+		}
+
+		if _, ok := impl.servicers[servicerID]; !ok {
+			return
+		}
+
+		_ = impl.mdi.AddTrackTalk(context.TODO(), talkID)
+
+		impl.sendTalkReload(context.TODO(), talkID, servicerID)
+	})
+}
+
+func (impl *servicerMDImpl) OnMessageRevision(talkID string, revision *defs.MessageRevision) {
+	impl.mrRunner.Post(func() {
+		impl.sendResponseToServicersForTalk(0, talkID, vo.ServiceNotifyResponse(vo.NotifyKindRevision,
+			vo.ServicerMessageRevision(revision)))
+	})
+}
+
+func (impl *servicerMDImpl) OnServicerKick(servicerID uint64, sessionID, reason string) {
+	impl.mrRunner.Post(func() {
+		for _, servicer := range impl.servicers[servicerID] {
+			if sessionID == "" || servicer.GetSessionID() == sessionID {
+				servicer.Remove(reason)
+			}
+		}
+	})
+}
+
+func (impl *servicerMDImpl) OnSLABreach(breach *defs.SLABreach) {
+	impl.mrRunner.Post(func() {
+		resp := vo.ServiceNotifyResponse(vo.NotifyKindSLABreach, breach)
+
+		impl.send4AllServicers(breach.ActID, breach.BizID, func(servicer defs.Servicer) error {
+			return servicer.SendMessage(resp)
+		})
+	})
+}
+
 //
 // defs.ServicerMD
 //
@@ -185,6 +337,18 @@ func (impl *servicerMDImpl) InstallServicer(ctx context.Context, servicer defs.S
 
 	_ = impl.sendPendingTalks(ctx, servicer)
 
+	for talkID, watchers := range impl.watchers {
+		if _, ok := watchers[servicer.GetUserID()]; ok {
+			_ = impl.mdi.AddTrackTalk(ctx, talkID)
+		}
+	}
+
+	for _, talkID := range impl.getParticipantTalkIDs(ctx, servicer.GetUserID()) {
+		_ = impl.mdi.AddTrackTalk(ctx, talkID)
+
+		impl.ServicerReloadTalk(ctx, servicer, talkID)
+	}
+
 	if _, ok := impl.servicers[servicer.GetUserID()]; !ok {
 		impl.servicers[servicer.GetUserID()] = make(map[uint64]defs.Servicer)
 	}
@@ -209,6 +373,24 @@ func (impl *servicerMDImpl) UninstallServicer(ctx context.Context, servicer defs
 		for _, info := range talkInfos {
 			impl.mdi.RemoveTrackTalk(context.TODO(), info.TalkID)
 		}
+
+		for talkID, watchers := range impl.watchers {
+			if _, ok = watchers[servicer.GetUserID()]; !ok {
+				continue
+			}
+
+			delete(watchers, servicer.GetUserID())
+
+			if len(watchers) == 0 {
+				delete(impl.watchers, talkID)
+			}
+
+			impl.releaseTrackTalk(ctx, talkID)
+		}
+
+		for _, talkID := range impl.getParticipantTalkIDs(ctx, servicer.GetUserID()) {
+			impl.releaseTrackTalk(ctx, talkID)
+		}
 	}
 }
 
@@ -233,16 +415,59 @@ func (impl *servicerMDImpl) ServicerAttachTalk(ctx context.Context, talkID strin
 
 			return
 		}
+
+		// taking over the talk of another servicer is a transfer
+		if !impl.canTakeOver(ctx, talkID, servicer) {
+			if err = servicer.SendMessage(&talkpb.ServiceResponse{
+				Response: &talkpb.ServiceResponse_Notify{
+					Notify: &talkpb.ServiceTalkNotifyResponse{
+						Msg: "talkAttachedByOthers",
+					},
+				},
+			}); err != nil {
+				impl.logger.WithFields(l.ErrorField(err)).Error("SendMessageFailed")
+			}
+
+			return
+		}
 	}
 
 	err = impl.mdi.GetM().UpdateTalkServiceID(ctx, servicer.GetActIDs(), servicer.GetBizIDs(), talkID, servicer.GetUserID())
 	if err != nil {
 		impl.logger.WithFields(l.ErrorField(err)).Error("UpdateTalkServiceID")
+	} else {
+		system := &defs.SystemMessage{
+			Event:        defs.SystemEventAttach,
+			ServicerID:   servicer.GetUserID(),
+			ServicerName: servicer.GetUserName(),
+		}
+
+		if servicerID > 0 {
+			system.Event = defs.SystemEventTransfer
+			system.FromServicerID = servicerID
+		}
+
+		addSystemMessage(ctx, impl.mdi, talkID, system, impl.logger)
+
+		if impl.slaTracker != nil {
+			impl.slaTracker.TalkAttached(ctx, talkID, servicer.GetUserID(), time.Now().Unix())
+		}
 	}
 
 	impl.mdi.SendServicerAttachMessage(talkID, servicer.GetUserID())
 }
 
+func (impl *servicerMDImpl) canTakeOver(ctx context.Context, talkID string, servicer defs.Servicer) bool {
+	talkInfo, err := impl.mdi.GetM().GetTalkInfo(ctx, servicer.GetActIDs(), servicer.GetBizIDs(), talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkInfoFailed")
+
+		return false
+	}
+
+	return servicer.GetRoles().Can(talkInfo.ActID, defs.PermissionTransfer)
+}
+
 func (impl *servicerMDImpl) ServicerDetachTalk(ctx context.Context, talkID string, servicer defs.Servicer) {
 	if talkID == "" || servicer == nil {
 		impl.logger.WithFields(l.StringField("talkID", talkID)).Error("noServicerOrTalkID")
@@ -275,6 +500,12 @@ func (impl *servicerMDImpl) ServicerDetachTalk(ctx context.Context, talkID strin
 
 	if err = impl.mdi.GetM().UpdateTalkServiceID(ctx, servicer.GetActIDs(), servicer.GetBizIDs(), talkID, 0); err != nil {
 		impl.logger.WithFields(l.ErrorField(err)).Error("UpdateTalkServiceID")
+	} else {
+		addSystemMessage(ctx, impl.mdi, talkID, &defs.SystemMessage{
+			Event:        defs.SystemEventDetach,
+			ServicerID:   servicer.GetUserID(),
+			ServicerName: servicer.GetUserName(),
+		}, impl.logger)
 	}
 
 	impl.mdi.SendServiceDetachMessage(talkID, servicer.GetUserID())
@@ -306,7 +537,7 @@ func (impl *servicerMDImpl) ServicerReloadTalk(ctx context.Context, servicer def
 }
 
 func (impl *servicerMDImpl) ServiceMessage(ctx context.Context, servicer defs.Servicer, talkID string,
-	seqID uint64, message *talkinters.TalkMessageW) {
+	seqID uint64, messageID string, message *talkinters.TalkMessageW) {
 	if servicer == nil || talkID == "" || message == nil {
 		impl.logger.Error("nilParameters")
 
@@ -320,7 +551,7 @@ func (impl *servicerMDImpl) ServiceMessage(ctx context.Context, servicer defs.Se
 		return
 	}
 
-	if servicerID != servicer.GetUserID() {
+	if servicerID != servicer.GetUserID() && !impl.isTalkParticipant(ctx, talkID, servicer.GetUserID()) {
 		impl.logger.WithFields(l.StringField("talkID", talkID), l.UInt64Field("curServicerID", servicer.GetUserID()),
 			l.UInt64Field("talkServicerID", servicerID)).Error("invalidServicerID")
 
@@ -342,11 +573,131 @@ func (impl *servicerMDImpl) ServiceMessage(ctx context.Context, servicer defs.Se
 		servicer.Remove("SendMessageFailed")
 
 		delete(servicersMap, servicer.GetUniqueID())
+	} else {
+		if messageID != "" {
+			_ = servicer.SendMessage(vo.ServiceNotifyResponse(vo.NotifyKindConfirmed, &vo.MessageConfirmed{
+				SeqID:     seqID,
+				At:        message.At,
+				MessageID: messageID,
+			}))
+		}
+
+		if impl.slaTracker != nil {
+			impl.slaTracker.ServicerResponded(ctx, talkID, message.At)
+		}
 	}
 
 	impl.mdi.SendMessage(servicer.GetUniqueID(), talkID, message)
 }
 
+func (impl *servicerMDImpl) ServicerWatchTalk(_ context.Context, talkID string, servicerID uint64, watch bool) {
+	if talkID == "" || servicerID == 0 {
+		impl.logger.WithFields(l.StringField("talkID", talkID)).Error("noServicerOrTalkID")
+
+		return
+	}
+
+	impl.mdi.SendServicerWatchMessage(talkID, servicerID, watch)
+}
+
+func (impl *servicerMDImpl) ServicerWhisper(_ context.Context, talkID string, whisper *defs.WhisperMessage) {
+	if talkID == "" || whisper == nil {
+		impl.logger.Error("nilParameters")
+
+		return
+	}
+
+	if whisper.At == 0 {
+		whisper.At = time.Now().Unix()
+	}
+
+	impl.mdi.SendWhisperMessage(talkID, whisper)
+}
+
+func (impl *servicerMDImpl) ServicerTalkParticipant(_ context.Context, talkID string, servicerID uint64, join bool) {
+	if talkID == "" || servicerID == 0 {
+		impl.logger.WithFields(l.StringField("talkID", talkID)).Error("noServicerOrTalkID")
+
+		return
+	}
+
+	impl.mdi.SendServicerParticipantMessage(talkID, servicerID, join)
+}
+
+// ServicerCloseTalk closes the talk for a servicer, the permission is checked by the caller.
+func (impl *servicerMDImpl) ServicerCloseTalk(ctx context.Context, talkID string, servicerID uint64, servicerName string) {
+	if talkID == "" || servicerID == 0 {
+		impl.logger.WithFields(l.StringField("talkID", talkID)).Error("noServicerOrTalkID")
+
+		return
+	}
+
+	if err := impl.mdi.GetM().CloseTalk(ctx, nil, nil, talkID); err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("CloseTalkFailed")
+
+		return
+	}
+
+	addSystemMessage(ctx, impl.mdi, talkID, &defs.SystemMessage{
+		Event:        defs.SystemEventClose,
+		ServicerID:   servicerID,
+		ServicerName: servicerName,
+		ClosedBy:     vo.ClosedByServicer,
+	}, impl.logger)
+
+	if impl.slaTracker != nil {
+		impl.slaTracker.TalkClosed(ctx, talkID, time.Now().Unix())
+	}
+
+	impl.mdi.SendTalkCloseMessage(talkID)
+}
+
+func (impl *servicerMDImpl) CustomerBanned(_ context.Context, ban *defs.CustomerBan) {
+	if ban == nil {
+		impl.logger.Error("nilParameters")
+
+		return
+	}
+
+	impl.mdi.SendCustomerBanMessage(ban)
+}
+
+func (impl *servicerMDImpl) KickServicer(_ context.Context, servicerID uint64, sessionID, reason string) {
+	if servicerID == 0 {
+		impl.logger.Error("noServicerID")
+
+		return
+	}
+
+	impl.mdi.SendServicerKickMessage(servicerID, sessionID, reason)
+}
+
+func (impl *servicerMDImpl) MessageRevised(_ context.Context, talkID string, revision *defs.MessageRevision) {
+	if talkID == "" || revision == nil {
+		impl.logger.Error("nilParameters")
+
+		return
+	}
+
+	impl.mdi.SendMessageRevision(talkID, revision)
+}
+
+// CheckSLA checks the breaches off the main routine, a check is skipped if the last one isn't done.
+func (impl *servicerMDImpl) CheckSLA(ctx context.Context) {
+	if impl.slaTracker == nil || !impl.slaCheckLock.TryLock() {
+		return
+	}
+
+	go func() {
+		defer impl.slaCheckLock.Unlock()
+
+		// every instance checks, the breaches are marked once, so they're sent to the servicers of all the instances
+		for _, breach := range impl.slaTracker.CheckBreaches(ctx, time.Now().Unix()) {
+			impl.mdi.SendSLABreachMessage(breach)
+		}
+	}()
+}
+
 //
 //
 //
@@ -436,29 +787,28 @@ func (impl *servicerMDImpl) sendResponseToServicersForTalk(excludedUniqueID uint
 		return
 	}
 
-	if servicerID == 0 {
-		return
+	participants, err := impl.participantM.GetTalkParticipants(context.TODO(), talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkParticipantsFailed")
 	}
 
-	servicersMap := impl.servicers[servicerID]
+	if servicerID > 0 && !slices.Contains(participants, servicerID) {
+		participants = append(participants, servicerID)
+	}
 
-	for _, servicer := range servicersMap {
+	do := func(servicer defs.Servicer) error {
 		if servicer.GetUniqueID() == excludedUniqueID {
-			continue
+			return nil
 		}
 
-		if err = servicer.SendMessage(resp); err != nil {
-			impl.logger.WithFields(l.ErrorField(err)).Error("SendMessageFailed")
-
-			servicer.Remove("SendMessageFailed")
-
-			delete(servicersMap, servicer.GetUniqueID())
-		}
+		return servicer.SendMessage(resp)
 	}
 
-	if len(servicersMap) == 0 {
-		delete(impl.servicers, servicerID)
+	for _, participantID := range participants {
+		impl.send4AllOneServicer(participantID, do)
 	}
+
+	impl.send4TalkWatchers(talkID, participants, do)
 }
 
 func (impl *servicerMDImpl) send4AllServicers(actID, bizID string, do func(defs.Servicer) error) {
@@ -503,3 +853,96 @@ func (impl *servicerMDImpl) send4AllOneServicer(servicerID uint64, do func(defs.
 		delete(impl.servicers, servicerID)
 	}
 }
+
+// send4TalkWatchers sends to the watchers of the talk, the servicers already served are skipped.
+func (impl *servicerMDImpl) send4TalkWatchers(talkID string, excludedServicerIDs []uint64, do func(defs.Servicer) error) {
+	for watcherID := range impl.watchers[talkID] {
+		if slices.Contains(excludedServicerIDs, watcherID) {
+			continue
+		}
+
+		impl.send4AllOneServicer(watcherID, do)
+	}
+}
+
+// releaseTrackTalk stops tracking the talk when no local servicer attaches, joins or watches it.
+func (impl *servicerMDImpl) releaseTrackTalk(ctx context.Context, talkID string) {
+	for watcherID := range impl.watchers[talkID] {
+		if _, ok := impl.servicers[watcherID]; ok {
+			return
+		}
+	}
+
+	participants, _ := impl.participantM.GetTalkParticipants(ctx, talkID)
+	for _, participantID := range participants {
+		if _, ok := impl.servicers[participantID]; ok {
+			return
+		}
+	}
+
+	servicerID, err := impl.mdi.GetM().GetTalkServicerID(ctx, nil, nil, talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkServicerIDFailed")
+
+		return
+	}
+
+	if _, ok := impl.servicers[servicerID]; ok {
+		return
+	}
+
+	impl.mdi.RemoveTrackTalk(ctx, talkID)
+}
+
+func (impl *servicerMDImpl) sendTalkReload(ctx context.Context, talkID string, servicerID uint64) {
+	talkWithMessages, err := impl.getTalkInfoWithMessages(ctx, talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("getTalkInfoWithMessagesFailed")
+
+		return
+	}
+
+	resp := &talkpb.ServiceResponse{
+		Response: &talkpb.ServiceResponse_Reload{
+			Reload: &talkpb.ServiceTalkReloadResponse{
+				Talk: talkWithMessages,
+			},
+		},
+	}
+
+	impl.send4AllOneServicer(servicerID, func(servicer defs.Servicer) error {
+		return servicer.SendMessage(resp)
+	})
+}
+
+func (impl *servicerMDImpl) isTalkParticipant(ctx context.Context, talkID string, servicerID uint64) bool {
+	participants, err := impl.participantM.GetTalkParticipants(ctx, talkID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetTalkParticipantsFailed")
+
+		return false
+	}
+
+	return slices.Contains(participants, servicerID)
+}
+
+// getParticipantTalkIDs returns the opened talks the servicer joined.
+func (impl *servicerMDImpl) getParticipantTalkIDs(ctx context.Context, servicerID uint64) (talkIDs []string) {
+	allTalkIDs, err := impl.participantM.GetServicerParticipantTalkIDs(ctx, servicerID)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("GetServicerParticipantTalkIDsFailed")
+
+		return
+	}
+
+	for _, talkID := range allTalkIDs {
+		talkInfo, errT := impl.mdi.GetM().GetTalkInfo(ctx, nil, nil, talkID)
+		if errT != nil || talkInfo.Status != talkinters.TalkStatusOpened {
+			continue
+		}
+
+		talkIDs = append(talkIDs, talkID)
+	}
+
+	return
+}
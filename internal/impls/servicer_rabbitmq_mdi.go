@@ -73,6 +73,19 @@ func (impl *servicerRabbitMQImpl) SendMessage(senderUniqueID uint64, talkID stri
 	_ = impl.rabbitMQ.SendData(d)
 }
 
+func (impl *servicerRabbitMQImpl) SendMessageRevision(talkID string, revision *defs.MessageRevision) {
+	d := &mqData{
+		TalkID:          talkID,
+		MessageRevision: revision,
+	}
+
+	if args.RabbitMQUseSharedChannel {
+		d.ChannelID = specialTalkAll
+	}
+
+	_ = impl.rabbitMQ.SendData(d)
+}
+
 func (impl *servicerRabbitMQImpl) SetServicerObserver(ob defs.ServicerObserver) {
 	impl.rabbitMQ.SetServicerObserver(ob)
 }
@@ -80,7 +93,7 @@ func (impl *servicerRabbitMQImpl) SetServicerObserver(ob defs.ServicerObserver)
 func (impl *servicerRabbitMQImpl) SendServicerAttachMessage(talkID string, servicerID uint64) {
 	_ = impl.rabbitMQ.SendData(&mqData{
 		TalkID:    talkID,
-		ChannelID: specialTalkServicer,
+		ChannelID: specialTalkAll,
 		ServicerAttach: &mqDataServicerAttach{
 			ServicerID: servicerID,
 		},
@@ -96,3 +109,66 @@ func (impl *servicerRabbitMQImpl) SendServiceDetachMessage(talkID string, servic
 		},
 	})
 }
+
+func (impl *servicerRabbitMQImpl) SendServicerWatchMessage(talkID string, servicerID uint64, watch bool) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		TalkID:    talkID,
+		ChannelID: specialTalkServicer,
+		ServicerWatch: &mqDataServicerWatch{
+			ServicerID: servicerID,
+			Watch:      watch,
+		},
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendWhisperMessage(talkID string, whisper *defs.WhisperMessage) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		TalkID:    talkID,
+		ChannelID: specialTalkServicer,
+		Whisper:   whisper,
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendServicerParticipantMessage(talkID string, servicerID uint64, join bool) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		TalkID:    talkID,
+		ChannelID: specialTalkServicer,
+		ServicerParticipant: &mqDataServicerParticipant{
+			ServicerID: servicerID,
+			Join:       join,
+		},
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendCustomerBanMessage(ban *defs.CustomerBan) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		ChannelID:   specialTalkCustomer,
+		CustomerBan: ban,
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendServicerKickMessage(servicerID uint64, sessionID, reason string) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		ChannelID: specialTalkServicer,
+		ServicerKick: &mqDataServicerKick{
+			ServicerID: servicerID,
+			SessionID:  sessionID,
+			Reason:     reason,
+		},
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendTalkCloseMessage(talkID string) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		TalkID:    talkID,
+		ChannelID: specialTalkAll,
+		TalkClose: &mqDataTalkClose{},
+	})
+}
+
+func (impl *servicerRabbitMQImpl) SendSLABreachMessage(breach *defs.SLABreach) {
+	_ = impl.rabbitMQ.SendData(&mqData{
+		ChannelID: specialTalkServicer,
+		SLABreach: breach,
+	})
+}
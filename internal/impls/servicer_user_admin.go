@@ -0,0 +1,214 @@
+package impls
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+
+	"github.com/sbasestarter/bizinters/userinters/userpass"
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/libeasygo/crypt"
+	"github.com/spf13/cast"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	dKeyDisabled        = "disabled"
+	dKeyPasswordHistory = "passwordHistory"
+	dKeyTokenVersion    = "tokenVersion"
+
+	dKeyPasswordChangeRequired = "passwordChangeRequired"
+)
+
+// NewServicerUserAdmin manages the servicer users, the password secret must be the same as the one of the
+// user password manager.
+func NewServicerUserAdmin(passwordSecret string, policy defs.PasswordPolicy, model defs.ServicerUserModel) defs.ServicerUserAdmin {
+	return &servicerUserAdminImpl{
+		passwordSecret: passwordSecret,
+		policy:         policy,
+		model:          model,
+	}
+}
+
+type servicerUserAdminImpl struct {
+	passwordSecret string
+	policy         defs.PasswordPolicy
+	model          defs.ServicerUserModel
+}
+
+func (impl *servicerUserAdminImpl) QueryServicerUsers(ctx context.Context, actIDs []string, keyword string, offset,
+	limit int64) (users []*defs.ServicerUser, total int64, err error) {
+	us, total, err := impl.model.QueryUsers(ctx, actIDs, keyword, offset, limit)
+	if err != nil {
+		return
+	}
+
+	users = make([]*defs.ServicerUser, 0, len(us))
+
+	for _, u := range us {
+		users = append(users, servicerUserFromModel(u))
+	}
+
+	return
+}
+
+func (impl *servicerUserAdminImpl) GetServicerUser(ctx context.Context, userID uint64) (*defs.ServicerUser, error) {
+	u, err := impl.model.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return servicerUserFromModel(u), nil
+}
+
+func (impl *servicerUserAdminImpl) SetServicerUserDisabled(ctx context.Context, userID uint64, disabled bool) error {
+	return impl.model.UpdateUserExData(ctx, userID, dKeyDisabled, disabled)
+}
+
+func (impl *servicerUserAdminImpl) DeleteServicerUser(ctx context.Context, userID uint64) error {
+	return impl.model.DeleteUser(ctx, userID)
+}
+
+func (impl *servicerUserAdminImpl) SetServicerUserRole(ctx context.Context, userID uint64, actID string,
+	role defs.ServicerRole) error {
+	if actID == "" || (role != "" && !role.Valid()) {
+		return commerr.ErrInvalidArgument
+	}
+
+	u, err := impl.model.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// the role of the legacy permission flag is kept as an entry
+	roles := servicerRoles(u.ExData)
+
+	if role == "" {
+		delete(roles, actID)
+	} else {
+		roles[actID] = role
+	}
+
+	return impl.model.UpdateUserExData(ctx, userID, dKeyExDataRoles, rolesExData(roles))
+}
+
+func (impl *servicerUserAdminImpl) ResetServicerUserPassword(ctx context.Context, userID uint64, password string) error {
+	u, err := impl.model.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return impl.updatePassword(ctx, u, password)
+}
+
+func (impl *servicerUserAdminImpl) ChangeServicerUserPassword(ctx context.Context, userID uint64, oldPassword,
+	newPassword string) error {
+	u, err := impl.model.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ePassword, err := crypt.HMacSHa256(impl.passwordSecret, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(ePassword), []byte(u.Password)) {
+		return defs.ErrWrongPassword
+	}
+
+	if err = impl.updatePassword(ctx, u, newPassword); err != nil {
+		return err
+	}
+
+	if !cast.ToBool(u.ExData[dKeyPasswordChangeRequired]) {
+		return nil
+	}
+
+	return impl.model.UpdateUserExData(ctx, u.ID, dKeyPasswordChangeRequired, false)
+}
+
+func (impl *servicerUserAdminImpl) VerifyServicerUserPassword(ctx context.Context, userName,
+	password string) (*defs.ServicerUser, error) {
+	u, err := impl.model.GetUserByUserName(ctx, userName)
+	if err != nil {
+		if errors.Is(err, commerr.ErrNotFound) {
+			err = defs.ErrWrongPassword
+		}
+
+		return nil, err
+	}
+
+	ePassword, err := crypt.HMacSHa256(impl.passwordSecret, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(ePassword), []byte(u.Password)) {
+		return nil, defs.ErrWrongPassword
+	}
+
+	user := servicerUserFromModel(u)
+	if user.Disabled {
+		return nil, commerr.ErrPermissionDenied
+	}
+
+	return user, nil
+}
+
+// updatePassword keeps the hashes of the replaced passwords for the reuse check, and bumps the token version
+// to invalidate the tokens of the user.
+func (impl *servicerUserAdminImpl) updatePassword(ctx context.Context, u *userpass.User, password string) error {
+	if err := impl.policy.Check(password); err != nil {
+		return err
+	}
+
+	ePassword, err := crypt.HMacSHa256(impl.passwordSecret, password)
+	if err != nil {
+		return err
+	}
+
+	var history []string
+
+	if historySize := impl.policy.HistorySize(); historySize > 0 {
+		history = append([]string{u.Password}, parseIDs(u.ExData[dKeyPasswordHistory])...)
+		if len(history) > historySize {
+			history = history[:historySize]
+		}
+
+		if slices.Contains(history, ePassword) {
+			return defs.ErrPasswordReused
+		}
+
+		// the new password is the current one, the last N-1 replaced ones are kept
+		if len(history) == historySize {
+			history = history[:historySize-1]
+		}
+	}
+
+	if err = impl.model.UpdateUserPassword(ctx, u.ID, ePassword); err != nil {
+		return err
+	}
+
+	if err = impl.model.UpdateUserExData(ctx, u.ID, dKeyPasswordHistory, history); err != nil {
+		return err
+	}
+
+	return impl.model.UpdateUserExData(ctx, u.ID, dKeyTokenVersion, cast.ToInt64(u.ExData[dKeyTokenVersion])+1)
+}
+
+func servicerUserFromModel(u *userpass.User) *defs.ServicerUser {
+	return &defs.ServicerUser{
+		ID:          u.ID,
+		UserName:    u.UserName,
+		CreateAt:    u.CreateAt,
+		Roles:       servicerRoles(u.ExData),
+		Disabled:    cast.ToBool(u.ExData[dKeyDisabled]),
+		TOTPEnabled: totpEnabled(u),
+		ActIDs:      parseIDs(u.ExData[dKeyExDataActIDs]),
+		BizIDs:      parseIDs(u.ExData[dKeyExDataBizIDs]),
+
+		PasswordChangeRequired: cast.ToBool(u.ExData[dKeyPasswordChangeRequired]),
+	}
+}
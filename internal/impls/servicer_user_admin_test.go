@@ -0,0 +1,197 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
+	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/sbasestarter/userlib/policy/single"
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestServicerUserAdmin(t *testing.T) {
+	ctx := context.Background()
+	model := NewMemUserPassModel()
+	manager := userpassmanager.NewManager("secret", model)
+	admin := NewServicerUserAdmin("secret", NewPasswordPolicy(config.PasswordPolicy{
+		MinLength:    6,
+		RequireDigit: true,
+		HistorySize:  2,
+	}), model)
+
+	register := func(userName string, actIDs ...string) uint64 {
+		userID, err := manager.Register(ctx, userName, "123456")
+		assert.Nil(t, err)
+		assert.Nil(t, manager.UpdateUserAllExData(ctx, userID, map[string]interface{}{
+			dKeyExDataActIDs: actIDs,
+		}))
+
+		return userID
+	}
+
+	u1 := register("alice", "a1")
+	register("bob", "a1", "a2")
+	register("carol", "a2")
+	register("Alan")
+
+	users, total, err := admin.QueryServicerUsers(ctx, nil, "", 0, 0)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, total)
+	assert.Len(t, users, 4)
+
+	users, total, err = admin.QueryServicerUsers(ctx, []string{"a1"}, "", 1, 10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "bob", users[0].UserName)
+
+	users, total, err = admin.QueryServicerUsers(ctx, nil, "al", 0, 10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Equal(t, "alice", users[0].UserName)
+
+	assert.Nil(t, admin.SetServicerUserDisabled(ctx, u1, true))
+
+	user, err := admin.GetServicerUser(ctx, u1)
+	assert.Nil(t, err)
+	assert.True(t, user.Disabled)
+	assert.Equal(t, []string{"a1"}, user.ActIDs)
+
+	assert.Nil(t, admin.ResetServicerUserPassword(ctx, u1, "654321"))
+
+	_, ok, err := manager.Verify(ctx, "alice", "123456")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	userID, ok, err := manager.Verify(ctx, "alice", "654321")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, u1, userID)
+
+	assert.ErrorIs(t, admin.ChangeServicerUserPassword(ctx, u1, "123456", "abc123"), defs.ErrWrongPassword)
+	assert.ErrorIs(t, admin.ChangeServicerUserPassword(ctx, u1, "654321", "abcdef"), defs.ErrPasswordNeedsDigit)
+	assert.ErrorIs(t, admin.ChangeServicerUserPassword(ctx, u1, "654321", "654321"), defs.ErrPasswordReused)
+	assert.Nil(t, admin.ChangeServicerUserPassword(ctx, u1, "654321", "abc123"))
+	assert.ErrorIs(t, admin.ChangeServicerUserPassword(ctx, u1, "abc123", "654321"), commerr.ErrInvalidArgument)
+	assert.Nil(t, admin.ChangeServicerUserPassword(ctx, u1, "abc123", "123456"))
+
+	// the legacy permission flag is the role in all acts until the roles are set
+	assert.Equal(t, defs.ServicerRoles{defs.AllActs: defs.ServicerRoleAgent}, user.Roles)
+	assert.Nil(t, manager.UpdateUserExData(ctx, u1, dKeyPermission, 1))
+	assert.Nil(t, admin.SetServicerUserRole(ctx, u1, "a1", defs.ServicerRoleSupervisor))
+	assert.ErrorIs(t, admin.SetServicerUserRole(ctx, u1, "a1", "root"), commerr.ErrInvalidArgument)
+
+	user, err = admin.GetServicerUser(ctx, u1)
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ServicerRoles{
+		defs.AllActs: defs.ServicerRoleAdmin,
+		"a1":         defs.ServicerRoleSupervisor,
+	}, user.Roles)
+
+	assert.Nil(t, admin.SetServicerUserRole(ctx, u1, defs.AllActs, ""))
+
+	user, err = admin.GetServicerUser(ctx, u1)
+	assert.Nil(t, err)
+	assert.Equal(t, defs.ServicerRoles{"a1": defs.ServicerRoleSupervisor}, user.Roles)
+
+	assert.Nil(t, admin.DeleteServicerUser(ctx, u1))
+
+	_, err = admin.GetServicerUser(ctx, u1)
+	assert.NotNil(t, err)
+}
+
+func TestServicerTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	model := NewMemUserPassModel()
+	manager := userpassmanager.NewManager("secret", model)
+	admin := NewServicerUserAdmin("secret", NewPasswordPolicy(config.PasswordPolicy{}), model)
+	status := NewMemServicerStatusController()
+	user := userlib.NewUserCenter("tokenSecret", single.NewPolicy(userinters.AuthMethodNameUserPassword),
+		status, memoryauthingdatastorage.NewMemoryAuthingDataStorage(), nil)
+	tokenHelper := NewLocalServicerUserTokenHelper(user, manager, status)
+
+	login := func(password string) string {
+		resp, err := user.Login(ctx, &userinters.LoginRequest{
+			Authenticators:    []userinters.Authenticator{tokenHelper.NewAuthenticator("alice", password)},
+			TokenLiveDuration: time.Hour,
+		})
+		assert.Nil(t, err)
+
+		_, err = tokenHelper.StartSession(ctx, resp.Token, "test", "127.0.0.1")
+		assert.Nil(t, err)
+
+		return resp.Token
+	}
+
+	userID, err := manager.Register(ctx, "alice", "123456")
+	assert.Nil(t, err)
+
+	token := login("123456")
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, token, false)
+	assert.Nil(t, err)
+
+	assert.Nil(t, admin.ChangeServicerUserPassword(ctx, userID, "123456", "654321"))
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, token, false)
+	assert.ErrorIs(t, err, commerr.ErrUnauthenticated)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, login("654321"), false)
+	assert.Nil(t, err)
+
+	assert.Nil(t, admin.SetServicerUserDisabled(ctx, userID, true))
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, login("654321"), false)
+	assert.ErrorIs(t, err, commerr.ErrPermissionDenied)
+
+	assert.Nil(t, admin.SetServicerUserDisabled(ctx, userID, false))
+
+	// the renewed tokens keep the session, the revoked session invalidates them all
+	token = login("654321")
+
+	newToken, _, _, _, _, _, err := tokenHelper.ExplainToken(ctx, token, true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, newToken)
+
+	// the sessions of the old password are revoked by the servers, not by the user admin
+	sessions, err := tokenHelper.QuerySessions(ctx, userID)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 4)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, newToken, false)
+	assert.Nil(t, err)
+
+	session, err := tokenHelper.ExtractSessionFromGRPCContext(metadata.NewIncomingContext(ctx,
+		metadata.Pairs(tokenKeyOnMetadata, newToken)))
+	assert.Nil(t, err)
+	assert.Equal(t, "test", session.Device)
+
+	assert.Nil(t, tokenHelper.RevokeSession(ctx, session.ID))
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, token, false)
+	assert.ErrorIs(t, err, commerr.ErrUnauthenticated)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, newToken, false)
+	assert.ErrorIs(t, err, commerr.ErrUnauthenticated)
+
+	assert.Nil(t, tokenHelper.RevokeUserSessions(ctx, userID))
+
+	sessions, err = tokenHelper.QuerySessions(ctx, userID)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 0)
+}
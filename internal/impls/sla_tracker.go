@@ -0,0 +1,227 @@
+package impls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	webhookTimeout   = time.Second * 5
+	slaCheckPageSize = 500
+)
+
+func NewSLATracker(m defs.TalkSLAModel, cfg config.SLA, logger l.Wrapper) defs.SLATracker {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	return &slaTrackerImpl{
+		m:      m,
+		cfg:    cfg,
+		logger: logger.WithFields(l.StringField(l.ClsKey, "slaTrackerImpl")),
+		httpCli: &http.Client{
+			Timeout: webhookTimeout,
+		},
+		responded: make(map[string]struct{}),
+	}
+}
+
+type slaTrackerImpl struct {
+	m       defs.TalkSLAModel
+	cfg     config.SLA
+	logger  l.Wrapper
+	httpCli *http.Client
+
+	respondedLock sync.Mutex
+	responded     map[string]struct{} // talkIDs
+}
+
+func (impl *slaTrackerImpl) TalkCreated(ctx context.Context, talkInfo *talkinters.TalkInfoR) {
+	if talkInfo == nil {
+		return
+	}
+
+	if err := impl.m.UpdateTalkSLA(ctx, &defs.TalkSLA{
+		TalkID:  talkInfo.TalkID,
+		ActID:   talkInfo.ActID,
+		BizID:   talkInfo.BizID,
+		StartAt: talkInfo.StartAt,
+	}); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkInfo.TalkID)).Error("UpdateTalkSLAFailed")
+	}
+}
+
+func (impl *slaTrackerImpl) TalkAttached(ctx context.Context, talkID string, servicerID uint64, at int64) {
+	// the handle time starts from the first attaching, the transfers don't restart it
+	if err := impl.m.SetTalkSLAServicer(ctx, talkID, servicerID, at); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("SetTalkSLAServicerFailed")
+	}
+}
+
+// ServicerResponded records the first response once, the talks responded are kept to skip the later messages.
+func (impl *slaTrackerImpl) ServicerResponded(ctx context.Context, talkID string, at int64) {
+	impl.respondedLock.Lock()
+	_, responded := impl.responded[talkID]
+	impl.respondedLock.Unlock()
+
+	if responded {
+		return
+	}
+
+	if _, err := impl.m.SetTalkSLAFirstResponse(ctx, talkID, at); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("SetTalkSLAFirstResponseFailed")
+
+		return
+	}
+
+	impl.respondedLock.Lock()
+	impl.responded[talkID] = struct{}{}
+	impl.respondedLock.Unlock()
+}
+
+func (impl *slaTrackerImpl) CustomerRated(ctx context.Context, talkID string, score int) {
+	if err := impl.m.SetTalkSLARating(ctx, talkID, score); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("SetTalkSLARatingFailed")
+	}
+}
+
+func (impl *slaTrackerImpl) TalkClosed(ctx context.Context, talkID string, at int64) {
+	impl.respondedLock.Lock()
+	delete(impl.responded, talkID)
+	impl.respondedLock.Unlock()
+
+	if _, err := impl.m.SetTalkSLAClosed(ctx, talkID, at); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("SetTalkSLAClosedFailed")
+	}
+}
+
+// CheckBreaches loads the open records by pages.
+func (impl *slaTrackerImpl) CheckBreaches(ctx context.Context, now int64) (breaches []*defs.SLABreach) {
+	var afterTalkID string
+
+	for {
+		slas, err := impl.m.QueryOpenTalkSLAs(ctx, afterTalkID, slaCheckPageSize)
+		if err != nil {
+			impl.logger.WithFields(l.ErrorField(err)).Error("QueryOpenTalkSLAsFailed")
+
+			break
+		}
+
+		breaches = impl.appendBreaches(ctx, breaches, slas, now)
+
+		if len(slas) < slaCheckPageSize {
+			break
+		}
+
+		afterTalkID = slas[len(slas)-1].TalkID
+	}
+
+	if len(breaches) > 0 && len(impl.cfg.WebhookURLs) > 0 {
+		go impl.notifyWebhooks(breaches)
+	}
+
+	return
+}
+
+//
+//
+//
+
+func (impl *slaTrackerImpl) appendBreaches(ctx context.Context, breaches []*defs.SLABreach, slas []*defs.TalkSLA,
+	now int64) []*defs.SLABreach {
+	for _, sla := range slas {
+		threshold := impl.cfg.GetThreshold(sla.BizID)
+
+		if !sla.FirstResponseBreached && sla.FirstResponseAt == 0 && threshold.FirstResponseSeconds > 0 &&
+			now-sla.StartAt > threshold.FirstResponseSeconds {
+			breaches = impl.appendMarkedBreach(ctx, breaches, &defs.SLABreach{
+				TalkID:           sla.TalkID,
+				ActID:            sla.ActID,
+				BizID:            sla.BizID,
+				Metric:           defs.SLAMetricFirstResponse,
+				ThresholdSeconds: threshold.FirstResponseSeconds,
+				ElapsedSeconds:   now - sla.StartAt,
+			})
+		}
+
+		if !sla.HandleBreached && sla.AttachedAt > 0 && threshold.HandleSeconds > 0 &&
+			now-sla.AttachedAt > threshold.HandleSeconds {
+			breaches = impl.appendMarkedBreach(ctx, breaches, &defs.SLABreach{
+				TalkID:           sla.TalkID,
+				ActID:            sla.ActID,
+				BizID:            sla.BizID,
+				Metric:           defs.SLAMetricHandle,
+				ThresholdSeconds: threshold.HandleSeconds,
+				ElapsedSeconds:   now - sla.AttachedAt,
+			})
+		}
+	}
+
+	return breaches
+}
+
+// appendMarkedBreach appends the breach if this instance marks it, the others marked it already otherwise.
+func (impl *slaTrackerImpl) appendMarkedBreach(ctx context.Context, breaches []*defs.SLABreach,
+	breach *defs.SLABreach) []*defs.SLABreach {
+	marked, err := impl.m.MarkTalkSLABreached(ctx, breach.TalkID, breach.Metric)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("talkID", breach.TalkID)).Error("MarkTalkSLABreachedFailed")
+
+		return breaches
+	}
+
+	if !marked {
+		return breaches
+	}
+
+	return append(breaches, breach)
+}
+
+func (impl *slaTrackerImpl) notifyWebhooks(breaches []*defs.SLABreach) {
+	d, err := json.Marshal(breaches)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err)).Error("MarshalBreachesFailed")
+
+		return
+	}
+
+	for _, url := range impl.cfg.WebhookURLs {
+		impl.postWebhook(url, d)
+	}
+}
+
+func (impl *slaTrackerImpl) postWebhook(url string, d []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d))
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("url", url)).Error("NewRequestFailed")
+
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := impl.httpCli.Do(req)
+	if err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("url", url)).Error("PostWebhookFailed")
+
+		return
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		impl.logger.WithFields(l.IntField("status", resp.StatusCode), l.StringField("url", url)).Warn("WebhookStatusNotOK")
+	}
+}
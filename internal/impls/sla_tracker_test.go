@@ -0,0 +1,150 @@
+package impls
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestSLATracker(t *testing.T) {
+	m := NewMemSLAModel()
+	tracker := NewSLATracker(m, config.SLA{
+		Default: config.SLAThreshold{
+			FirstResponseSeconds: 60,
+			HandleSeconds:        600,
+		},
+		BizIDs: map[string]config.SLAThreshold{
+			"fastBiz": {
+				FirstResponseSeconds: 10,
+			},
+		},
+	}, nil)
+
+	ctx := context.Background()
+
+	tracker.TalkCreated(ctx, &talkinters.TalkInfoR{
+		TalkID: "t1",
+		TalkInfoW: talkinters.TalkInfoW{
+			StartAt: 1000,
+			ActID:   "act",
+			BizID:   "biz",
+		},
+	})
+	tracker.TalkCreated(ctx, &talkinters.TalkInfoR{
+		TalkID: "t2",
+		TalkInfoW: talkinters.TalkInfoW{
+			StartAt: 1000,
+			ActID:   "act",
+			BizID:   "fastBiz",
+		},
+	})
+
+	breaches := tracker.CheckBreaches(ctx, 1030)
+	assert.Len(t, breaches, 1)
+	assert.Equal(t, "t2", breaches[0].TalkID)
+	assert.Equal(t, defs.SLAMetricFirstResponse, breaches[0].Metric)
+
+	assert.Empty(t, tracker.CheckBreaches(ctx, 1031))
+
+	tracker.TalkAttached(ctx, "t1", 100, 1020)
+	tracker.ServicerResponded(ctx, "t1", 1040)
+	tracker.ServicerResponded(ctx, "t1", 1050)
+	tracker.TalkAttached(ctx, "t1", 101, 1060)
+	tracker.CustomerRated(ctx, "t1", 4)
+
+	breaches = tracker.CheckBreaches(ctx, 1700)
+	assert.Len(t, breaches, 1)
+	assert.Equal(t, defs.SLAMetricHandle, breaches[0].Metric)
+
+	tracker.TalkClosed(ctx, "t1", 1720)
+
+	sla, err := m.GetTalkSLA(ctx, "t1")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 40, sla.FirstResponseSeconds)
+	assert.EqualValues(t, 700, sla.HandleSeconds)
+	assert.EqualValues(t, 101, sla.ServicerID)
+	assert.EqualValues(t, 4, sla.Rating)
+	assert.True(t, sla.HandleBreached)
+	assert.False(t, sla.FirstResponseBreached)
+}
+
+func TestSLATrackerBreachedOnce(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemSLAModel()
+	cfg := config.SLA{
+		Default: config.SLAThreshold{
+			FirstResponseSeconds: 60,
+		},
+	}
+
+	// the trackers of two instances
+	tracker1, tracker2 := NewSLATracker(m, cfg, nil), NewSLATracker(m, cfg, nil)
+
+	tracker1.TalkCreated(ctx, &talkinters.TalkInfoR{
+		TalkID: "t1",
+		TalkInfoW: talkinters.TalkInfoW{
+			StartAt: 1000,
+			ActID:   "act",
+			BizID:   "biz",
+		},
+	})
+
+	// a stale copy read before the breach
+	stale, err := m.GetTalkSLA(ctx, "t1")
+	assert.Nil(t, err)
+
+	assert.Len(t, tracker1.CheckBreaches(ctx, 1100), 1)
+	assert.Empty(t, tracker2.CheckBreaches(ctx, 1100))
+
+	// the updates keep the breach flags
+	stale.AttachedAt = 1050
+	assert.Nil(t, m.UpdateTalkSLA(ctx, stale))
+	assert.Empty(t, tracker2.CheckBreaches(ctx, 1200))
+
+	sla, err := m.GetTalkSLA(ctx, "t1")
+	assert.Nil(t, err)
+	assert.True(t, sla.FirstResponseBreached)
+	assert.EqualValues(t, 1050, sla.AttachedAt)
+
+	marked, err := m.MarkTalkSLABreached(ctx, "t1", defs.SLAMetricFirstResponse)
+	assert.Nil(t, err)
+	assert.False(t, marked)
+
+	_, err = m.MarkTalkSLABreached(ctx, "t2", defs.SLAMetricFirstResponse)
+	assert.NotNil(t, err)
+}
+
+func TestSLATrackerCheckBreachesPaged(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemSLAModel()
+	tracker := NewSLATracker(m, config.SLA{
+		Default: config.SLAThreshold{
+			FirstResponseSeconds: 60,
+		},
+	}, nil)
+
+	const count = slaCheckPageSize*2 + 1
+
+	for idx := 0; idx < count; idx++ {
+		tracker.TalkCreated(ctx, &talkinters.TalkInfoR{
+			TalkID: fmt.Sprintf("t%04d", idx),
+			TalkInfoW: talkinters.TalkInfoW{
+				StartAt: 1000,
+				ActID:   "act",
+				BizID:   "biz",
+			},
+		})
+	}
+
+	// the responded and closed talks are skipped
+	tracker.ServicerResponded(ctx, "t0000", 1010)
+	tracker.TalkClosed(ctx, "t0001", 1020)
+
+	assert.Len(t, tracker.CheckBreaches(ctx, 1100), count-2)
+	assert.Empty(t, tracker.CheckBreaches(ctx, 1100))
+}
@@ -0,0 +1,28 @@
+package impls
+
+import (
+	"context"
+	"time"
+
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+// addSystemMessage persists a talk event into the message history and sends it to all connections of the talk.
+func addSystemMessage(ctx context.Context, mdi defs.MDIBase, talkID string, system *defs.SystemMessage, logger l.Wrapper) {
+	message, err := vo.NewSystemMessage(time.Now().Unix(), system)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("NewSystemMessageFailed")
+
+		return
+	}
+
+	if err = mdi.GetM().AddTalkMessage(ctx, talkID, message); err != nil {
+		logger.WithFields(l.ErrorField(err), l.StringField("talkID", talkID)).Error("AddSystemMessageFailed")
+
+		return
+	}
+
+	mdi.SendMessage(0, talkID, message)
+}
@@ -0,0 +1,434 @@
+package impls
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/bizinters/userinters/userpass"
+	"github.com/sgostarter/i/commerr"
+	"github.com/spf13/cast"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	dKeyTOTPSecret               = "totpSecret"
+	dKeyTOTPRecoveryCodes        = "totpRecoveryCodes"
+	dKeyTOTPPendingSecret        = "totpPendingSecret"
+	dKeyTOTPPendingRecoveryCodes = "totpPendingRecoveryCodes"
+	dKeyTOTPLastStep             = "totpLastStep"
+
+	defaultTOTPIssuer = "talk"
+
+	totpSecretSize        = 20
+	totpStepSeconds       = 30
+	totpDigits            = 6
+	totpSkewSteps         = 1
+	totpRecoveryCodeCount = 10
+	totpRecoveryCodeSize  = 5
+)
+
+var totpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTwoFactorAuth keeps the TOTP data in the ex data of the servicer users, the secrets are encrypted and the
+// recovery codes are hashed by the keys derived from the password secret.
+func NewTwoFactorAuth(cfg config.TOTP, passwordSecret string, userM defs.ServicerUserModel,
+	policyM defs.TwoFactorPolicyModel) (defs.TwoFactorAuth, error) {
+	if userM == nil || policyM == nil {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultTOTPIssuer
+	}
+
+	encryptKey := sha256.Sum256([]byte("totp-encrypt:" + passwordSecret))
+
+	block, err := aes.NewCipher(encryptKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	signKey := sha256.Sum256([]byte("totp-sign:" + passwordSecret))
+
+	return &twoFactorAuthImpl{
+		cfg:     cfg,
+		userM:   userM,
+		policyM: policyM,
+		aead:    aead,
+		signKey: signKey[:],
+		now:     time.Now,
+	}, nil
+}
+
+type twoFactorAuthImpl struct {
+	cfg     config.TOTP
+	userM   defs.ServicerUserModel
+	policyM defs.TwoFactorPolicyModel
+	aead    cipher.AEAD
+	signKey []byte
+	now     func() time.Time
+}
+
+func (impl *twoFactorAuthImpl) Enroll(ctx context.Context, userID uint64, code string) (*defs.TOTPEnrollment, error) {
+	u, err := impl.userM.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totpEnabled(u) {
+		if err = impl.verifyCode(ctx, u, code); err != nil {
+			return nil, err
+		}
+	}
+
+	secret := make([]byte, totpSecretSize)
+	if _, err = io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := impl.encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, 0, totpRecoveryCodeCount)
+	recoveryCodeHashes := make([]string, 0, totpRecoveryCodeCount)
+
+	for idx := 0; idx < totpRecoveryCodeCount; idx++ {
+		d := make([]byte, totpRecoveryCodeSize)
+		if _, err = io.ReadFull(rand.Reader, d); err != nil {
+			return nil, err
+		}
+
+		recoveryCode := hex.EncodeToString(d)
+		recoveryCode = recoveryCode[:totpRecoveryCodeSize] + "-" + recoveryCode[totpRecoveryCodeSize:]
+
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, impl.hashRecoveryCode(recoveryCode))
+	}
+
+	if err = impl.userM.UpdateUserExData(ctx, userID, dKeyTOTPPendingSecret, encryptedSecret); err != nil {
+		return nil, err
+	}
+
+	if err = impl.userM.UpdateUserExData(ctx, userID, dKeyTOTPPendingRecoveryCodes, recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+
+	return &defs.TOTPEnrollment{
+		URI:           impl.provisioningURI(u.UserName, secret),
+		Secret:        totpBase32.EncodeToString(secret),
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (impl *twoFactorAuthImpl) Activate(ctx context.Context, userID uint64, code string) error {
+	u, err := impl.userM.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	pendingSecret := cast.ToString(u.ExData[dKeyTOTPPendingSecret])
+	if pendingSecret == "" {
+		return defs.ErrTOTPNotEnrolled
+	}
+
+	secret, err := impl.decrypt(pendingSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := impl.matchTOTP(secret, code, 0)
+	if !ok {
+		return defs.ErrWrongTOTPCode
+	}
+
+	return impl.updateExData(ctx, userID, map[string]interface{}{
+		dKeyTOTPSecret:               pendingSecret,
+		dKeyTOTPRecoveryCodes:        parseIDs(u.ExData[dKeyTOTPPendingRecoveryCodes]),
+		dKeyTOTPPendingSecret:        "",
+		dKeyTOTPPendingRecoveryCodes: nil,
+		dKeyTOTPLastStep:             step,
+	})
+}
+
+func (impl *twoFactorAuthImpl) Disable(ctx context.Context, userID uint64, code string) error {
+	u, err := impl.userM.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !totpEnabled(u) {
+		return defs.ErrTOTPNotEnrolled
+	}
+
+	required, err := impl.required(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return defs.ErrTOTPEnrollmentRequired
+	}
+
+	if err = impl.verifyCode(ctx, u, code); err != nil {
+		return err
+	}
+
+	return impl.Reset(ctx, userID)
+}
+
+func (impl *twoFactorAuthImpl) Reset(ctx context.Context, userID uint64) error {
+	return impl.updateExData(ctx, userID, map[string]interface{}{
+		dKeyTOTPSecret:               "",
+		dKeyTOTPRecoveryCodes:        nil,
+		dKeyTOTPPendingSecret:        "",
+		dKeyTOTPPendingRecoveryCodes: nil,
+		dKeyTOTPLastStep:             0,
+	})
+}
+
+func (impl *twoFactorAuthImpl) Enabled(ctx context.Context, userID uint64) (bool, error) {
+	u, err := impl.userM.GetUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return totpEnabled(u), nil
+}
+
+func (impl *twoFactorAuthImpl) Required(ctx context.Context, userID uint64) (bool, error) {
+	u, err := impl.userM.GetUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return impl.required(ctx, u)
+}
+
+func (impl *twoFactorAuthImpl) NewAuthenticator(userName, code string) userinters.Authenticator {
+	return &totpAuthenticatorImpl{
+		userName:  userName,
+		code:      code,
+		twoFactor: impl,
+	}
+}
+
+func (impl *twoFactorAuthImpl) SignContinueID(userName string, continueID uint64) string {
+	s := strconv.FormatUint(continueID, 10)
+
+	return s + "." + impl.sign(userName, s)
+}
+
+func (impl *twoFactorAuthImpl) VerifyContinueID(userName, signedContinueID string) (uint64, error) {
+	s, signature, ok := strings.Cut(signedContinueID, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(impl.sign(userName, s))) {
+		return 0, commerr.ErrUnauthenticated
+	}
+
+	return strconv.ParseUint(s, 10, 64)
+}
+
+//
+//
+//
+
+func totpEnabled(u *userpass.User) bool {
+	return cast.ToString(u.ExData[dKeyTOTPSecret]) != ""
+}
+
+func (impl *twoFactorAuthImpl) required(ctx context.Context, u *userpass.User) (bool, error) {
+	actIDs := parseIDs(u.ExData[dKeyExDataActIDs])
+	if len(actIDs) == 0 {
+		return false, nil
+	}
+
+	return impl.policyM.IsTwoFactorRequired(ctx, actIDs)
+}
+
+// verifyCode accepts a TOTP code newer than the last accepted one, or an unused recovery code.
+func (impl *twoFactorAuthImpl) verifyCode(ctx context.Context, u *userpass.User, code string) error {
+	if code == "" {
+		return defs.ErrWrongTOTPCode
+	}
+
+	secret, err := impl.decrypt(cast.ToString(u.ExData[dKeyTOTPSecret]))
+	if err != nil {
+		return err
+	}
+
+	if step, ok := impl.matchTOTP(secret, code, cast.ToInt64(u.ExData[dKeyTOTPLastStep])); ok {
+		return impl.userM.UpdateUserExData(ctx, u.ID, dKeyTOTPLastStep, step)
+	}
+
+	recoveryCodeHashes := parseIDs(u.ExData[dKeyTOTPRecoveryCodes])
+
+	idx := slices.Index(recoveryCodeHashes, impl.hashRecoveryCode(code))
+	if idx < 0 {
+		return defs.ErrWrongTOTPCode
+	}
+
+	return impl.userM.UpdateUserExData(ctx, u.ID, dKeyTOTPRecoveryCodes,
+		append(append([]string{}, recoveryCodeHashes[:idx]...), recoveryCodeHashes[idx+1:]...))
+}
+
+// matchTOTP returns the time step of the code, the steps not after the last step are replays.
+func (impl *twoFactorAuthImpl) matchTOTP(secret []byte, code string, lastStep int64) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := impl.now().Unix() / totpStepSeconds
+
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		if hmac.Equal([]byte(totpCode(secret, step)), []byte(code)) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode is the HOTP value of the time step, RFC 4226 and RFC 6238.
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	_, _ = mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	var modulo uint32 = 1
+
+	for idx := 0; idx < totpDigits; idx++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+func (impl *twoFactorAuthImpl) provisioningURI(userName string, secret []byte) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + impl.cfg.Issuer + ":" + userName,
+		RawQuery: url.Values{
+			"secret":    []string{totpBase32.EncodeToString(secret)},
+			"issuer":    []string{impl.cfg.Issuer},
+			"algorithm": []string{"SHA1"},
+			"digits":    []string{strconv.Itoa(totpDigits)},
+			"period":    []string{strconv.Itoa(totpStepSeconds)},
+		}.Encode(),
+	}
+
+	return u.String()
+}
+
+func (impl *twoFactorAuthImpl) hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+
+	mac := hmac.New(sha256.New, impl.signKey)
+	_, _ = mac.Write([]byte("recovery:" + code))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (impl *twoFactorAuthImpl) sign(userName, s string) string {
+	mac := hmac.New(sha256.New, impl.signKey)
+	_, _ = mac.Write([]byte("continue:" + userName + "\n" + s))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (impl *twoFactorAuthImpl) encrypt(secret []byte) (string, error) {
+	nonce := make([]byte, impl.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(impl.aead.Seal(nonce, nonce, secret, nil)), nil
+}
+
+func (impl *twoFactorAuthImpl) decrypt(s string) ([]byte, error) {
+	d, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d) < impl.aead.NonceSize() {
+		return nil, commerr.ErrBadFormat
+	}
+
+	return impl.aead.Open(nil, d[:impl.aead.NonceSize()], d[impl.aead.NonceSize():], nil)
+}
+
+func (impl *twoFactorAuthImpl) updateExData(ctx context.Context, userID uint64, exData map[string]interface{}) error {
+	for key, val := range exData {
+		if err := impl.userM.UpdateUserExData(ctx, userID, key, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+//
+//
+
+type totpAuthenticatorImpl struct {
+	userName  string
+	code      string
+	twoFactor *twoFactorAuthImpl
+}
+
+func (impl *totpAuthenticatorImpl) GetMethodName() string {
+	return defs.AuthMethodNameTOTP
+}
+
+func (impl *totpAuthenticatorImpl) Verify(ctx context.Context) (uid uint64, tokenData []byte, ok bool, err error) {
+	u, err := impl.twoFactor.userM.GetUserByUserName(ctx, impl.userName)
+	if err != nil || !totpEnabled(u) {
+		return
+	}
+
+	if err = impl.twoFactor.verifyCode(ctx, u, impl.code); err != nil {
+		return
+	}
+
+	uid = u.ID
+	ok = true
+
+	return
+}
@@ -0,0 +1,138 @@
+package impls
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
+	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 test vectors for SHA1, the last 6 digits
+	secret := []byte("12345678901234567890")
+
+	assert.Equal(t, "287082", totpCode(secret, 59/totpStepSeconds))
+	assert.Equal(t, "081804", totpCode(secret, 1111111109/totpStepSeconds))
+	assert.Equal(t, "050471", totpCode(secret, 1111111111/totpStepSeconds))
+	assert.Equal(t, "005924", totpCode(secret, 1234567890/totpStepSeconds))
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	model := NewMemUserPassModel()
+	manager := userpassmanager.NewManager("secret", model)
+	policyM := NewMemTwoFactorPolicyModel()
+
+	twoFactor, err := NewTwoFactorAuth(config.TOTP{}, "secret", model, policyM)
+	assert.Nil(t, err)
+
+	now := time.Unix(1700000000, 0)
+	twoFactor.(*twoFactorAuthImpl).now = func() time.Time {
+		return now
+	}
+
+	status := NewMemServicerStatusController()
+	user := userlib.NewUserCenter("tokenSecret", NewServicerLoginPolicy(twoFactor), status,
+		memoryauthingdatastorage.NewMemoryAuthingDataStorage(), nil)
+	tokenHelper := NewLocalServicerUserTokenHelper(user, manager, status)
+
+	userID, err := manager.Register(ctx, "alice", "123456")
+	assert.Nil(t, err)
+	assert.Nil(t, manager.UpdateUserExData(ctx, userID, dKeyExDataActIDs, []string{"a1"}))
+
+	login := func(continueID uint64, authenticators ...userinters.Authenticator) (*userinters.LoginResponse, error) {
+		return user.Login(ctx, &userinters.LoginRequest{
+			ContinueID:        continueID,
+			Authenticators:    authenticators,
+			TokenLiveDuration: time.Hour,
+		})
+	}
+
+	resp, err := login(0, tokenHelper.NewAuthenticator("alice", "123456"))
+	assert.Nil(t, err)
+	assert.Equal(t, userinters.LoginStatusSuccess, resp.Status)
+
+	enrollment, err := twoFactor.Enroll(ctx, userID, "")
+	assert.Nil(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/talk:alice?")
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.Len(t, enrollment.RecoveryCodes, totpRecoveryCodeCount)
+
+	secret, err := totpBase32.DecodeString(enrollment.Secret)
+	assert.Nil(t, err)
+
+	code := func() string {
+		return totpCode(secret, now.Unix()/totpStepSeconds)
+	}
+
+	assert.ErrorIs(t, twoFactor.Activate(ctx, userID, "000000"), defs.ErrWrongTOTPCode)
+	assert.Nil(t, twoFactor.Activate(ctx, userID, code()))
+
+	enabled, err := twoFactor.Enabled(ctx, userID)
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+
+	// the password is verified, the login continues with a TOTP code
+	resp, err = login(0, tokenHelper.NewAuthenticator("alice", "123456"))
+	assert.Nil(t, err)
+	assert.Equal(t, userinters.LoginStatusNeedMoreAuthenticator, resp.Status)
+	assert.Equal(t, []string{defs.AuthMethodNameTOTP}, resp.RequiredOrMethods)
+
+	signedContinueID := twoFactor.SignContinueID("alice", resp.ContinueID)
+
+	_, err = twoFactor.VerifyContinueID("bob", signedContinueID)
+	assert.NotNil(t, err)
+
+	continueID, err := twoFactor.VerifyContinueID("alice", signedContinueID)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.ContinueID, continueID)
+
+	// the code of the activation is used
+	_, err = login(continueID, twoFactor.NewAuthenticator("alice", code()))
+	assert.NotNil(t, err)
+
+	now = now.Add(time.Second * totpStepSeconds)
+
+	resp, err = login(continueID, twoFactor.NewAuthenticator("alice", code()))
+	assert.Nil(t, err)
+	assert.Equal(t, userinters.LoginStatusSuccess, resp.Status)
+
+	_, err = tokenHelper.StartSession(ctx, resp.Token, "test", "127.0.0.1")
+	assert.Nil(t, err)
+
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, resp.Token, false)
+	assert.Nil(t, err)
+
+	// a recovery code is used once
+	resp, err = login(0, tokenHelper.NewAuthenticator("alice", "123456"),
+		twoFactor.NewAuthenticator("alice", enrollment.RecoveryCodes[0]))
+	assert.Nil(t, err)
+	assert.Equal(t, userinters.LoginStatusSuccess, resp.Status)
+
+	_, err = login(0, tokenHelper.NewAuthenticator("alice", "123456"),
+		twoFactor.NewAuthenticator("alice", enrollment.RecoveryCodes[0]))
+	assert.NotNil(t, err)
+
+	// the required two-factor authentication can't be disabled, and the users without it can't log in
+	assert.Nil(t, policyM.SetTwoFactorRequired(ctx, "a1", true))
+	assert.ErrorIs(t, twoFactor.Disable(ctx, userID, enrollment.RecoveryCodes[1]), defs.ErrTOTPEnrollmentRequired)
+
+	assert.Nil(t, twoFactor.Reset(ctx, userID))
+
+	_, err = login(0, tokenHelper.NewAuthenticator("alice", "123456"))
+	assert.ErrorIs(t, err, defs.ErrTOTPEnrollmentRequired)
+
+	assert.Nil(t, policyM.SetTwoFactorRequired(ctx, "a1", false))
+
+	resp, err = login(0, tokenHelper.NewAuthenticator("alice", "123456"))
+	assert.Nil(t, err)
+	assert.Equal(t, userinters.LoginStatusSuccess, resp.Status)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"google.golang.org/grpc/codes"
+)
+
+// checkCustomerBan returns a gRPC error if the customer is banned in the act.
+func checkCustomerBan(ctx context.Context, banM defs.CustomerBanModel, userID uint64, userName, actID string) error {
+	_, err := banM.FindActiveCustomerBan(ctx, userID, userName, actID, time.Now().Unix())
+	if errors.Is(err, commerr.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return gRPCError(codes.Internal, err)
+	}
+
+	return gRPCMessageError(codes.PermissionDenied, vo.KickOutReasonBanned)
+}
@@ -16,12 +16,15 @@ import (
 )
 
 func NewCustomerServer(controller *controller.CustomerController, userTokenHelper defs.CustomerUserTokenHelper, model defs.ModelEx,
-	logger l.Wrapper) talkpb.CustomerTalkServiceServer {
+	imageProcessor defs.ImageProcessor, messageReviser defs.MessageReviser,
+	redactor defs.Redactor, moderator defs.Moderator, flagM defs.ModerationFlagModel,
+	rateLimiter defs.RateLimiter, banM defs.CustomerBanModel, logger l.Wrapper) talkpb.CustomerTalkServiceServer {
 	if logger == nil {
 		logger = l.NewNopLoggerWrapper()
 	}
 
-	if controller == nil || userTokenHelper == nil || model == nil {
+	if controller == nil || userTokenHelper == nil || model == nil || imageProcessor == nil || messageReviser == nil || redactor == nil ||
+		moderator == nil || flagM == nil || rateLimiter == nil || banM == nil {
 		logger.Fatal("invalid input args")
 	}
 
@@ -30,6 +33,13 @@ func NewCustomerServer(controller *controller.CustomerController, userTokenHelpe
 		controller:      controller,
 		userTokenHelper: userTokenHelper,
 		model:           model,
+		imageProcessor:  imageProcessor,
+		messageReviser:  messageReviser,
+		redactor:        redactor,
+		moderator:       moderator,
+		flagM:           flagM,
+		rateLimiter:     rateLimiter,
+		banM:            banM,
 	}
 }
 
@@ -39,6 +49,13 @@ type customerServerImpl struct {
 	logger          l.Wrapper
 	userTokenHelper defs.CustomerUserTokenHelper
 	model           defs.ModelEx
+	imageProcessor  defs.ImageProcessor
+	messageReviser  defs.MessageReviser
+	redactor        defs.Redactor
+	moderator       defs.Moderator
+	flagM           defs.ModerationFlagModel
+	rateLimiter     defs.RateLimiter
+	banM            defs.CustomerBanModel
 
 	controller *controller.CustomerController
 }
@@ -82,6 +99,12 @@ func (impl *customerServerImpl) Talk(server talkpb.CustomerTalkService_TalkServe
 		return gRPCError(codes.Unauthenticated, nil)
 	}
 
+	if err = checkCustomerBan(server.Context(), impl.banM, userID, userName, actID); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.UInt64Field("userID", userID)).Error("CheckCustomerBanFailed")
+
+		return err
+	}
+
 	uniqueID := snowflake.ID()
 
 	logger := impl.logger.WithFields(l.StringField(l.RoutineKey, "Talk"),
@@ -113,7 +136,7 @@ func (impl *customerServerImpl) Talk(server talkpb.CustomerTalkService_TalkServe
 
 	chSendMessage := make(chan *talkpb.TalkResponse, 100)
 
-	customer := controller.NewCustomer(actID, bizID, uniqueID, talkID, createTalkFlag, userID, chSendMessage)
+	customer := controller.NewCustomer(actID, bizID, uniqueID, talkID, createTalkFlag, userID, userName, chSendMessage)
 
 	err = impl.controller.InstallCustomer(customer)
 	if err != nil {
@@ -228,21 +251,114 @@ func (impl *customerServerImpl) customerReceiveRoutine(server talkpb.CustomerTal
 			break
 		}
 
-		if message := request.GetMessage(); message != nil {
+		if request.GetMessage() != nil {
+			decision := impl.rateLimiter.Take(server.Context(), true, userID, customer.GetTalkID())
+			if decision == defs.RateLimitKick {
+				logger.Warn("RateLimitKickOut")
+
+				customer.Remove(vo.RejectReasonRateLimited)
+
+				break
+			}
+
+			if decision == defs.RateLimitReject {
+				_ = customer.SendMessage(vo.TalkNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+					SeqID:  request.GetMessage().GetSeqId(),
+					Reason: vo.RejectReasonRateLimited,
+				}))
+
+				continue
+			}
+		}
+
+		if command, ok := vo.MessageCommandFromPb(request.GetMessage()); ok {
+			if command.Command != defs.MessageCommandRate {
+				impl.reviseMessage(server.Context(), customer, userID, userName, command, logger)
+
+				continue
+			}
+
+			if err = impl.rateTalk(server.Context(), customer, userID, userName, request.GetMessage().GetSeqId(),
+				command, logger); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("CustomerMessageIncomingFailed")
+
+				break
+			}
+		} else if message := request.GetMessage(); message != nil {
 			dbMessage := vo.TalkMessageWPb2Db(message)
 			dbMessage.At = time.Now().Unix()
 			dbMessage.CustomerMessage = true
 			dbMessage.SenderID = userID
 			dbMessage.SenderUserName = userName
 
-			err = impl.model.AddTalkMessage(server.Context(), customer.GetTalkID(), dbMessage)
+			if err = vo.ValidateTalkMessage(customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ValidateTalkMessageFailed")
+				rejectCustomerMessage(customer, message.SeqId, "", err)
+
+				continue
+			}
+
+			if err = impl.imageProcessor.Process(server.Context(), customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ProcessImageFailed")
+				rejectCustomerMessage(customer, message.SeqId, "", err)
+
+				continue
+			}
+
+			if err = checkReplyMessage(server.Context(), impl.model, customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("CheckReplyMessageFailed")
+				rejectCustomerMessage(customer, message.SeqId, "", err)
+
+				continue
+			}
+
+			if err = checkQuoteMessage(server.Context(), impl.model, customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("CheckQuoteMessageFailed")
+				rejectCustomerMessage(customer, message.SeqId, "", err)
+
+				continue
+			}
+
+			var moderation *defs.ModerationResult
+
+			if moderation, err = moderateMessage(server.Context(), impl.moderator, impl.redactor, customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ModerateMessageFailed")
+
+				continue
+			}
+
+			if moderation.Action == defs.ModerationActionReject {
+				_ = customer.SendMessage(vo.TalkNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+					SeqID:  message.SeqId,
+					Reason: moderation.Reason,
+				}))
+
+				continue
+			}
+
+			if err = impl.redactor.Redact(server.Context(), customer.GetTalkID(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("RedactFailed")
+
+				continue
+			}
+
+			var messageID string
+
+			messageID, err = impl.model.AddTalkMessageWithID(server.Context(), customer.GetTalkID(), dbMessage)
 			if err != nil {
 				logger.WithFields(l.ErrorField(err)).Error("AddTalkMessageFailed")
 
 				continue
 			}
 
-			err = impl.controller.CustomerMessageIncoming(customer, message.SeqId, dbMessage)
+			if moderation.Action == defs.ModerationActionFlag {
+				if err = flagMessage(server.Context(), impl.model, impl.flagM, customer.GetTalkID(), messageID,
+					dbMessage, moderation.Reason); err != nil {
+					logger.WithFields(l.ErrorField(err)).Error("FlagMessageFailed")
+				}
+			}
+
+			err = impl.controller.CustomerMessageIncoming(customer, message.SeqId, messageID, dbMessage)
 			if err != nil {
 				logger.WithFields(l.ErrorField(err)).Error("CustomerMessageIncomingFailed")
 
@@ -260,3 +376,74 @@ func (impl *customerServerImpl) customerReceiveRoutine(server talkpb.CustomerTal
 		}
 	}
 }
+
+func (impl *customerServerImpl) reviseMessage(ctx context.Context, customer defs.Customer, userID uint64, userName string,
+	command *defs.MessageCommand, logger l.Wrapper) {
+	moderation, err := moderateEdit(ctx, impl.moderator, impl.redactor, customer.GetTalkID(), userID, true, command)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("ModerateEditFailed")
+
+		return
+	}
+
+	if moderation.Action == defs.ModerationActionReject {
+		_ = customer.SendMessage(vo.TalkNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+			MessageID: command.MessageID,
+			Reason:    moderation.Reason,
+		}))
+
+		return
+	}
+
+	revision, err := impl.messageReviser.Revise(ctx, customer.GetTalkID(), userID, true, command)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err), l.StringField("command", command.Command)).Error("ReviseMessageFailed")
+		rejectCustomerMessage(customer, 0, command.MessageID, err)
+
+		return
+	}
+
+	if moderation.Action == defs.ModerationActionFlag && revision.Edit != nil {
+		if err = flagMessage(ctx, impl.model, impl.flagM, customer.GetTalkID(), revision.MessageID,
+			editMessage(revision, userName), moderation.Reason); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("FlagMessageFailed")
+		}
+	}
+
+	if err = impl.controller.CustomerMessageRevised(customer.GetTalkID(), revision); err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("CustomerMessageRevisedFailed")
+	}
+}
+
+// rejectCustomerMessage tells the customer why the message or the command of the message id is rejected.
+func rejectCustomerMessage(customer defs.Customer, seqID uint64, messageID string, err error) {
+	_ = customer.SendMessage(vo.TalkNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+		SeqID:     seqID,
+		MessageID: messageID,
+		Reason:    vo.MessageRejectReason(err),
+	}))
+}
+
+// rateTalk writes the rating message of the rate command, the last rating of the talk counts.
+func (impl *customerServerImpl) rateTalk(ctx context.Context, customer defs.Customer, userID uint64, userName string,
+	seqID uint64, command *defs.MessageCommand, logger l.Wrapper) error {
+	message, err := vo.NewRatingMessage(time.Now().Unix(), command.Score)
+	if err != nil {
+		rejectCustomerMessage(customer, seqID, "", err)
+
+		return nil
+	}
+
+	message.SenderID = userID
+	message.SenderUserName = userName
+
+	messageID, err := impl.model.AddTalkMessageWithID(ctx, customer.GetTalkID(), message)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("AddTalkMessageFailed")
+		rejectCustomerMessage(customer, seqID, "", err)
+
+		return nil
+	}
+
+	return impl.controller.CustomerMessageIncoming(customer, seqID, messageID, message)
+}
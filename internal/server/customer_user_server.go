@@ -11,10 +11,12 @@ import (
 	"google.golang.org/grpc/codes"
 )
 
-func NewCustomerUserServer(user userinters.UserCenter, tokenHelper defs.CustomerUserTokenHelper) talkpb.CustomerUserServicerServer {
+func NewCustomerUserServer(user userinters.UserCenter, tokenHelper defs.CustomerUserTokenHelper,
+	banM defs.CustomerBanModel) talkpb.CustomerUserServicerServer {
 	return &customerUserServerImpl{
 		user:        user,
 		tokenHelper: tokenHelper,
+		banM:        banM,
 	}
 }
 
@@ -23,6 +25,7 @@ type customerUserServerImpl struct {
 
 	user        userinters.UserCenter
 	tokenHelper defs.CustomerUserTokenHelper
+	banM        defs.CustomerBanModel
 }
 
 func (impl *customerUserServerImpl) CheckToken(ctx context.Context, request *talkpb.CheckTokenRequest) (*talkpb.CheckTokenResponse, error) {
@@ -54,26 +57,65 @@ func (impl *customerUserServerImpl) CreateToken(ctx context.Context, request *ta
 		return nil, gRPCMessageError(codes.InvalidArgument, "noRequest")
 	}
 
-	token, userName, err := impl.createToken(ctx, request.GetUserName(), request.GetActId(), request.GetBizId())
+	authenticator, userName, err := impl.createAuthenticator(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := impl.createToken(ctx, authenticator)
 	if err != nil {
 		return nil, gRPCError(codes.Internal, err)
 	}
 
+	if userName == "" {
+		userName = "Guest"
+	}
+
 	return &talkpb.CreateTokenResponse{
 		Token:    token,
 		UserName: userName,
 	}, nil
 }
 
-func (impl *customerUserServerImpl) createToken(ctx context.Context, userName, actID, bizID string) (token, tokenUserName string, err error) {
-	tokenUserName = userName
-	if tokenUserName == "" {
-		tokenUserName = "Guest"
+// createAuthenticator binds the identity signed by the host app if there is one, the anonymous customers are
+// rejected if the act requires the identities.
+func (impl *customerUserServerImpl) createAuthenticator(ctx context.Context,
+	request *talkpb.CreateTokenRequest) (userinters.Authenticator, string, error) {
+	identityToken, err := impl.tokenHelper.ExtractIdentityTokenFromGRPCContext(ctx)
+	if err != nil {
+		if impl.tokenHelper.IdentityRequired(request.GetActId()) {
+			return nil, "", gRPCMessageError(codes.Unauthenticated, "identityRequired")
+		}
+
+		if err = checkCustomerBan(ctx, impl.banM, 0, request.GetUserName(), request.GetActId()); err != nil {
+			return nil, "", err
+		}
+
+		return impl.tokenHelper.NewAnonymousAuthenticator(request.GetUserName(), request.GetActId(), request.GetBizId()),
+			request.GetUserName(), nil
 	}
 
+	userID, identity, err := impl.tokenHelper.VerifyIdentity(identityToken)
+	if err != nil {
+		return nil, "", gRPCError(codes.Unauthenticated, err)
+	}
+
+	if request.GetActId() != "" && request.GetActId() != identity.ActID ||
+		request.GetBizId() != "" && request.GetBizId() != identity.BizID {
+		return nil, "", gRPCMessageError(codes.PermissionDenied, "identityMismatch")
+	}
+
+	if err = checkCustomerBan(ctx, impl.banM, userID, identity.UserName, identity.ActID); err != nil {
+		return nil, "", err
+	}
+
+	return impl.tokenHelper.NewVerifiedAuthenticator(identity), identity.UserName, nil
+}
+
+func (impl *customerUserServerImpl) createToken(ctx context.Context, authenticator userinters.Authenticator) (token string, err error) {
 	resp, err := impl.user.Login(ctx, &userinters.LoginRequest{
 		ContinueID:        0,
-		Authenticators:    []userinters.Authenticator{impl.tokenHelper.NewAnonymousAuthenticator(userName, actID, bizID)},
+		Authenticators:    []userinters.Authenticator{authenticator},
 		TokenLiveDuration: time.Hour * 24 * 7,
 	})
 	if err != nil {
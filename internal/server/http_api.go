@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type apiResponse struct {
+	Code    codes.Code  `json:"code"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// maxAPIBodyBytes caps the json body of an api request.
+const maxAPIBodyBytes = 1024 * 1024
+
+type apiFunc[T any] func(ctx context.Context, request *T) (resp interface{}, code codes.Code, err error)
+
+// apiHandler adapts a json api to http, the token header is moved into the incoming grpc metadata and the client
+// ip into the peer, so the user token helpers and the login guard work the same way as the grpc services.
+// Only POST is accepted and the body is capped by maxAPIBodyBytes.
+func apiHandler[T any](do apiFunc[T], trustedProxies TrustedProxies, logger l.Wrapper) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAPIResponse(w, nil, codes.Unimplemented, commerr.ErrUnimplemented)
+
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxAPIBodyBytes)
+
+		var request T
+
+		d, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.WithFields(l.ErrorField(err)).Warn("ReadAllFailed")
+			writeAPIResponse(w, nil, codes.InvalidArgument, err)
+
+			return
+		}
+
+		if len(d) > 0 {
+			if err = json.Unmarshal(d, &request); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("UnmarshalFailed")
+				writeAPIResponse(w, nil, codes.InvalidArgument, err)
+
+				return
+			}
+		}
+
+		ctx := metadata.NewIncomingContext(r.Context(), metadata.New(map[string]string{
+			httpTokenHeaderKey:  r.Header.Get(httpTokenHeaderKey),
+			deviceKeyOnMetadata: r.UserAgent(),
+		}))
+		ctx = peer.NewContext(ctx, &peer.Peer{
+			Addr: &net.IPAddr{IP: net.ParseIP(trustedProxies.httpClientIP(r))},
+		})
+
+		resp, code, err := do(ctx, &request)
+		if code != codes.OK {
+			logger.WithFields(l.StringField("path", r.URL.Path), l.StringField("code", code.String()),
+				l.ErrorField(err)).Warn("APIFailed")
+		}
+
+		writeAPIResponse(w, resp, code, err)
+	}
+}
+
+func writeAPIResponse(w http.ResponseWriter, resp interface{}, code codes.Code, err error) {
+	apiResp := &apiResponse{
+		Code: code,
+		Data: resp,
+	}
+
+	if err != nil && code != codes.OK {
+		apiResp.Message = err.Error()
+	}
+
+	d, _ := json.Marshal(apiResp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(code))
+
+	_, _ = w.Write(d)
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func codeFromError(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, commerr.ErrInvalidArgument):
+		return codes.InvalidArgument
+	case errors.Is(err, commerr.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, commerr.ErrAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, commerr.ErrPermissionDenied):
+		return codes.PermissionDenied
+	case errors.Is(err, commerr.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, commerr.ErrResourceExhausted):
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// scopeIDs narrows the requested ids to the allowed ones, empty allowed ids means no limit.
+func scopeIDs(allowed, requested []string) (ids []string, ok bool) {
+	if len(requested) == 0 {
+		return allowed, true
+	}
+
+	if len(allowed) == 0 {
+		return requested, true
+	}
+
+	for _, id := range requested {
+		if !slices.Contains(allowed, id) {
+			return
+		}
+	}
+
+	return requested, true
+}
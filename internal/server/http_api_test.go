@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sgostarter/i/l"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAPIHandler(t *testing.T) {
+	type echoRequest struct {
+		Text string `json:"text"`
+	}
+
+	handler := apiHandler(func(ctx context.Context, request *echoRequest) (interface{}, codes.Code, error) {
+		return request, codes.OK, nil
+	}, nil, l.NewNopLoggerWrapper())
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, "/api/echo", strings.NewReader(body)))
+
+		return w
+	}
+
+	w := serve(http.MethodPost, `{"text":"hi"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"hi"`)
+
+	w = serve(http.MethodGet, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+
+	w = serve(http.MethodPost, `{"text":"`+strings.Repeat("a", maxAPIBodyBytes)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+// moderateMessage runs the moderator on the text of a text or quote message, the other messages are allowed.
+// The moderator gets the text redacted by the policy of the talk, so the sensitive data isn't sent to a external
+// moderator. The text given by the moderator replaces the original unless the message is rejected.
+func moderateMessage(ctx context.Context, moderator defs.Moderator, redactor defs.Redactor, talkID string,
+	message *talkinters.TalkMessageW) (*defs.ModerationResult, error) {
+	text, ok := moderationText(message)
+	if !ok {
+		return &defs.ModerationResult{
+			Action: defs.ModerationActionAllow,
+		}, nil
+	}
+
+	text = redactor.RedactText(ctx, talkID, text)
+
+	result, err := moderator.Moderate(ctx, &defs.ModerationRequest{
+		TalkID:          talkID,
+		SenderID:        message.SenderID,
+		CustomerMessage: message.CustomerMessage,
+		Text:            text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Action == defs.ModerationActionReject || result.Text == "" || result.Text == text {
+		return result, nil
+	}
+
+	if message.Type == talkinters.TalkMessageTypeText {
+		message.Text = result.Text
+
+		return result, nil
+	}
+
+	richMessage, _ := vo.RichMessageFromDB(message)
+	richMessage.Quote.Text = result.Text
+
+	return result, vo.UpdateRichMessage(message, richMessage)
+}
+
+func moderationText(message *talkinters.TalkMessageW) (string, bool) {
+	switch message.Type {
+	case talkinters.TalkMessageTypeText:
+		return vo.TalkMessageDB2Pb4Servicer(message).GetText(), true // the masked text of a redacted message
+	case defs.TalkMessageTypeQuote:
+		if richMessage, ok := vo.RichMessageFromDB(message); ok && richMessage.Quote != nil {
+			return richMessage.Quote.Text, true
+		}
+	}
+
+	return "", false
+}
+
+// flagMessage records a stored message flagged by the moderator for the supervisor review.
+func flagMessage(ctx context.Context, model defs.ModelEx, flagM defs.ModerationFlagModel, talkID, messageID string,
+	message *talkinters.TalkMessageW, reason string) error {
+	talkInfo, err := model.GetTalkInfo(ctx, nil, nil, talkID)
+	if err != nil {
+		return err
+	}
+
+	text, _ := moderationText(message)
+
+	return flagM.AddModerationFlag(ctx, &defs.ModerationFlag{
+		TalkID:          talkID,
+		ActID:           talkInfo.ActID,
+		BizID:           talkInfo.BizID,
+		MessageID:       messageID,
+		At:              message.At,
+		SenderID:        message.SenderID,
+		SenderUserName:  message.SenderUserName,
+		CustomerMessage: message.CustomerMessage,
+		Text:            text,
+		Reason:          reason,
+	})
+}
+
+// moderateEdit runs the moderator on the text of an edit command, the text of the command is replaced
+// by the moderated one, the other commands are allowed.
+func moderateEdit(ctx context.Context, moderator defs.Moderator, redactor defs.Redactor, talkID string, senderID uint64,
+	customerMessage bool, command *defs.MessageCommand) (*defs.ModerationResult, error) {
+	if command.Command != defs.MessageCommandEdit {
+		return &defs.ModerationResult{
+			Action: defs.ModerationActionAllow,
+		}, nil
+	}
+
+	message := &talkinters.TalkMessageW{
+		Type:            talkinters.TalkMessageTypeText,
+		Text:            command.Text,
+		SenderID:        senderID,
+		CustomerMessage: customerMessage,
+	}
+
+	result, err := moderateMessage(ctx, moderator, redactor, talkID, message)
+	if err != nil {
+		return nil, err
+	}
+
+	command.Text = message.Text
+
+	return result, nil
+}
+
+// editMessage makes the text message of an edit, for the flag record.
+func editMessage(revision *defs.MessageRevision, userName string) *talkinters.TalkMessageW {
+	return &talkinters.TalkMessageW{
+		At:              revision.Edit.At,
+		Type:            talkinters.TalkMessageTypeText,
+		Text:            revision.Edit.Text,
+		Data:            revision.Edit.Data,
+		SenderID:        revision.SenderID,
+		SenderUserName:  userName,
+		CustomerMessage: revision.CustomerMessage,
+	}
+}
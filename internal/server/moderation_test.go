@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/impls"
+)
+
+type recordingModerator struct {
+	texts []string
+}
+
+func (m *recordingModerator) Moderate(_ context.Context, request *defs.ModerationRequest) (*defs.ModerationResult, error) {
+	m.texts = append(m.texts, request.Text)
+
+	return &defs.ModerationResult{
+		Action: defs.ModerationActionAllow,
+	}, nil
+}
+
+func TestModerateRedactedText(t *testing.T) {
+	ctx := context.Background()
+	m := impls.NewModelEx(impls.NewMemModel(), impls.NewMemMessageExModel())
+
+	redactor, err := impls.NewRedactor(config.Redaction{
+		Default: config.RedactionPolicy{Mode: config.RedactionModeServicer},
+	}, m, nil)
+	assert.Nil(t, err)
+
+	moderator := &recordingModerator{}
+
+	message := &talkinters.TalkMessageW{Type: talkinters.TalkMessageTypeText, Text: "call 13812345678"}
+	_, err = moderateMessage(ctx, moderator, redactor, "t1", message)
+	assert.Nil(t, err)
+	assert.Equal(t, "call 13812345678", message.Text)
+
+	_, err = moderateEdit(ctx, moderator, redactor, "t1", 1, true, &defs.MessageCommand{
+		Command: defs.MessageCommandEdit,
+		Text:    "phone 13912345678",
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"call *******5678", "phone *******5678"}, moderator.texts)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+// checkQuoteMessage checks the quoted message exists in the talk and isn't recalled,
+// the snippet of the quote is taken from the quoted message.
+func checkQuoteMessage(ctx context.Context, model defs.ModelEx, talkID string, message *talkinters.TalkMessageW) error {
+	if message.Type != defs.TalkMessageTypeQuote {
+		return nil
+	}
+
+	richMessage, ok := vo.RichMessageFromDB(message)
+	if !ok || richMessage.Quote == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	messageEx, err := talkMessageEx(ctx, model, talkID, richMessage.Quote.MessageID)
+	if err != nil {
+		return err
+	}
+
+	richMessage.Quote.QuotedAt = messageEx.At
+	richMessage.Quote.QuotedCustomerMessage = messageEx.CustomerMessage
+	richMessage.Quote.Snippet = messageEx.Snippet
+
+	if len(messageEx.Edits) > 0 {
+		edit := messageEx.Edits[len(messageEx.Edits)-1]
+
+		richMessage.Quote.Snippet = vo.MessageSnippet(&talkinters.TalkMessageW{
+			Type: talkinters.TalkMessageTypeText,
+			Text: edit.Text,
+			Data: edit.Data,
+		})
+	}
+
+	return vo.UpdateRichMessage(message, richMessage)
+}
+
+// checkReplyMessage checks the replied button exists in a card or quick reply of the servicers in the talk,
+// the reply title is taken from the button.
+func checkReplyMessage(ctx context.Context, model defs.ModelEx, talkID string, message *talkinters.TalkMessageW) error {
+	if message.Type != defs.TalkMessageTypeReply {
+		return nil
+	}
+
+	richMessage, ok := vo.RichMessageFromDB(message)
+	if !ok || richMessage.Reply == nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	messageEx, err := talkMessageEx(ctx, model, talkID, richMessage.Reply.MessageID)
+	if err != nil {
+		return err
+	}
+
+	if messageEx.CustomerMessage {
+		return commerr.ErrNotFound
+	}
+
+	for _, button := range messageEx.Buttons {
+		if button.ID == richMessage.Reply.ButtonID && button.URL == "" {
+			richMessage.Reply.Title = button.Title
+
+			return vo.UpdateRichMessage(message, richMessage)
+		}
+	}
+
+	return commerr.ErrNotFound
+}
+
+// talkMessageEx loads the record of a message which isn't recalled by its id.
+func talkMessageEx(ctx context.Context, model defs.ModelEx, talkID, messageID string) (*defs.TalkMessageEx, error) {
+	if messageID == "" {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	messageEx, err := model.GetTalkMessageEx(ctx, talkID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if messageEx.RecalledAt != 0 {
+		return nil, commerr.ErrNotFound
+	}
+
+	return messageEx, nil
+}
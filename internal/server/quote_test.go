@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/impls"
+	"github.com/zservicer/talkbe/internal/vo"
+)
+
+func TestCheckQuoteAndReplyMessage(t *testing.T) {
+	ctx := context.Background()
+	m := impls.NewModelEx(impls.NewMemModel(), impls.NewMemMessageExModel())
+
+	talkID, err := m.CreateTalk(ctx, &talkinters.TalkInfoW{
+		Status:          talkinters.TalkStatusOpened,
+		Title:           "title",
+		StartAt:         1000,
+		CreatorID:       1,
+		CreatorUserName: "customer",
+		ActID:           "act",
+		BizID:           "biz",
+	})
+	assert.Nil(t, err)
+
+	quickReply := &talkinters.TalkMessageW{At: 1000, SenderID: 2}
+	assert.Nil(t, vo.UpdateRichMessage(quickReply, &defs.RichMessage{Kind: defs.RichMessageKindQuickReply,
+		QuickReply: &defs.QuickReplyMessage{Text: "solved?", Buttons: []*defs.ReplyButton{{ID: "y", Title: "Yes"}}}}))
+	quickReply.Type = defs.TalkMessageTypeQuickReply
+
+	quickReplyID, err := m.AddTalkMessageWithID(ctx, talkID, quickReply)
+	assert.Nil(t, err)
+
+	textID, err := m.AddTalkMessageWithID(ctx, talkID, &talkinters.TalkMessageW{At: 1001, CustomerMessage: true,
+		Type: talkinters.TalkMessageTypeText, SenderID: 1, Text: "hello"})
+	assert.Nil(t, err)
+
+	newMessage := func(richMessage *defs.RichMessage, messageType talkinters.TalkMessageType) *talkinters.TalkMessageW {
+		message := &talkinters.TalkMessageW{At: 1010, CustomerMessage: true, Type: messageType, SenderID: 1}
+		assert.Nil(t, vo.UpdateRichMessage(message, richMessage))
+
+		return message
+	}
+
+	reply := newMessage(&defs.RichMessage{Kind: defs.RichMessageKindReply,
+		Reply: &defs.ReplyMessage{MessageID: quickReplyID, ButtonID: "y"}}, defs.TalkMessageTypeReply)
+	assert.Nil(t, checkReplyMessage(ctx, m, talkID, reply))
+
+	richMessage, ok := vo.RichMessageFromDB(reply)
+	assert.True(t, ok)
+	assert.Equal(t, "Yes", richMessage.Reply.Title)
+
+	for _, r := range []*defs.ReplyMessage{{MessageID: quickReplyID, ButtonID: "n"}, {MessageID: textID, ButtonID: "y"}} {
+		reply = newMessage(&defs.RichMessage{Kind: defs.RichMessageKindReply, Reply: r}, defs.TalkMessageTypeReply)
+		assert.ErrorIs(t, checkReplyMessage(ctx, m, talkID, reply), commerr.ErrNotFound)
+	}
+
+	assert.Nil(t, m.AddTalkMessageEdit(ctx, talkID, textID, &defs.TalkMessageEdit{At: 1002, Text: "hi"}))
+
+	quote := newMessage(&defs.RichMessage{Kind: defs.RichMessageKindQuote,
+		Quote: &defs.QuoteMessage{MessageID: textID, Text: "yes"}}, defs.TalkMessageTypeQuote)
+	assert.Nil(t, checkQuoteMessage(ctx, m, talkID, quote))
+
+	richMessage, ok = vo.RichMessageFromDB(quote)
+	assert.True(t, ok)
+	assert.Equal(t, "hi", richMessage.Quote.Snippet)
+	assert.Equal(t, int64(1001), richMessage.Quote.QuotedAt)
+	assert.True(t, richMessage.Quote.QuotedCustomerMessage)
+
+	assert.Nil(t, m.RecallTalkMessage(ctx, talkID, textID, 1003))
+	assert.ErrorIs(t, checkQuoteMessage(ctx, m, talkID, quote), commerr.ErrNotFound)
+	assert.ErrorIs(t, checkQuoteMessage(ctx, m, "other", quote), commerr.ErrNotFound)
+}
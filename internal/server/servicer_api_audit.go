@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/codes"
+)
+
+type auditLogsRequest struct {
+	ActIDs   []string `json:"actIDs"`
+	StartAt  int64    `json:"startAt"`
+	FinishAt int64    `json:"finishAt"`
+}
+
+func (impl *ServicerAPIServer) auditLogs(ctx context.Context, request *auditLogsRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, _, actIDs, code, err := impl.permittedActIDs(ctx, defs.PermissionReadReports, request.ActIDs)
+	if code != codes.OK {
+		return
+	}
+
+	logs, err := impl.auditM.QueryAuditLogs(ctx, actIDs, request.StartAt, request.FinishAt)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if logs == nil {
+		logs = []*defs.AuditLog{}
+	}
+
+	resp = logs
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) audit(ctx context.Context, log *defs.AuditLog) {
+	if err := impl.auditM.AddAuditLog(ctx, log); err != nil {
+		impl.logger.WithFields(l.ErrorField(err), l.StringField("action", log.Action)).Error("AddAuditLogFailed")
+	}
+}
+
+// permittedActIDs checks the user has the permission and narrows the requested actIDs to the acts with it.
+func (impl *ServicerAPIServer) permittedActIDs(ctx context.Context, permission defs.Permission, requested []string) (
+	userID uint64, userName string, roles defs.ServicerRoles, actIDs []string, code codes.Code, err error) {
+	_, userID, userName, roles, allowed, _, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	allowed, ok := roles.PermittedActIDs(allowed, permission)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if actIDs, ok = scopeIDs(allowed, requested); !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/codes"
+)
+
+type customerBanRequest struct {
+	ActID           string `json:"actID"`
+	UserID          uint64 `json:"userID"`
+	UserName        string `json:"userName"` // bans the anonymous identity if UserID is zero
+	DurationSeconds int64  `json:"durationSeconds"`
+	Reason          string `json:"reason"`
+}
+
+type customerUnbanRequest struct {
+	ID string `json:"id"`
+}
+
+type customerBansRequest struct {
+	ActIDs     []string `json:"actIDs"`
+	ActiveOnly bool     `json:"activeOnly"`
+}
+
+// banCustomer bans a customer for a duration, zero duration means forever, the live streams are kicked out.
+func (impl *ServicerAPIServer) banCustomer(ctx context.Context, request *customerBanRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ActID == "" || (request.UserID == 0 && request.UserName == "") || request.DurationSeconds < 0 {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	userID, userName, _, _, code, err := impl.permittedActIDs(ctx, defs.PermissionModerate, []string{request.ActID})
+	if code != codes.OK {
+		return
+	}
+
+	now := time.Now().Unix()
+
+	ban := &defs.CustomerBan{
+		ActID:     request.ActID,
+		UserID:    request.UserID,
+		UserName:  request.UserName,
+		Reason:    request.Reason,
+		CreatedAt: now,
+		CreatorID: userID,
+	}
+
+	if request.UserID != 0 {
+		ban.UserName = ""
+	}
+
+	if request.DurationSeconds > 0 {
+		ban.ExpireAt = now + request.DurationSeconds
+	}
+
+	if err = impl.banM.AddCustomerBan(ctx, ban); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               now,
+		ActID:            ban.ActID,
+		OperatorID:       userID,
+		OperatorUserName: userName,
+		Action:           defs.AuditActionCustomerBan,
+		Target:           customerBanTarget(ban),
+		Detail:           fmt.Sprintf("id:%s expireAt:%d reason:%s", ban.ID, ban.ExpireAt, ban.Reason),
+	})
+
+	if err = impl.controller.CustomerBanned(ban); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	resp = ban
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) unbanCustomer(ctx context.Context, request *customerUnbanRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	ban, err := impl.banM.GetCustomerBan(ctx, request.ID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	userID, userName, _, _, code, err := impl.permittedActIDs(ctx, defs.PermissionModerate, []string{ban.ActID})
+	if code != codes.OK {
+		return
+	}
+
+	now := time.Now().Unix()
+
+	if err = impl.banM.RevokeCustomerBan(ctx, ban.ID, now, userID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               now,
+		ActID:            ban.ActID,
+		OperatorID:       userID,
+		OperatorUserName: userName,
+		Action:           defs.AuditActionCustomerUnban,
+		Target:           customerBanTarget(ban),
+		Detail:           "id:" + ban.ID,
+	})
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) customerBans(ctx context.Context, request *customerBansRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, _, actIDs, code, err := impl.permittedActIDs(ctx, defs.PermissionModerate, request.ActIDs)
+	if code != codes.OK {
+		return
+	}
+
+	var activeAt int64
+
+	if request.ActiveOnly {
+		activeAt = time.Now().Unix()
+	}
+
+	bans, err := impl.banM.QueryCustomerBans(ctx, actIDs, activeAt)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if bans == nil {
+		bans = []*defs.CustomerBan{}
+	}
+
+	resp = bans
+	code = codes.OK
+
+	return
+}
+
+func customerBanTarget(ban *defs.CustomerBan) string {
+	if ban.UserID != 0 {
+		return fmt.Sprintf("customer:%d", ban.UserID)
+	}
+
+	return "customer:" + ban.UserName
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/codes"
+)
+
+type servicerInvitationsRequest struct {
+	ActIDs []string `json:"actIDs"`
+}
+
+type servicerInvitationCreateRequest struct {
+	ActIDs     []string          `json:"actIDs"`     // the operator's acts by default
+	BizIDs     []string          `json:"bizIDs"`     // the operator's bizs by default
+	Role       defs.ServicerRole `json:"role"`       // agent by default
+	TTLSeconds int64             `json:"ttlSeconds"` // the configured max lifetime by default
+}
+
+type servicerInvitationCreateResponse struct {
+	Code       string                   `json:"code"` // shown once, the servicer registers with it
+	Invitation *defs.ServicerInvitation `json:"invitation"`
+}
+
+type servicerInvitationRevokeRequest struct {
+	ID string `json:"id"`
+}
+
+// servicerInvitations lists the invitations in the acts the user manages, the codes aren't kept.
+func (impl *ServicerAPIServer) servicerInvitations(ctx context.Context, request *servicerInvitationsRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, _, allowed, code, err := impl.permittedActIDs(ctx, defs.PermissionManageUsers, nil)
+	if code != codes.OK {
+		return
+	}
+
+	actIDs, ok := scopeIDs(allowed, request.ActIDs)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	invitations, err := impl.invitations.QueryInvitations(ctx, actIDs)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	scoped := make([]*defs.ServicerInvitation, 0, len(invitations))
+
+	for _, invitation := range invitations {
+		if actIDsInScope(allowed, invitation.ActIDs) {
+			scoped = append(scoped, invitation)
+		}
+	}
+
+	resp = scoped
+	code = codes.OK
+
+	return
+}
+
+// createServicerInvitation creates an invitation in the acts the user manages, the registered servicer gets the acts,
+// the bizs and the role of it. The operator grants the roles he outranks only, the owners grant any role.
+func (impl *ServicerAPIServer) createServicerInvitation(ctx context.Context, request *servicerInvitationCreateRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Role == "" {
+		request.Role = defs.ServicerRoleAgent
+	}
+
+	if request.TTLSeconds < 0 || !request.Role.Valid() {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, operatorID, operatorUserName, roles, allowedActIDs, allowedBizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	allowedActIDs, ok := roles.PermittedActIDs(allowedActIDs, defs.PermissionManageUsers)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	actIDs, ok := scopeIDs(allowedActIDs, request.ActIDs)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	bizIDs, ok := scopeIDs(allowedBizIDs, request.BizIDs)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	// the servicers without acts or bizs can't log in
+	if len(actIDs) == 0 || len(bizIDs) == 0 {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	if !roles.CanManage(actIDs, defs.ServicerRoles{defs.AllActs: request.Role}) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	invitation := &defs.ServicerInvitation{
+		ActIDs:          actIDs,
+		BizIDs:          bizIDs,
+		Role:            request.Role,
+		CreatorID:       operatorID,
+		CreatorUserName: operatorUserName,
+	}
+
+	invitationCode, err := impl.invitations.CreateInvitation(ctx, invitation, time.Duration(request.TTLSeconds)*time.Second)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               time.Now().Unix(),
+		ActID:            actIDs[0],
+		OperatorID:       operatorID,
+		OperatorUserName: operatorUserName,
+		Action:           defs.AuditActionInvitationCreate,
+		Target:           "invitation:" + invitation.ID,
+		Detail:           fmt.Sprintf("actIDs:%v bizIDs:%v role:%s expireAt:%d", actIDs, bizIDs, request.Role, invitation.ExpireAt),
+	})
+
+	resp = &servicerInvitationCreateResponse{
+		Code:       invitationCode,
+		Invitation: invitation,
+	}
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) revokeServicerInvitation(ctx context.Context, request *servicerInvitationRevokeRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	operatorID, operatorUserName, roles, allowed, code, err := impl.permittedActIDs(ctx, defs.PermissionManageUsers, nil)
+	if code != codes.OK {
+		return
+	}
+
+	invitation, err := impl.invitations.GetInvitation(ctx, request.ID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if !actIDsInScope(allowed, invitation.ActIDs) ||
+		!roles.CanManage(invitation.ActIDs, defs.ServicerRoles{defs.AllActs: invitation.Role}) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if err = impl.invitations.RevokeInvitation(ctx, invitation.ID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	log := &defs.AuditLog{
+		At:               time.Now().Unix(),
+		OperatorID:       operatorID,
+		OperatorUserName: operatorUserName,
+		Action:           defs.AuditActionInvitationRevoke,
+		Target:           "invitation:" + invitation.ID,
+	}
+
+	if len(invitation.ActIDs) > 0 {
+		log.ActID = invitation.ActIDs[0]
+	}
+
+	impl.audit(ctx, log)
+
+	code = codes.OK
+
+	return
+}
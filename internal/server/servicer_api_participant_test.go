@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sbasestarter/userlib"
+	memoryauthingdatastorage "github.com/sbasestarter/userlib/authingdatastorage/memory"
+	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/sbasestarter/userlib/policy/single"
+	"github.com/sgostarter/i/l"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/impls"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// receivedResponse waits for a matched response, the other responses are skipped.
+func receivedResponse(ch chan *talkpb.ServiceResponse, match func(resp *talkpb.ServiceResponse) bool,
+	timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case resp := <-ch:
+			if match(resp) {
+				return true
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+type testServicerAPI struct {
+	t            *testing.T
+	ctx          context.Context
+	manager      userpassmanager.Manager
+	userAdmin    defs.ServicerUserAdmin
+	userCenter   userinters.UserCenter
+	tokenHelper  defs.ServicerUserTokenHelper
+	m            defs.ModelEx
+	mdi          defs.MDI
+	participantM defs.TalkParticipantModel
+	redactor     defs.Redactor
+	controller   *controller.ServicerController
+	api          *ServicerAPIServer
+}
+
+func newTestServicerAPI(t *testing.T) *testServicerAPI {
+	ctx := context.Background()
+
+	userModel := impls.NewMemUserPassModel()
+	manager := userpassmanager.NewManager("secret", userModel)
+	status := impls.NewMemServicerStatusController()
+	userCenter := userlib.NewUserCenter("tokenSecret", single.NewPolicy(userinters.AuthMethodNameUserPassword),
+		status, memoryauthingdatastorage.NewMemoryAuthingDataStorage(), nil)
+
+	s := &testServicerAPI{
+		t:            t,
+		ctx:          ctx,
+		manager:      manager,
+		userAdmin:    impls.NewServicerUserAdmin("secret", impls.NewPasswordPolicy(config.PasswordPolicy{}), userModel),
+		userCenter:   userCenter,
+		tokenHelper:  impls.NewLocalServicerUserTokenHelper(userCenter, manager, status),
+		m:            impls.NewModelEx(impls.NewMemModel(), impls.NewMemMessageExModel()),
+		participantM: impls.NewMemParticipantModel(),
+	}
+
+	var err error
+
+	s.redactor, err = impls.NewRedactor(config.Redaction{
+		Default: config.RedactionPolicy{Mode: config.RedactionModeServicer},
+	}, s.m, nil)
+	assert.Nil(t, err)
+
+	s.mdi = impls.NewAllInOneMDI(s.m, nil)
+	_ = controller.NewCustomerController(impls.NewCustomerMD(s.mdi, nil, nil), s.m, nil)
+	s.controller = controller.NewServicerController(impls.NewServicerMD(s.mdi, s.participantM, nil, nil), s.m, nil)
+
+	s.api = &ServicerAPIServer{
+		logger:          l.NewNopLoggerWrapper(),
+		controller:      s.controller,
+		userTokenHelper: s.tokenHelper,
+		m:               s.m,
+		participantM:    s.participantM,
+		userAdmin:       s.userAdmin,
+		redactor:        s.redactor,
+	}
+
+	return s
+}
+
+func (s *testServicerAPI) register(userName string, actIDs []string, role defs.ServicerRole) uint64 {
+	userID, err := s.manager.Register(s.ctx, userName, "123456")
+	assert.Nil(s.t, err)
+
+	exData := s.tokenHelper.GenExData(actIDs, []string{"b1"})
+	for key, val := range s.tokenHelper.GenRolesExData(defs.ServicerRoles{defs.AllActs: role}) {
+		exData[key] = val
+	}
+
+	assert.Nil(s.t, s.manager.UpdateUserAllExData(s.ctx, userID, exData))
+
+	return userID
+}
+
+// login returns the context of the api requests of the user.
+func (s *testServicerAPI) login(userName string) context.Context {
+	resp, err := s.userCenter.Login(s.ctx, &userinters.LoginRequest{
+		Authenticators:    []userinters.Authenticator{s.tokenHelper.NewAuthenticator(userName, "123456")},
+		TokenLiveDuration: time.Hour,
+	})
+	assert.Nil(s.t, err)
+
+	_, err = s.tokenHelper.StartSession(s.ctx, resp.Token, "test", "127.0.0.1")
+	assert.Nil(s.t, err)
+
+	return metadata.NewIncomingContext(s.ctx, metadata.Pairs("token", resp.Token))
+}
+
+func (s *testServicerAPI) createTalk(servicerID uint64) string {
+	talkID, err := s.m.CreateTalk(s.ctx, &talkinters.TalkInfoW{
+		Status:          talkinters.TalkStatusOpened,
+		Title:           "title",
+		StartAt:         time.Now().Unix(),
+		CreatorID:       1,
+		CreatorUserName: "customer",
+		ActID:           "a1",
+		BizID:           "b1",
+	})
+	assert.Nil(s.t, err)
+	assert.Nil(s.t, s.m.UpdateTalkServiceID(s.ctx, nil, nil, talkID, servicerID))
+
+	return talkID
+}
+
+func TestServicerAPIInviteParticipant(t *testing.T) {
+	s := newTestServicerAPI(t)
+	ctx, api, mdi, participantM, servicerController := s.ctx, s.api, s.mdi, s.participantM, s.controller
+
+	aliceID := s.register("alice", []string{"a1"}, defs.ServicerRoleAgent)
+	bobID := s.register("bob", []string{"a1"}, defs.ServicerRoleAgent)
+	carolID := s.register("carol", []string{"a2"}, defs.ServicerRoleAgent)
+	daveID := s.register("dave", []string{"a1"}, defs.ServicerRoleAgent)
+	assert.Nil(t, s.userAdmin.SetServicerUserDisabled(ctx, daveID, true))
+
+	aliceCtx := s.login("alice")
+	talkID := s.createTalk(aliceID)
+
+	chBob := make(chan *talkpb.ServiceResponse, 100)
+	chCarol := make(chan *talkpb.ServiceResponse, 100)
+
+	assert.Nil(t, servicerController.InstallServicer(controller.NewServicer(bobID, "bob", 2, "", chBob,
+		[]string{"a1"}, []string{"b1"}, defs.ServicerRoles{defs.AllActs: defs.ServicerRoleAgent})))
+	assert.Nil(t, servicerController.InstallServicer(controller.NewServicer(carolID, "carol", 3, "", chCarol,
+		[]string{"a2"}, []string{"b1"}, defs.ServicerRoles{defs.AllActs: defs.ServicerRoleAgent})))
+
+	// the servicers are installed by the main routine of the controller
+	isPendingTalks := func(resp *talkpb.ServiceResponse) bool {
+		return resp.GetPendingTalks() != nil
+	}
+	assert.True(t, receivedResponse(chBob, isPendingTalks, time.Second))
+	assert.True(t, receivedResponse(chCarol, isPendingTalks, time.Second))
+
+	invite := func(servicerID uint64) codes.Code {
+		_, code, _ := api.inviteParticipant(aliceCtx, &talkParticipantRequest{
+			TalkID:     talkID,
+			ServicerID: servicerID,
+		})
+
+		return code
+	}
+
+	assert.Equal(t, codes.PermissionDenied, invite(carolID))
+	assert.Equal(t, codes.FailedPrecondition, invite(daveID))
+	assert.Equal(t, codes.NotFound, invite(10000))
+	assert.Equal(t, codes.InvalidArgument, invite(aliceID))
+	assert.Equal(t, codes.OK, invite(bobID))
+	assert.Equal(t, codes.AlreadyExists, invite(bobID))
+
+	assert.True(t, receivedResponse(chBob, func(resp *talkpb.ServiceResponse) bool {
+		return resp.GetReload().GetTalk().GetTalkInfo().GetTalkId() == talkID
+	}, time.Second))
+
+	participants, err := participantM.GetTalkParticipants(ctx, talkID)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{bobID}, participants)
+
+	mdi.SendMessage(0, talkID, &talkinters.TalkMessageW{
+		At:              time.Now().Unix(),
+		CustomerMessage: true,
+		Type:            talkinters.TalkMessageTypeText,
+		SenderID:        1,
+		Text:            "hello",
+	})
+
+	isTalkMessage := func(resp *talkpb.ServiceResponse) bool {
+		return resp.GetMessage().GetTalkId() == talkID
+	}
+	assert.True(t, receivedResponse(chBob, isTalkMessage, time.Second))
+	assert.False(t, receivedResponse(chCarol, isTalkMessage, time.Millisecond*100))
+
+	// the attached servicer removes the participant
+	_, code, _ := api.leaveParticipant(aliceCtx, &talkParticipantRequest{
+		TalkID:     talkID,
+		ServicerID: bobID,
+	})
+	assert.Equal(t, codes.OK, code)
+
+	_, code, _ = api.leaveParticipant(aliceCtx, &talkParticipantRequest{
+		TalkID:     talkID,
+		ServicerID: bobID,
+	})
+	assert.Equal(t, codes.NotFound, code)
+
+	assert.True(t, receivedResponse(chBob, func(resp *talkpb.ServiceResponse) bool {
+		return strings.Contains(resp.GetNotify().GetMsg(), `"join":false`)
+	}, time.Second))
+
+	mdi.SendMessage(0, talkID, &talkinters.TalkMessageW{
+		At:              time.Now().Unix(),
+		CustomerMessage: true,
+		Type:            talkinters.TalkMessageTypeText,
+		SenderID:        1,
+		Text:            "bye",
+	})
+
+	assert.False(t, receivedResponse(chBob, isTalkMessage, time.Millisecond*100))
+}
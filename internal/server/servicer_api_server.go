@@ -0,0 +1,691 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sbasestarter/bizinters/userinters"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+)
+
+func NewServicerAPIServer(controller *controller.ServicerController, userTokenHelper defs.ServicerUserTokenHelper, m defs.ModelEx,
+	participantM defs.TalkParticipantModel, reporter defs.Reporter, redactor defs.Redactor, flagM defs.ModerationFlagModel,
+	banM defs.CustomerBanModel, auditM defs.AuditLogModel, userAdmin defs.ServicerUserAdmin, user userinters.UserCenter,
+	loginGuard defs.LoginGuard, twoFactor defs.TwoFactorAuth, twoFactorPolicyM defs.TwoFactorPolicyModel,
+	invitations defs.ServicerInvitations, trustedProxies TrustedProxies, logger l.Wrapper) *ServicerAPIServer {
+	if logger == nil {
+		logger = l.NewNopLoggerWrapper()
+	}
+
+	if controller == nil || userTokenHelper == nil || m == nil || participantM == nil || reporter == nil || redactor == nil || flagM == nil ||
+		banM == nil || auditM == nil || userAdmin == nil || user == nil || loginGuard == nil ||
+		twoFactor == nil || twoFactorPolicyM == nil || invitations == nil {
+		logger.Fatal("invalid input args")
+	}
+
+	return &ServicerAPIServer{
+		logger:           logger.WithFields(l.StringField(l.ClsKey, "ServicerAPIServer")),
+		controller:       controller,
+		userTokenHelper:  userTokenHelper,
+		m:                m,
+		participantM:     participantM,
+		reporter:         reporter,
+		redactor:         redactor,
+		flagM:            flagM,
+		banM:             banM,
+		auditM:           auditM,
+		userAdmin:        userAdmin,
+		user:             user,
+		loginGuard:       loginGuard,
+		twoFactor:        twoFactor,
+		twoFactorPolicyM: twoFactorPolicyM,
+		invitations:      invitations,
+		trustedProxies:   trustedProxies,
+	}
+}
+
+type ServicerAPIServer struct {
+	logger           l.Wrapper
+	controller       *controller.ServicerController
+	userTokenHelper  defs.ServicerUserTokenHelper
+	m                defs.ModelEx
+	participantM     defs.TalkParticipantModel
+	reporter         defs.Reporter
+	redactor         defs.Redactor
+	flagM            defs.ModerationFlagModel
+	banM             defs.CustomerBanModel
+	auditM           defs.AuditLogModel
+	userAdmin        defs.ServicerUserAdmin
+	user             userinters.UserCenter
+	loginGuard       defs.LoginGuard
+	twoFactor        defs.TwoFactorAuth
+	twoFactorPolicyM defs.TwoFactorPolicyModel
+	invitations      defs.ServicerInvitations
+	trustedProxies   TrustedProxies
+}
+
+type talkWatchRequest struct {
+	TalkID string `json:"talkID"`
+}
+
+// talkExportRequest exports the texts as shown to servicers, the originals are revealed if asked and permitted.
+type talkExportRequest struct {
+	TalkID string `json:"talkID"`
+	Reveal bool   `json:"reveal"`
+}
+
+type talkParticipantRequest struct {
+	TalkID     string `json:"talkID"`
+	ServicerID uint64 `json:"servicerID"`
+}
+
+type talkParticipantsResponse struct {
+	TalkID             string   `json:"talkID"`
+	AttachedServicerID uint64   `json:"attachedServicerID"`
+	ServicerIDs        []uint64 `json:"servicerIDs"`
+}
+
+type talkMessageRequest struct {
+	TalkID    string `json:"talkID"`
+	MessageID string `json:"messageID"`
+}
+
+type talkMessageOriginalResponse struct {
+	MessageID string `json:"messageID"`
+	Text      string `json:"text"`
+}
+
+type moderationFlagsRequest struct {
+	ActIDs   []string `json:"actIDs"`
+	BizIDs   []string `json:"bizIDs"`
+	StartAt  int64    `json:"startAt"`
+	FinishAt int64    `json:"finishAt"`
+}
+
+type talkExportResponse struct {
+	TalkInfo *talkinters.TalkInfoR      `json:"talkInfo"`
+	Messages []*talkinters.TalkMessageR `json:"messages"`
+}
+
+type talkWhisperRequest struct {
+	TalkID string `json:"talkID"`
+	Text   string `json:"text"`
+}
+
+func (impl *ServicerAPIServer) Setup(mux *http.ServeMux) {
+	mux.HandleFunc("/api/report", apiHandler(impl.report, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/watch", apiHandler(impl.watchTalk, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/unwatch", apiHandler(impl.unwatchTalk, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/whisper", apiHandler(impl.whisper, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/participants", apiHandler(impl.talkParticipants, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/invite", apiHandler(impl.inviteParticipant, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/leave", apiHandler(impl.leaveParticipant, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/access", apiHandler(impl.talkAccess, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/close", apiHandler(impl.closeTalk, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/export", apiHandler(impl.exportTalk, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/talk/message/original", apiHandler(impl.messageOriginal, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/moderation/flags", apiHandler(impl.moderationFlags, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/customer/ban", apiHandler(impl.banCustomer, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/customer/unban", apiHandler(impl.unbanCustomer, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/customer/bans", apiHandler(impl.customerBans, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/audit/logs", apiHandler(impl.auditLogs, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/users", apiHandler(impl.servicerUsers, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/disable", apiHandler(impl.disableServicer, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/delete", apiHandler(impl.deleteServicer, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/password/reset", apiHandler(impl.resetServicerPassword, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/password/change", apiHandler(impl.changeServicerPassword, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/unlock", apiHandler(impl.unlockServicer, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/role", apiHandler(impl.setServicerRole, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/sessions", apiHandler(impl.servicerSessions, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/sessions/revoke", apiHandler(impl.revokeServicerSession, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/sessions/revokeAll", apiHandler(impl.revokeServicerSessions, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/totp/enroll", apiHandler(impl.enrollServicerTOTP, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/totp/activate", apiHandler(impl.activateServicerTOTP, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/totp/disable", apiHandler(impl.disableServicerTOTP, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/totp/reset", apiHandler(impl.resetServicerTOTP, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/totp/require", apiHandler(impl.requireServicerTOTP, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/invitations", apiHandler(impl.servicerInvitations, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/invitations/create", apiHandler(impl.createServicerInvitation, impl.trustedProxies, impl.logger))
+	mux.HandleFunc("/api/servicer/invitations/revoke", apiHandler(impl.revokeServicerInvitation, impl.trustedProxies, impl.logger))
+}
+
+func (impl *ServicerAPIServer) report(ctx context.Context, request *defs.ReportRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, _, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	actIDs, ok := roles.PermittedActIDs(actIDs, defs.PermissionReadReports)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if request.ActIDs, ok = scopeIDs(actIDs, request.ActIDs); !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if request.BizIDs, ok = scopeIDs(bizIDs, request.BizIDs); !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	resp, err = impl.reporter.Report(ctx, request)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// moderationFlags lists the messages flagged by the moderation in the acts the user moderates.
+func (impl *ServicerAPIServer) moderationFlags(ctx context.Context, request *moderationFlagsRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, _, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	actIDs, ok := roles.PermittedActIDs(actIDs, defs.PermissionModerate)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if request.ActIDs, ok = scopeIDs(actIDs, request.ActIDs); !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if request.BizIDs, ok = scopeIDs(bizIDs, request.BizIDs); !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	flags, err := impl.flagM.QueryModerationFlags(ctx, request.ActIDs, request.BizIDs, request.StartAt, request.FinishAt)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if flags == nil {
+		flags = []*defs.ModerationFlag{}
+	}
+
+	resp = flags
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) watchTalk(ctx context.Context, request *talkWatchRequest) (resp interface{}, code codes.Code, err error) {
+	return impl.doWatchTalk(ctx, request, true)
+}
+
+func (impl *ServicerAPIServer) unwatchTalk(ctx context.Context, request *talkWatchRequest) (resp interface{}, code codes.Code, err error) {
+	return impl.doWatchTalk(ctx, request, false)
+}
+
+func (impl *ServicerAPIServer) doWatchTalk(ctx context.Context, request *talkWatchRequest, watch bool) (resp interface{}, code codes.Code, err error) {
+	userID, _, talkInfo, code, err := impl.permittedTalkInfo(ctx, request.TalkID, defs.PermissionMonitor)
+	if code != codes.OK {
+		return
+	}
+
+	if watch && talkInfo.Status == talkinters.TalkStatusClosed {
+		code = codes.FailedPrecondition
+
+		return
+	}
+
+	if err = impl.controller.ServicerWatchTalk(userID, request.TalkID, watch); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) whisper(ctx context.Context, request *talkWhisperRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Text == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	userID, userName, talkInfo, code, err := impl.permittedTalkInfo(ctx, request.TalkID, defs.PermissionMonitor)
+	if code != codes.OK {
+		return
+	}
+
+	if talkInfo.Status == talkinters.TalkStatusClosed || talkInfo.ServiceID == 0 {
+		code = codes.FailedPrecondition
+
+		return
+	}
+
+	if err = impl.controller.ServicerWhisper(request.TalkID, &defs.WhisperMessage{
+		SenderID:       userID,
+		SenderUserName: userName,
+		Text:           request.Text,
+	}); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// messageOriginal reveals the original text of a redacted message to the moderators.
+func (impl *ServicerAPIServer) messageOriginal(ctx context.Context, request *talkMessageRequest) (resp interface{}, code codes.Code, err error) {
+	if request.MessageID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, _, _, code, err = impl.permittedTalkInfo(ctx, request.TalkID, defs.PermissionModerate)
+	if code != codes.OK {
+		return
+	}
+
+	messages, err := impl.m.GetTalkMessages(ctx, request.TalkID, 0, 0)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	for _, message := range messages {
+		if message.MessageID != request.MessageID {
+			continue
+		}
+
+		var text string
+
+		text, err = impl.redactor.Reveal(&message.TalkMessageW)
+		if err != nil {
+			code = codeFromError(err)
+
+			return
+		}
+
+		resp = &talkMessageOriginalResponse{
+			MessageID: message.MessageID,
+			Text:      text,
+		}
+		code = codes.OK
+
+		return
+	}
+
+	code = codes.NotFound
+
+	return
+}
+
+func (impl *ServicerAPIServer) talkParticipants(ctx context.Context, request *talkWatchRequest) (resp interface{}, code codes.Code, err error) {
+	userID, roles, talkInfo, participants, code, err := impl.participantTalkInfo(ctx, request.TalkID)
+	if code != codes.OK {
+		return
+	}
+
+	if !roles.Can(talkInfo.ActID, defs.PermissionMonitor) && userID != talkInfo.ServiceID &&
+		!slices.Contains(participants, userID) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	resp = &talkParticipantsResponse{
+		TalkID:             talkInfo.TalkID,
+		AttachedServicerID: talkInfo.ServiceID,
+		ServicerIDs:        participants,
+	}
+	code = codes.OK
+
+	return
+}
+
+// talkAccess checks the servicer may read the talk's attachments, for the gateways.
+func (impl *ServicerAPIServer) talkAccess(ctx context.Context, request *talkWatchRequest) (resp interface{}, code codes.Code, err error) {
+	userID, roles, talkInfo, participants, code, err := impl.participantTalkInfo(ctx, request.TalkID)
+	if code != codes.OK {
+		return
+	}
+
+	if !roles.Can(talkInfo.ActID, defs.PermissionMonitor) && userID != talkInfo.ServiceID &&
+		!slices.Contains(participants, userID) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// closeTalk lets the attached servicer or the servicers with the closeOthers permission close the talk.
+func (impl *ServicerAPIServer) closeTalk(ctx context.Context, request *talkWatchRequest) (resp interface{}, code codes.Code, err error) {
+	if request.TalkID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, userID, userName, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	talkInfo, err := impl.m.GetTalkInfo(ctx, actIDs, bizIDs, request.TalkID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if !roles.Can(talkInfo.ActID, defs.PermissionCloseOthers) &&
+		(userID != talkInfo.ServiceID || !roles.Can(talkInfo.ActID, defs.PermissionAttach)) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if talkInfo.Status == talkinters.TalkStatusClosed {
+		code = codes.FailedPrecondition
+
+		return
+	}
+
+	if err = impl.controller.ServicerCloseTalk(request.TalkID, userID, userName); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// exportTalk exports the talk with all its messages, the redacted texts are exported as shown to servicers,
+// the originals are revealed to the moderators only, as messageOriginal does.
+func (impl *ServicerAPIServer) exportTalk(ctx context.Context, request *talkExportRequest) (resp interface{}, code codes.Code, err error) {
+	_, _, talkInfo, code, err := impl.permittedTalkInfo(ctx, request.TalkID, defs.PermissionExport)
+	if code != codes.OK {
+		return
+	}
+
+	if request.Reveal {
+		if _, _, _, code, err = impl.permittedTalkInfo(ctx, request.TalkID, defs.PermissionModerate); code != codes.OK {
+			return
+		}
+	}
+
+	messages, err := impl.m.GetTalkMessages(ctx, request.TalkID, 0, 0)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if messages == nil {
+		messages = []*talkinters.TalkMessageR{}
+	}
+
+	for _, message := range messages {
+		if message.Type != talkinters.TalkMessageTypeText {
+			continue
+		}
+
+		text := vo.TalkMessageDB2Pb4Servicer(&message.TalkMessageW).GetText()
+
+		if request.Reveal {
+			if text, err = impl.redactor.Reveal(&message.TalkMessageW); err != nil {
+				code = codeFromError(err)
+
+				return
+			}
+		}
+
+		message.Text = text
+		message.Data = nil
+	}
+
+	resp = &talkExportResponse{
+		TalkInfo: talkInfo,
+		Messages: messages,
+	}
+	code = codes.OK
+
+	return
+}
+
+// inviteParticipant lets the attached servicer or the servicers with the transfer permission invite another
+// servicer into the talk.
+func (impl *ServicerAPIServer) inviteParticipant(ctx context.Context, request *talkParticipantRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ServicerID == 0 {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	userID, roles, talkInfo, participants, code, err := impl.participantTalkInfo(ctx, request.TalkID)
+	if code != codes.OK {
+		return
+	}
+
+	if !roles.Can(talkInfo.ActID, defs.PermissionTransfer) && userID != talkInfo.ServiceID {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if talkInfo.Status == talkinters.TalkStatusClosed {
+		code = codes.FailedPrecondition
+
+		return
+	}
+
+	if request.ServicerID == userID {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	if request.ServicerID == talkInfo.ServiceID || slices.Contains(participants, request.ServicerID) {
+		code = codes.AlreadyExists
+
+		return
+	}
+
+	if code, err = impl.checkInvitee(ctx, talkInfo, request.ServicerID); code != codes.OK {
+		return
+	}
+
+	if err = impl.participantM.AddTalkParticipant(ctx, request.TalkID, request.ServicerID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if err = impl.controller.ServicerTalkParticipant(request.TalkID, request.ServicerID, true); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// checkInvitee requires the invitee to be an enabled servicer who may attach the talks in the act and the biz
+// of the talk, the participants receive all the messages of the talk.
+func (impl *ServicerAPIServer) checkInvitee(ctx context.Context, talkInfo *talkinters.TalkInfoR, servicerID uint64) (
+	code codes.Code, err error) {
+	invitee, err := impl.userAdmin.GetServicerUser(ctx, servicerID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if invitee.Disabled {
+		code = codes.FailedPrecondition
+
+		return
+	}
+
+	if !actIDsInScope(invitee.ActIDs, []string{talkInfo.ActID}) || !actIDsInScope(invitee.BizIDs, []string{talkInfo.BizID}) ||
+		!invitee.Roles.Can(talkInfo.ActID, defs.PermissionAttach) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// leaveParticipant removes a participant from the talk, a participant can leave by itself,
+// the attached servicer or the servicers with the transfer permission can remove anyone.
+func (impl *ServicerAPIServer) leaveParticipant(ctx context.Context, request *talkParticipantRequest) (resp interface{}, code codes.Code, err error) {
+	userID, roles, talkInfo, participants, code, err := impl.participantTalkInfo(ctx, request.TalkID)
+	if code != codes.OK {
+		return
+	}
+
+	if request.ServicerID == 0 {
+		request.ServicerID = userID
+	}
+
+	if !roles.Can(talkInfo.ActID, defs.PermissionTransfer) && userID != talkInfo.ServiceID && userID != request.ServicerID {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if !slices.Contains(participants, request.ServicerID) {
+		code = codes.NotFound
+
+		return
+	}
+
+	if err = impl.participantM.RemoveTalkParticipant(ctx, request.TalkID, request.ServicerID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if err = impl.controller.ServicerTalkParticipant(request.TalkID, request.ServicerID, false); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) participantTalkInfo(ctx context.Context, talkID string) (userID uint64, roles defs.ServicerRoles,
+	talkInfo *talkinters.TalkInfoR, participants []uint64, code codes.Code, err error) {
+	if talkID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, userID, _, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	talkInfo, err = impl.m.GetTalkInfo(ctx, actIDs, bizIDs, talkID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	participants, err = impl.participantM.GetTalkParticipants(ctx, talkID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// permittedTalkInfo checks the user has the permission in the act of the talk.
+func (impl *ServicerAPIServer) permittedTalkInfo(ctx context.Context, talkID string, permission defs.Permission) (
+	userID uint64, userName string, talkInfo *talkinters.TalkInfoR, code codes.Code, err error) {
+	if talkID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, userID, userName, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	actIDs, ok := roles.PermittedActIDs(actIDs, permission)
+	if !ok {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	talkInfo, err = impl.m.GetTalkInfo(ctx, actIDs, bizIDs, talkID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"google.golang.org/grpc/codes"
+)
+
+type servicerSessionsRequest struct{}
+
+type servicerSessionsResponse struct {
+	CurrentID string                  `json:"currentID"`
+	Sessions  []*defs.ServicerSession `json:"sessions"`
+}
+
+type servicerSessionRevokeRequest struct {
+	ID string `json:"id"`
+}
+
+type servicerSessionsRevokeRequest struct {
+	UserID uint64 `json:"userID"` // the other sessions of the servicer himself if it's zero
+}
+
+// servicerSessions lists the active sessions of the servicer himself.
+func (impl *ServicerAPIServer) servicerSessions(ctx context.Context, _ *servicerSessionsRequest) (resp interface{}, code codes.Code, err error) {
+	session, err := impl.userTokenHelper.ExtractSessionFromGRPCContext(ctx)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	sessions, err := impl.userTokenHelper.QuerySessions(ctx, session.UserID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	resp = &servicerSessionsResponse{
+		CurrentID: session.ID,
+		Sessions:  sessions,
+	}
+	code = codes.OK
+
+	return
+}
+
+// revokeServicerSession revokes a session of the servicer himself, the current one logs out.
+func (impl *ServicerAPIServer) revokeServicerSession(ctx context.Context, request *servicerSessionRevokeRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, userID, userName, _, _, _, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	sessions, err := impl.userTokenHelper.QuerySessions(ctx, userID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	var session *defs.ServicerSession
+
+	for _, s := range sessions {
+		if s.ID == request.ID {
+			session = s
+
+			break
+		}
+	}
+
+	if session == nil {
+		code = codes.NotFound
+
+		return
+	}
+
+	if err = impl.userTokenHelper.RevokeSession(ctx, session.ID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               time.Now().Unix(),
+		OperatorID:       userID,
+		OperatorUserName: userName,
+		Action:           defs.AuditActionServicerSessionRevoke,
+		Target:           fmt.Sprintf("servicer:%d:%s", userID, userName),
+		Detail:           fmt.Sprintf("session:%s device:%s ip:%s", session.ID, session.Device, session.IP),
+	})
+
+	if err = impl.controller.KickServicer(userID, session.ID, vo.KickOutReasonSessionRevoked); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// revokeServicerSessions revokes all the sessions of a managed servicer, or the other sessions of the servicer
+// himself, the live streams of the sessions are kicked out.
+func (impl *ServicerAPIServer) revokeServicerSessions(ctx context.Context, request *servicerSessionsRevokeRequest) (resp interface{}, code codes.Code, err error) {
+	if request.UserID == 0 {
+		return impl.revokeOtherServicerSessions(ctx)
+	}
+
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.userTokenHelper.RevokeUserSessions(ctx, user.ID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerSessionsRevoke, user, "")
+
+	if err = impl.controller.KickServicer(user.ID, "", vo.KickOutReasonSessionRevoked); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) revokeOtherServicerSessions(ctx context.Context) (resp interface{}, code codes.Code, err error) {
+	_, userID, userName, _, _, _, err := impl.userTokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	current, err := impl.userTokenHelper.ExtractSessionFromGRPCContext(ctx)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	sessions, err := impl.userTokenHelper.QuerySessions(ctx, userID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	for _, session := range sessions {
+		if session.ID == current.ID {
+			continue
+		}
+
+		if err = impl.userTokenHelper.RevokeSession(ctx, session.ID); err != nil {
+			code = codeFromError(err)
+
+			return
+		}
+
+		if err = impl.controller.KickServicer(userID, session.ID, vo.KickOutReasonSessionRevoked); err != nil {
+			code = codes.Unavailable
+
+			return
+		}
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               time.Now().Unix(),
+		OperatorID:       userID,
+		OperatorUserName: userName,
+		Action:           defs.AuditActionServicerSessionsRevoke,
+		Target:           fmt.Sprintf("servicer:%d:%s", userID, userName),
+		Detail:           "except:" + current.ID,
+	})
+
+	code = codes.OK
+
+	return
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/internal/controller"
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/codes"
+)
+
+func TestServicerAPICloseAndExportTalk(t *testing.T) {
+	s := newTestServicerAPI(t)
+
+	aliceID := s.register("alice", []string{"a1"}, defs.ServicerRoleAgent)
+	_ = s.register("bob", []string{"a1"}, defs.ServicerRoleAgent)
+	_ = s.register("sam", []string{"a1"}, defs.ServicerRoleSupervisor)
+	_ = s.register("ada", []string{"a1"}, defs.ServicerRoleAdmin)
+
+	aliceCtx, bobCtx, samCtx, adaCtx := s.login("alice"), s.login("bob"), s.login("sam"), s.login("ada")
+
+	talkClosed := func(talkID string) func() bool {
+		return func() bool {
+			talkInfo, err := s.m.GetTalkInfo(s.ctx, nil, nil, talkID)
+
+			return err == nil && talkInfo.Status == talkinters.TalkStatusClosed
+		}
+	}
+
+	closeTalk := func(ctx context.Context, talkID string) codes.Code {
+		_, code, _ := s.api.closeTalk(ctx, &talkWatchRequest{TalkID: talkID})
+
+		return code
+	}
+
+	exportTalk := func(ctx context.Context, talkID string, reveal bool) (*talkExportResponse, codes.Code) {
+		resp, code, _ := s.api.exportTalk(ctx, &talkExportRequest{TalkID: talkID, Reveal: reveal})
+		if resp == nil {
+			return nil, code
+		}
+
+		return resp.(*talkExportResponse), code
+	}
+
+	// the attached servicer closes its own talk
+	talkID := s.createTalk(aliceID)
+
+	assert.Equal(t, codes.PermissionDenied, closeTalk(bobCtx, talkID))
+	assert.Equal(t, codes.OK, closeTalk(aliceCtx, talkID))
+	assert.Eventually(t, talkClosed(talkID), time.Second, time.Millisecond*10)
+	assert.Equal(t, codes.FailedPrecondition, closeTalk(aliceCtx, talkID))
+
+	// the talks of the others need the closeOthers permission
+	talkID = s.createTalk(aliceID)
+
+	assert.Equal(t, codes.PermissionDenied, closeTalk(bobCtx, talkID))
+	assert.Equal(t, codes.OK, closeTalk(samCtx, talkID))
+	assert.Eventually(t, talkClosed(talkID), time.Second, time.Millisecond*10)
+
+	// the export permission
+	_, code := exportTalk(aliceCtx, talkID, false)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	_, code = exportTalk(samCtx, talkID, false)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	// the redacted texts are exported masked unless revealed
+	const phoneText = "call 13812345678"
+
+	message := &talkinters.TalkMessageW{At: time.Now().Unix(), CustomerMessage: true,
+		Type: talkinters.TalkMessageTypeText, SenderID: 1, Text: phoneText}
+	assert.Nil(t, s.redactor.Redact(s.ctx, talkID, message))
+
+	_, err := s.m.AddTalkMessageWithID(s.ctx, talkID, message)
+	assert.Nil(t, err)
+
+	exportedText := func(reveal bool) string {
+		export, codeE := exportTalk(adaCtx, talkID, reveal)
+		assert.Equal(t, codes.OK, codeE)
+
+		for _, m := range export.Messages {
+			if m.Type == talkinters.TalkMessageTypeText && m.CustomerMessage {
+				assert.Nil(t, m.Data)
+
+				return m.Text
+			}
+		}
+
+		return ""
+	}
+
+	assert.Equal(t, "call *******5678", exportedText(false))
+	assert.Equal(t, phoneText, exportedText(true))
+
+	// the close event is recorded right after the talk is closed
+	closedBySam := func() bool {
+		export, code := exportTalk(adaCtx, talkID, false)
+		assert.Equal(t, codes.OK, code)
+		assert.Equal(t, talkID, export.TalkInfo.TalkID)
+
+		for _, message := range export.Messages {
+			if message.Text == "客服 sam 结束了会话" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	assert.Eventually(t, closedBySam, time.Second, time.Millisecond*10)
+}
+
+func TestServicerAttachTalkTakeOver(t *testing.T) {
+	s := newTestServicerAPI(t)
+
+	aliceID := s.register("alice", []string{"a1"}, defs.ServicerRoleAgent)
+	bobID := s.register("bob", []string{"a1"}, defs.ServicerRoleAgent)
+	samID := s.register("sam", []string{"a1"}, defs.ServicerRoleSupervisor)
+
+	talkID := s.createTalk(aliceID)
+
+	chBob := make(chan *talkpb.ServiceResponse, 100)
+	chSam := make(chan *talkpb.ServiceResponse, 100)
+
+	bob := controller.NewServicer(bobID, "bob", 2, "", chBob, []string{"a1"}, []string{"b1"},
+		defs.ServicerRoles{defs.AllActs: defs.ServicerRoleAgent})
+	sam := controller.NewServicer(samID, "sam", 3, "", chSam, []string{"a1"}, []string{"b1"},
+		defs.ServicerRoles{defs.AllActs: defs.ServicerRoleSupervisor})
+
+	assert.Nil(t, s.controller.InstallServicer(bob))
+	assert.Nil(t, s.controller.InstallServicer(sam))
+
+	// an agent can't take over the talk of another servicer
+	assert.Nil(t, s.controller.ServicerAttachTalk(bob, talkID))
+	assert.True(t, receivedResponse(chBob, func(resp *talkpb.ServiceResponse) bool {
+		return resp.GetNotify().GetMsg() == "talkAttachedByOthers"
+	}, time.Second))
+
+	servicerID, err := s.m.GetTalkServicerID(s.ctx, nil, nil, talkID)
+	assert.Nil(t, err)
+	assert.Equal(t, aliceID, servicerID)
+
+	// the transfer permission
+	assert.Nil(t, s.controller.ServicerAttachTalk(sam, talkID))
+	assert.True(t, receivedResponse(chSam, func(resp *talkpb.ServiceResponse) bool {
+		return resp.GetAttach().GetTalk().GetTalkId() == talkID && resp.GetAttach().GetAttachedServiceId() == samID
+	}, time.Second))
+
+	servicerID, err = s.m.GetTalkServicerID(s.ctx, nil, nil, talkID)
+	assert.Nil(t, err)
+	assert.Equal(t, samID, servicerID)
+}
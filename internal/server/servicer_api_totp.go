@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zservicer/talkbe/internal/defs"
+	"google.golang.org/grpc/codes"
+)
+
+// servicerTOTPRequest authenticates by the password rather than the token, the servicers who must enroll the
+// two-factor authentication can't log in before the enrollment.
+type servicerTOTPRequest struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+	Code     string `json:"code"` // a TOTP or recovery code, for the activation, the disabling and the re-enrollment
+}
+
+type servicerTOTPResetRequest struct {
+	UserID uint64 `json:"userID"`
+}
+
+type twoFactorRequireRequest struct {
+	ActID    string `json:"actID"`
+	Required bool   `json:"required"`
+}
+
+func (impl *ServicerAPIServer) enrollServicerTOTP(ctx context.Context, request *servicerTOTPRequest) (resp interface{}, code codes.Code, err error) {
+	user, code, err := impl.totpServicerUser(ctx, request)
+	if code != codes.OK {
+		return
+	}
+
+	enrollment, err := impl.twoFactor.Enroll(ctx, user.ID, request.Code)
+	if err != nil {
+		code = impl.totpFailed(ctx, request, err)
+
+		return
+	}
+
+	resp = enrollment
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) activateServicerTOTP(ctx context.Context, request *servicerTOTPRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Code == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	user, code, err := impl.totpServicerUser(ctx, request)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.twoFactor.Activate(ctx, user.ID, request.Code); err != nil {
+		code = impl.totpFailed(ctx, request, err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, user.ID, user.UserName, defs.AuditActionServicerTOTPEnable, user, "")
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) disableServicerTOTP(ctx context.Context, request *servicerTOTPRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Code == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	user, code, err := impl.totpServicerUser(ctx, request)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.twoFactor.Disable(ctx, user.ID, request.Code); err != nil {
+		code = impl.totpFailed(ctx, request, err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, user.ID, user.UserName, defs.AuditActionServicerTOTPDisable, user, "")
+
+	code = codes.OK
+
+	return
+}
+
+// resetServicerTOTP disables the two-factor authentication of a servicer who lost the authenticator and the
+// recovery codes.
+func (impl *ServicerAPIServer) resetServicerTOTP(ctx context.Context, request *servicerTOTPResetRequest) (resp interface{}, code codes.Code, err error) {
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.twoFactor.Reset(ctx, user.ID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerTOTPReset, user, "")
+
+	code = codes.OK
+
+	return
+}
+
+// requireServicerTOTP requires the two-factor authentication for all the servicers of an act, the servicers
+// who didn't enroll it can't log in until they do.
+func (impl *ServicerAPIServer) requireServicerTOTP(ctx context.Context, request *twoFactorRequireRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ActID == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	operatorID, operatorUserName, _, _, code, err := impl.permittedActIDs(ctx, defs.PermissionManageUsers,
+		[]string{request.ActID})
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.twoFactorPolicyM.SetTwoFactorRequired(ctx, request.ActID, request.Required); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               time.Now().Unix(),
+		ActID:            request.ActID,
+		OperatorID:       operatorID,
+		OperatorUserName: operatorUserName,
+		Action:           defs.AuditActionTwoFactorRequire,
+		Target:           "act:" + request.ActID,
+		Detail:           fmt.Sprintf("required:%t", request.Required),
+	})
+
+	code = codes.OK
+
+	return
+}
+
+// totpServicerUser verifies the password of the servicer, the attempts are guarded as the logins.
+func (impl *ServicerAPIServer) totpServicerUser(ctx context.Context, request *servicerTOTPRequest) (
+	user *defs.ServicerUser, code codes.Code, err error) {
+	if request.UserName == "" || request.Password == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	if code, err = checkLoginGuard(ctx, impl.loginGuard, request.UserName, peerIP(ctx)); code != codes.OK {
+		return
+	}
+
+	user, err = impl.userAdmin.VerifyServicerUserPassword(ctx, request.UserName, request.Password)
+	if err != nil {
+		code = impl.totpFailed(ctx, request, err)
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// totpFailed counts the wrong passwords and codes as login failures.
+func (impl *ServicerAPIServer) totpFailed(ctx context.Context, request *servicerTOTPRequest, err error) codes.Code {
+	if errors.Is(err, defs.ErrWrongPassword) || errors.Is(err, defs.ErrWrongTOTPCode) {
+		_ = impl.loginGuard.Fail(ctx, request.UserName, peerIP(ctx))
+	}
+
+	return codeFromError(err)
+}
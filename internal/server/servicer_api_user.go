@@ -0,0 +1,421 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/vo"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+)
+
+type servicerUsersRequest struct {
+	ActIDs  []string `json:"actIDs"`
+	Keyword string   `json:"keyword"` // matches the user names
+	Offset  int64    `json:"offset"`
+	Limit   int64    `json:"limit"`
+}
+
+type servicerUsersResponse struct {
+	Total int64                `json:"total"`
+	Users []*defs.ServicerUser `json:"users"`
+}
+
+type servicerDisableRequest struct {
+	UserID   uint64 `json:"userID"`
+	Disabled bool   `json:"disabled"`
+}
+
+type servicerDeleteRequest struct {
+	UserID uint64 `json:"userID"`
+}
+
+type servicerPasswordResetRequest struct {
+	UserID   uint64 `json:"userID"`
+	Password string `json:"password"`
+}
+
+type servicerUnlockRequest struct {
+	UserID uint64 `json:"userID"`
+}
+
+type servicerRoleSetRequest struct {
+	UserID uint64            `json:"userID"`
+	ActID  string            `json:"actID"` // "*" for all the acts of the servicer
+	Role   defs.ServicerRole `json:"role"`  // empty to remove the role in the act
+}
+
+type servicerPasswordChangeRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+	TOTPCode    string `json:"totpCode"` // required to return the new token if the two-factor authentication is enabled
+}
+
+type servicerPasswordChangeResponse struct {
+	Token string `json:"token,omitempty"`
+}
+
+const (
+	defaultServicerUsersLimit = 20
+	maxServicerUsersLimit     = 200
+)
+
+func (impl *ServicerAPIServer) servicerUsers(ctx context.Context, request *servicerUsersRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Offset < 0 {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	_, _, _, actIDs, code, err := impl.permittedActIDs(ctx, defs.PermissionManageUsers, request.ActIDs)
+	if code != codes.OK {
+		return
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultServicerUsersLimit
+	} else if limit > maxServicerUsersLimit {
+		limit = maxServicerUsersLimit
+	}
+
+	users, total, err := impl.userAdmin.QueryServicerUsers(ctx, actIDs, request.Keyword, request.Offset, limit)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	resp = &servicerUsersResponse{
+		Total: total,
+		Users: users,
+	}
+	code = codes.OK
+
+	return
+}
+
+// disableServicer disables or enables a servicer, the live streams of a disabled servicer are kicked out.
+func (impl *ServicerAPIServer) disableServicer(ctx context.Context, request *servicerDisableRequest) (resp interface{}, code codes.Code, err error) {
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.userAdmin.SetServicerUserDisabled(ctx, user.ID, request.Disabled); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	action := defs.AuditActionServicerEnable
+	if request.Disabled {
+		action = defs.AuditActionServicerDisable
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, action, user, "")
+
+	if request.Disabled {
+		if err = impl.controller.KickServicer(user.ID, "", vo.KickOutReasonDisabled); err != nil {
+			code = codes.Unavailable
+
+			return
+		}
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) deleteServicer(ctx context.Context, request *servicerDeleteRequest) (resp interface{}, code codes.Code, err error) {
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.userAdmin.DeleteServicerUser(ctx, user.ID); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	_ = impl.userTokenHelper.RevokeUserSessions(ctx, user.ID)
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerDelete, user, "")
+
+	if err = impl.controller.KickServicer(user.ID, "", vo.KickOutReasonDeleted); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func (impl *ServicerAPIServer) resetServicerPassword(ctx context.Context, request *servicerPasswordResetRequest) (resp interface{}, code codes.Code, err error) {
+	if request.Password == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.userAdmin.ResetServicerUserPassword(ctx, user.ID, request.Password); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerPasswordReset, user, "")
+
+	// the tokens are invalid with the old password
+	if err = impl.revokeAllServicerSessions(ctx, user.ID); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// unlockServicer clears the login failures of a servicer locked out by the failures.
+func (impl *ServicerAPIServer) unlockServicer(ctx context.Context, request *servicerUnlockRequest) (resp interface{}, code codes.Code, err error) {
+	operatorID, operatorUserName, _, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	if err = impl.loginGuard.Unlock(ctx, user.UserName); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerUnlock, user, "")
+
+	code = codes.OK
+
+	return
+}
+
+// setServicerRole sets the role of a servicer in an act, the operator grants the roles he outranks only,
+// the owners grant any role. The live streams are kicked out to reconnect with the new roles.
+func (impl *ServicerAPIServer) setServicerRole(ctx context.Context, request *servicerRoleSetRequest) (resp interface{}, code codes.Code, err error) {
+	if request.ActID == "" || (request.Role != "" && !request.Role.Valid()) {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	operatorID, operatorUserName, roles, user, code, err := impl.managedServicerUser(ctx, request.UserID)
+	if code != codes.OK {
+		return
+	}
+
+	actIDs := user.ActIDs
+
+	if request.ActID != defs.AllActs {
+		if len(user.ActIDs) > 0 && !slices.Contains(user.ActIDs, request.ActID) {
+			code = codes.FailedPrecondition
+
+			return
+		}
+
+		actIDs = []string{request.ActID}
+	}
+
+	if request.Role != "" && !roles.CanManage(actIDs, defs.ServicerRoles{defs.AllActs: request.Role}) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	if err = impl.userAdmin.SetServicerUserRole(ctx, user.ID, request.ActID, request.Role); err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.auditServicerUser(ctx, operatorID, operatorUserName, defs.AuditActionServicerRoleSet, user,
+		fmt.Sprintf("actID:%s role:%s", request.ActID, request.Role))
+
+	if err = impl.controller.KickServicer(user.ID, "", vo.KickOutReasonRoleChanged); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// changeServicerPassword changes the password of the servicer himself, the other tokens of the servicer are
+// invalidated and a new token is returned.
+func (impl *ServicerAPIServer) changeServicerPassword(ctx context.Context, request *servicerPasswordChangeRequest) (resp interface{}, code codes.Code, err error) {
+	if request.OldPassword == "" || request.NewPassword == "" {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	// the only call accepted before the required password change
+	userID, userName, err := impl.userTokenHelper.ExtractUserForPasswordChange(ctx)
+	if err != nil {
+		code = codes.Unauthenticated
+
+		return
+	}
+
+	// the wrong old passwords are guarded as the logins
+	if code, err = checkLoginGuard(ctx, impl.loginGuard, userName, peerIP(ctx)); code != codes.OK {
+		return
+	}
+
+	if err = impl.userAdmin.ChangeServicerUserPassword(ctx, userID, request.OldPassword, request.NewPassword); err != nil {
+		if errors.Is(err, defs.ErrWrongPassword) {
+			_ = impl.loginGuard.Fail(ctx, userName, peerIP(ctx))
+		}
+
+		code = codeFromError(err)
+
+		return
+	}
+
+	impl.audit(ctx, &defs.AuditLog{
+		At:               time.Now().Unix(),
+		OperatorID:       userID,
+		OperatorUserName: userName,
+		Action:           defs.AuditActionServicerPasswordChange,
+		Target:           fmt.Sprintf("servicer:%d:%s", userID, userName),
+	})
+
+	if err = impl.revokeAllServicerSessions(ctx, userID); err != nil {
+		code = codes.Unavailable
+
+		return
+	}
+
+	_, token, _, code, err := servicerLogin(ctx, impl.user, impl.userTokenHelper, impl.twoFactor, &servicerCredentials{
+		UserName: userName,
+		Password: request.NewPassword,
+		TOTPCode: request.TOTPCode,
+		IP:       peerIP(ctx),
+	})
+	// the password is changed, the servicer logs in again with a TOTP code
+	if errors.Is(err, defs.ErrTOTPRequired) {
+		token, code, err = "", codes.OK, nil
+	}
+
+	if code != codes.OK {
+		return
+	}
+
+	resp = &servicerPasswordChangeResponse{
+		Token: token,
+	}
+
+	return
+}
+
+// revokeAllServicerSessions revokes the sessions of a servicer and kicks out the live streams.
+func (impl *ServicerAPIServer) revokeAllServicerSessions(ctx context.Context, userID uint64) error {
+	if err := impl.userTokenHelper.RevokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	return impl.controller.KickServicer(userID, "", vo.KickOutReasonSessionRevoked)
+}
+
+// managedServicerUser checks the user manages the users in all the acts of the servicer and outranks the servicer
+// there, a servicer can't manage himself.
+func (impl *ServicerAPIServer) managedServicerUser(ctx context.Context, userID uint64) (operatorID uint64,
+	operatorUserName string, roles defs.ServicerRoles, user *defs.ServicerUser, code codes.Code, err error) {
+	if userID == 0 {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	operatorID, operatorUserName, roles, allowed, code, err := impl.permittedActIDs(ctx, defs.PermissionManageUsers, nil)
+	if code != codes.OK {
+		return
+	}
+
+	if operatorID == userID {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	user, err = impl.userAdmin.GetServicerUser(ctx, userID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	if !servicerUserInScope(allowed, user) || !roles.CanManage(user.ActIDs, user.Roles) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+// servicerUserInScope returns true if all the acts of the servicer are allowed, only the unscoped managers manage
+// the servicers without acts.
+func servicerUserInScope(allowed []string, user *defs.ServicerUser) bool {
+	return actIDsInScope(allowed, user.ActIDs)
+}
+
+func actIDsInScope(allowed, actIDs []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	if len(actIDs) == 0 {
+		return false
+	}
+
+	for _, actID := range actIDs {
+		if !slices.Contains(allowed, actID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (impl *ServicerAPIServer) auditServicerUser(ctx context.Context, operatorID uint64, operatorUserName, action string,
+	user *defs.ServicerUser, detail string) {
+	log := &defs.AuditLog{
+		At:               time.Now().Unix(),
+		OperatorID:       operatorID,
+		OperatorUserName: operatorUserName,
+		Action:           action,
+		Target:           fmt.Sprintf("servicer:%d:%s", user.ID, user.UserName),
+		Detail:           detail,
+	}
+
+	if len(user.ActIDs) > 0 {
+		log.ActID = user.ActIDs[0]
+	}
+
+	impl.audit(ctx, log)
+}
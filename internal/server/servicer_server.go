@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -13,12 +14,16 @@ import (
 	"google.golang.org/grpc/codes"
 )
 
-func NewServicerServer(controller *controller.ServicerController, userTokenHelper defs.ServicerUserTokenHelper, model defs.ModelEx, logger l.Wrapper) talkpb.ServiceTalkServiceServer {
+func NewServicerServer(controller *controller.ServicerController, userTokenHelper defs.ServicerUserTokenHelper, model defs.ModelEx,
+	imageProcessor defs.ImageProcessor, messageReviser defs.MessageReviser,
+	redactor defs.Redactor, moderator defs.Moderator, flagM defs.ModerationFlagModel,
+	rateLimiter defs.RateLimiter, logger l.Wrapper) talkpb.ServiceTalkServiceServer {
 	if logger == nil {
 		logger = l.NewNopLoggerWrapper()
 	}
 
-	if controller == nil || userTokenHelper == nil || model == nil {
+	if controller == nil || userTokenHelper == nil || model == nil || imageProcessor == nil || messageReviser == nil || redactor == nil ||
+		moderator == nil || flagM == nil || rateLimiter == nil {
 		logger.Fatal("invalid input args")
 	}
 
@@ -27,6 +32,12 @@ func NewServicerServer(controller *controller.ServicerController, userTokenHelpe
 		controller:      controller,
 		userTokenHelper: userTokenHelper,
 		model:           model,
+		imageProcessor:  imageProcessor,
+		messageReviser:  messageReviser,
+		redactor:        redactor,
+		moderator:       moderator,
+		flagM:           flagM,
+		rateLimiter:     rateLimiter,
 	}
 }
 
@@ -36,6 +47,12 @@ type servicerServerImpl struct {
 	logger          l.Wrapper
 	userTokenHelper defs.ServicerUserTokenHelper
 	model           defs.ModelEx
+	imageProcessor  defs.ImageProcessor
+	messageReviser  defs.MessageReviser
+	redactor        defs.Redactor
+	moderator       defs.Moderator
+	flagM           defs.ModerationFlagModel
+	rateLimiter     defs.RateLimiter
 
 	controller *controller.ServicerController
 }
@@ -47,7 +64,19 @@ func (impl *servicerServerImpl) Service(server talkpb.ServiceTalkService_Service
 		return gRPCMessageError(codes.InvalidArgument, "noServerStream")
 	}
 
-	_, userID, userName, _, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(server.Context(), false)
+	_, userID, userName, roles, actIDs, bizIDs, err := impl.userTokenHelper.ExtractUserFromGRPCContext(server.Context(), false)
+	if err != nil {
+		return gRPCError(codes.Unauthenticated, err)
+	}
+
+	// the servicer attaches and talks in the acts with the permission only
+	actIDs, ok := roles.PermittedActIDs(actIDs, defs.PermissionAttach)
+	if !ok {
+		return gRPCMessageError(codes.PermissionDenied, "noAttachPermission")
+	}
+
+	// the streams of a revoked session are kicked out
+	session, err := impl.userTokenHelper.ExtractSessionFromGRPCContext(server.Context())
 	if err != nil {
 		return gRPCError(codes.Unauthenticated, err)
 	}
@@ -66,7 +95,7 @@ func (impl *servicerServerImpl) Service(server talkpb.ServiceTalkService_Service
 
 	chSendMessage := make(chan *talkpb.ServiceResponse, 100)
 
-	servicer := controller.NewServicer(userID, uniqueID, chSendMessage, actIDs, bizIDs)
+	servicer := controller.NewServicer(userID, userName, uniqueID, session.ID, chSendMessage, actIDs, bizIDs, roles)
 
 	err = impl.controller.InstallServicer(servicer)
 	if err != nil {
@@ -138,6 +167,27 @@ func (impl *servicerServerImpl) serverReceiveRoutine(server talkpb.ServiceTalkSe
 			break
 		}
 
+		if message := request.GetMessage(); message != nil {
+			decision := impl.rateLimiter.Take(server.Context(), false, userID, message.GetTalkId())
+			if decision == defs.RateLimitKick {
+				logger.Warn("RateLimitKickOut")
+
+				servicer.Remove(vo.RejectReasonRateLimited)
+
+				break
+			}
+
+			if decision == defs.RateLimitReject {
+				_ = servicer.SendMessage(vo.ServiceNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+					SeqID:  message.GetMessage().GetSeqId(),
+					TalkID: message.GetTalkId(),
+					Reason: vo.RejectReasonRateLimited,
+				}))
+
+				continue
+			}
+		}
+
 		if request.GetAttachedTalks() != nil {
 			err = impl.controller.ServicerQueryAttachedTalks(servicer)
 			if err != nil {
@@ -160,6 +210,8 @@ func (impl *servicerServerImpl) serverReceiveRoutine(server talkpb.ServiceTalkSe
 
 				continue
 			}
+		} else if command, ok := vo.MessageCommandFromPb(request.GetMessage().GetMessage()); ok {
+			impl.reviseMessage(server.Context(), servicer, request.GetMessage().GetTalkId(), userID, userName, command, logger)
 		} else if message := request.GetMessage(); message != nil {
 			dbMessage := vo.TalkMessageWPb2Db(message.GetMessage())
 			dbMessage.At = time.Now().Unix()
@@ -167,19 +219,73 @@ func (impl *servicerServerImpl) serverReceiveRoutine(server talkpb.ServiceTalkSe
 			dbMessage.SenderID = userID
 			dbMessage.SenderUserName = userName
 
-			err = impl.model.AddTalkMessage(server.Context(), message.GetTalkId(), dbMessage)
+			if err = vo.ValidateTalkMessage(message.GetTalkId(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ValidateTalkMessageFailed")
+				rejectServicerMessage(servicer, message.GetTalkId(), message.GetMessage().GetSeqId(), "", err)
+
+				continue
+			}
+
+			if err = impl.imageProcessor.Process(server.Context(), message.GetTalkId(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ProcessImageFailed")
+				rejectServicerMessage(servicer, message.GetTalkId(), message.GetMessage().GetSeqId(), "", err)
+
+				continue
+			}
+
+			if err = checkQuoteMessage(server.Context(), impl.model, message.GetTalkId(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("CheckQuoteMessageFailed")
+				rejectServicerMessage(servicer, message.GetTalkId(), message.GetMessage().GetSeqId(), "", err)
+
+				continue
+			}
+
+			var moderation *defs.ModerationResult
+
+			if moderation, err = moderateMessage(server.Context(), impl.moderator, impl.redactor, message.GetTalkId(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("ModerateMessageFailed")
+
+				continue
+			}
+
+			if moderation.Action == defs.ModerationActionReject {
+				_ = servicer.SendMessage(vo.ServiceNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+					SeqID:  message.GetMessage().GetSeqId(),
+					TalkID: message.GetTalkId(),
+					Reason: moderation.Reason,
+				}))
+
+				continue
+			}
+
+			if err = impl.redactor.Redact(server.Context(), message.GetTalkId(), dbMessage); err != nil {
+				logger.WithFields(l.ErrorField(err)).Error("RedactFailed")
+
+				continue
+			}
+
+			var messageID string
+
+			messageID, err = impl.model.AddTalkMessageWithID(server.Context(), message.GetTalkId(), dbMessage)
 			if err != nil {
 				logger.WithFields(l.ErrorField(err)).Error("AddTalkMessageFailed")
 
 				break
 			}
 
+			if moderation.Action == defs.ModerationActionFlag {
+				if err = flagMessage(server.Context(), impl.model, impl.flagM, message.GetTalkId(), messageID,
+					dbMessage, moderation.Reason); err != nil {
+					logger.WithFields(l.ErrorField(err)).Error("FlagMessageFailed")
+				}
+			}
+
 			var seqID uint64
 			if message.GetMessage() != nil {
 				seqID = message.GetMessage().GetSeqId()
 			}
 
-			err = impl.controller.ServicerMessageIncoming(servicer, seqID, message.GetTalkId(), dbMessage)
+			err = impl.controller.ServicerMessageIncoming(servicer, seqID, message.GetTalkId(), messageID, dbMessage)
 			if err != nil {
 				logger.WithFields(l.ErrorField(err)).Error("CustomerMessageIncomingFailed")
 
@@ -206,3 +312,52 @@ func (impl *servicerServerImpl) serverReceiveRoutine(server talkpb.ServiceTalkSe
 		}
 	}
 }
+
+func (impl *servicerServerImpl) reviseMessage(ctx context.Context, servicer defs.Servicer, talkID string, userID uint64,
+	userName string, command *defs.MessageCommand, logger l.Wrapper) {
+	moderation, err := moderateEdit(ctx, impl.moderator, impl.redactor, talkID, userID, false, command)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("ModerateEditFailed")
+
+		return
+	}
+
+	if moderation.Action == defs.ModerationActionReject {
+		_ = servicer.SendMessage(vo.ServiceNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+			TalkID:    talkID,
+			MessageID: command.MessageID,
+			Reason:    moderation.Reason,
+		}))
+
+		return
+	}
+
+	revision, err := impl.messageReviser.Revise(ctx, talkID, userID, false, command)
+	if err != nil {
+		logger.WithFields(l.ErrorField(err), l.StringField("command", command.Command)).Error("ReviseMessageFailed")
+		rejectServicerMessage(servicer, talkID, 0, command.MessageID, err)
+
+		return
+	}
+
+	if moderation.Action == defs.ModerationActionFlag && revision.Edit != nil {
+		if err = flagMessage(ctx, impl.model, impl.flagM, talkID, revision.MessageID,
+			editMessage(revision, userName), moderation.Reason); err != nil {
+			logger.WithFields(l.ErrorField(err)).Error("FlagMessageFailed")
+		}
+	}
+
+	if err = impl.controller.ServicerMessageRevised(talkID, revision); err != nil {
+		logger.WithFields(l.ErrorField(err)).Error("ServicerMessageRevisedFailed")
+	}
+}
+
+// rejectServicerMessage tells the servicer why the message or the command of the message id is rejected.
+func rejectServicerMessage(servicer defs.Servicer, talkID string, seqID uint64, messageID string, err error) {
+	_ = servicer.SendMessage(vo.ServiceNotifyResponse(vo.NotifyKindRejected, &vo.MessageRejected{
+		SeqID:     seqID,
+		TalkID:    talkID,
+		MessageID: messageID,
+		Reason:    vo.MessageRejectReason(err),
+	}))
+}
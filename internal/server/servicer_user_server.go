@@ -2,32 +2,74 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"time"
 
 	"github.com/sbasestarter/bizinters/userinters"
-	"github.com/sbasestarter/userlib/authenticator/userpass"
 	userpassmanager "github.com/sbasestarter/userlib/manager/userpass"
+	"github.com/sgostarter/i/commerr"
 	"github.com/sgostarter/libeasygo/crypt/simencrypt"
 	"github.com/zservicer/protorepo/gens/talkpb"
 	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 )
 
-func NewServicerUserServer(userManager userpassmanager.Manager, user userinters.UserCenter, tokenHelper defs.ServicerUserTokenHelper) talkpb.ServicerUserServicerServer {
+const (
+	clientIPKeyOnMetadata = "x-real-ip"
+	deviceKeyOnMetadata   = "x-device"
+
+	// the login request can't carry the second step, the TOTP code and the continue id of the first step
+	// are given by the metadata, the continue id is returned by the trailer of the totpRequired error
+	totpCodeKeyOnMetadata   = "totp-code"
+	continueIDKeyOnMetadata = "continue-id"
+
+	// the register request can't carry the invitation code either
+	invitationCodeKeyOnMetadata = "invitation-code"
+
+	// the trailer of a successful login tells the token is only for changing the password
+	passwordChangeRequiredKeyOnMetadata = "password-change-required"
+)
+
+type servicerCredentials struct {
+	UserName   string
+	Password   string
+	TOTPCode   string
+	ContinueID string // the signed continue id of the first step
+	IP         string // the client ip of the session
+}
+
+func NewServicerUserServer(userManager userpassmanager.Manager, user userinters.UserCenter, tokenHelper defs.ServicerUserTokenHelper,
+	passwordPolicy defs.PasswordPolicy, loginGuard defs.LoginGuard, twoFactor defs.TwoFactorAuth,
+	invitations defs.ServicerInvitations, trustedProxies TrustedProxies) talkpb.ServicerUserServicerServer {
 	return &servicerUserServerImpl{
-		userManager: userManager,
-		user:        user,
-		tokenHelper: tokenHelper,
+		userManager:    userManager,
+		user:           user,
+		tokenHelper:    tokenHelper,
+		passwordPolicy: passwordPolicy,
+		loginGuard:     loginGuard,
+		twoFactor:      twoFactor,
+		invitations:    invitations,
+		trustedProxies: trustedProxies,
 	}
 }
 
 type servicerUserServerImpl struct {
 	talkpb.UnimplementedServicerUserServicerServer
 
-	userManager userpassmanager.Manager
-	user        userinters.UserCenter
-	tokenHelper defs.ServicerUserTokenHelper
+	userManager    userpassmanager.Manager
+	user           userinters.UserCenter
+	tokenHelper    defs.ServicerUserTokenHelper
+	passwordPolicy defs.PasswordPolicy
+	loginGuard     defs.LoginGuard
+	twoFactor      defs.TwoFactorAuth
+	invitations    defs.ServicerInvitations
+	trustedProxies TrustedProxies
 }
 
 func (impl *servicerUserServerImpl) Register(ctx context.Context, request *talkpb.RegisterRequest) (*talkpb.RegisterResponse, error) {
@@ -43,18 +85,39 @@ func (impl *servicerUserServerImpl) Register(ctx context.Context, request *talkp
 	}, nil
 }
 
+// Login verifies the password and, for the users who enabled the two-factor authentication, a TOTP code. The code
+// is given with the password, or in a second step with the continue id of the first step instead of the password.
 func (impl *servicerUserServerImpl) Login(ctx context.Context, request *talkpb.LoginRequest) (*talkpb.LoginResponse, error) {
-	if request == nil || request.GetUserName() == "" || request.GetPassword() == "" {
+	if request == nil || request.GetUserName() == "" {
 		return nil, gRPCMessageError(codes.InvalidArgument, "")
 	}
 
-	userID, token, code, err := impl.login(ctx, request.GetUserName(), request.GetPassword())
+	credentials := &servicerCredentials{
+		UserName:   request.GetUserName(),
+		Password:   request.GetPassword(),
+		TOTPCode:   incomingMetadataValue(ctx, totpCodeKeyOnMetadata),
+		ContinueID: incomingMetadataValue(ctx, continueIDKeyOnMetadata),
+		IP:         impl.trustedProxies.clientIP(ctx),
+	}
+
+	if credentials.Password == "" && (credentials.ContinueID == "" || credentials.TOTPCode == "") {
+		return nil, gRPCMessageError(codes.InvalidArgument, "")
+	}
+
+	userID, token, continueID, code, err := impl.guardedLogin(ctx, credentials)
 	if code != codes.OK {
+		if continueID != "" {
+			_ = grpc.SetTrailer(ctx, metadata.Pairs(continueIDKeyOnMetadata, continueID))
+		}
+
 		return nil, gRPCError(code, err)
 	}
 
 	// nolint: dogsled
-	_, _, _, _, actIDs, bizIDs, _ := impl.tokenHelper.ExplainToken(ctx, token, false)
+	_, _, _, _, actIDs, bizIDs, err := impl.tokenHelper.ExplainToken(ctx, token, false)
+	if errors.Is(err, defs.ErrPasswordChangeRequired) {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(passwordChangeRequiredKeyOnMetadata, "true"))
+	}
 
 	return &talkpb.LoginResponse{
 		Token:    token,
@@ -77,45 +140,169 @@ func (impl *servicerUserServerImpl) register(ctx context.Context, request *talkp
 		return
 	}
 
+	if err = impl.passwordPolicy.Check(request.GetPassword()); err != nil {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	var invitation *defs.ServicerInvitation
+
+	if invitationCode := incomingMetadataValue(ctx, invitationCodeKeyOnMetadata); invitationCode != "" {
+		invitation, err = impl.invitations.UseInvitation(ctx, invitationCode, request.GetUserName())
+		if err != nil {
+			code = codeFromError(err)
+
+			return
+		}
+	} else if !impl.invitations.OpenRegistration() {
+		code = codes.PermissionDenied
+		err = defs.ErrInvitationRequired
+
+		return
+	}
+
 	userID, err = impl.userManager.Register(ctx, request.GetUserName(), request.GetPassword())
 	if err != nil {
+		if invitation != nil {
+			_ = impl.invitations.ReleaseInvitation(ctx, invitation)
+		}
+
 		code = codes.Internal
 
 		return
 	}
 
-	_ = impl.userManager.UpdateUserAllExData(ctx, userID, impl.tokenHelper.GenExData([]string{
+	// the open registrations get their own acts
+	exData := impl.tokenHelper.GenExData([]string{
 		strconv.FormatUint(userID, 10),
 	}, []string{
 		"default",
-	}))
+	})
 
-	userID, token, code, err = impl.login(ctx, request.GetUserName(), request.GetPassword())
-	if code != codes.OK {
+	if invitation != nil {
+		exData = impl.tokenHelper.GenExData(invitation.ActIDs, invitation.BizIDs)
+
+		for key, val := range impl.tokenHelper.GenRolesExData(defs.ServicerRoles{defs.AllActs: invitation.Role}) {
+			exData[key] = val
+		}
+	}
+
+	_ = updateServicerExData(ctx, impl.userManager, userID, exData)
+
+	userID, token, _, code, err = servicerLogin(ctx, impl.user, impl.tokenHelper, impl.twoFactor, &servicerCredentials{
+		UserName: request.GetUserName(),
+		Password: request.GetPassword(),
+		IP:       impl.trustedProxies.clientIP(ctx),
+	})
+
+	return
+}
+
+// guardedLogin rejects the attempts during the delays or the lockouts after failures, the wrong TOTP codes are
+// failures too.
+func (impl *servicerUserServerImpl) guardedLogin(ctx context.Context, credentials *servicerCredentials) (
+	userID uint64, token, continueID string, code codes.Code, err error) {
+	userName := credentials.UserName
+	ip := credentials.IP
+
+	if code, err = checkLoginGuard(ctx, impl.loginGuard, userName, ip); code != codes.OK {
 		return
 	}
 
+	userID, token, continueID, code, err = servicerLogin(ctx, impl.user, impl.tokenHelper, impl.twoFactor, credentials)
+
+	switch code {
+	case codes.OK:
+		_ = impl.loginGuard.Succeed(ctx, userName, ip)
+	case codes.Unauthenticated:
+		_ = impl.loginGuard.Fail(ctx, userName, ip)
+	}
+
 	return
 }
 
-func (impl *servicerUserServerImpl) login(ctx context.Context, userName, password string) (
-	userID uint64, token string, code codes.Code, err error) {
-	authenticator, err := userpass.NewAuthenticator(userName, password, impl.userManager)
+func checkLoginGuard(ctx context.Context, loginGuard defs.LoginGuard, userName, ip string) (code codes.Code, err error) {
+	retryAfter, err := loginGuard.Check(ctx, userName, ip)
 	if err != nil {
 		code = codes.Internal
 
 		return
 	}
 
-	resp, err := impl.user.Login(ctx, &userinters.LoginRequest{
-		ContinueID: 0,
-		Authenticators: []userinters.Authenticator{
-			authenticator,
-		},
+	if retryAfter > 0 {
+		code = codes.ResourceExhausted
+		err = fmt.Errorf("%w: retryAfter %ds", commerr.ErrResourceExhausted, int64(math.Ceil(retryAfter.Seconds())))
+
+		return
+	}
+
+	code = codes.OK
+
+	return
+}
+
+func incomingMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+
+	return ""
+}
+
+// clientDevice returns the device forwarded by the gateways, or the user agent of the grpc client.
+func clientDevice(ctx context.Context) string {
+	if device := incomingMetadataValue(ctx, deviceKeyOnMetadata); device != "" {
+		return device
+	}
+
+	return incomingMetadataValue(ctx, "user-agent")
+}
+
+// servicerLogin returns codes.FailedPrecondition with defs.ErrTOTPRequired and the signed continue id if the
+// password is verified but a TOTP code is required.
+func servicerLogin(ctx context.Context, user userinters.UserCenter, tokenHelper defs.ServicerUserTokenHelper,
+	twoFactor defs.TwoFactorAuth, credentials *servicerCredentials) (userID uint64, token, continueID string,
+	code codes.Code, err error) {
+	request := &userinters.LoginRequest{
 		TokenLiveDuration: time.Hour * 24 * 7,
-	})
+	}
+
+	if credentials.ContinueID != "" {
+		request.ContinueID, err = twoFactor.VerifyContinueID(credentials.UserName, credentials.ContinueID)
+		if err != nil {
+			code = codes.Unauthenticated
+
+			return
+		}
+	}
+
+	if credentials.Password != "" {
+		request.Authenticators = append(request.Authenticators, tokenHelper.NewAuthenticator(credentials.UserName,
+			credentials.Password))
+	}
+
+	if credentials.TOTPCode != "" {
+		request.Authenticators = append(request.Authenticators, twoFactor.NewAuthenticator(credentials.UserName,
+			credentials.TOTPCode))
+	}
+
+	resp, err := user.Login(ctx, request)
 	if err != nil {
-		code = codes.Internal
+		// the user center rejects the wrong passwords and codes
+		switch {
+		case errors.Is(err, commerr.ErrReject):
+			code = codes.Unauthenticated
+		case errors.Is(err, defs.ErrTOTPEnrollmentRequired):
+			code = codes.FailedPrecondition
+		default:
+			code = codes.Internal
+		}
 
 		return
 	}
@@ -123,6 +310,35 @@ func (impl *servicerUserServerImpl) login(ctx context.Context, userName, passwor
 	if resp.Status != userinters.LoginStatusSuccess {
 		code = codes.Unauthenticated
 
+		// the continue id is expired if the password is required again
+		if slices.Contains(resp.RequiredOrMethods, defs.AuthMethodNameTOTP) {
+			continueID = twoFactor.SignContinueID(credentials.UserName, resp.ContinueID)
+			code = codes.FailedPrecondition
+			err = defs.ErrTOTPRequired
+		}
+
+		return
+	}
+
+	session, err := tokenHelper.StartSession(ctx, resp.Token, clientDevice(ctx), credentials.IP)
+	if err != nil {
+		code = codes.Internal
+
+		return
+	}
+
+	// the disabled users are rejected by the token explaining, the token of a user who must change the password
+	// is only accepted for the change
+	// nolint: dogsled
+	_, _, _, _, _, _, err = tokenHelper.ExplainToken(ctx, resp.Token, false)
+	if errors.Is(err, defs.ErrPasswordChangeRequired) {
+		err = nil
+	}
+
+	if err != nil {
+		_ = tokenHelper.RevokeSession(ctx, session.ID)
+		code = codes.PermissionDenied
+
 		return
 	}
 
@@ -187,6 +403,8 @@ func (impl *servicerUserServerImpl) SetPermissions(ctx context.Context, request
 	return &talkpb.Empty{}, nil
 }
 
+// setPermissions sets the acts and the bizs of a servicer, the operator manages the users in the current and the
+// new acts of the servicer and outranks the servicer there.
 func (impl *servicerUserServerImpl) setPermissions(ctx context.Context, request *talkpb.SetPermissionsRequest) (code codes.Code, err error) {
 	code = codes.Unknown
 
@@ -201,14 +419,15 @@ func (impl *servicerUserServerImpl) setPermissions(ctx context.Context, request
 		return
 	}
 
-	_, _, _, admin, _, _, err := impl.tokenHelper.ExtractUserFromGRPCContext(ctx, false)
+	_, operatorID, _, roles, allowedActIDs, allowedBizIDs, err := impl.tokenHelper.ExtractUserFromGRPCContext(ctx, false)
 	if err != nil {
 		code = codes.Unauthenticated
 
 		return
 	}
 
-	if !admin {
+	permitted, ok := roles.PermittedActIDs(allowedActIDs, defs.PermissionManageUsers)
+	if !ok {
 		code = codes.PermissionDenied
 
 		return
@@ -219,7 +438,29 @@ func (impl *servicerUserServerImpl) setPermissions(ctx context.Context, request
 		return
 	}
 
-	err = impl.userManager.UpdateUserAllExData(ctx, userID, impl.tokenHelper.GenExData(request.GetActIds(),
+	if userID == operatorID {
+		code = codes.InvalidArgument
+
+		return
+	}
+
+	actIDs, userRoles, err := impl.tokenHelper.GetServicerRoles(ctx, userID)
+	if err != nil {
+		code = codeFromError(err)
+
+		return
+	}
+
+	// the operator manages the servicer in both the current and the new acts
+	if !actIDsInScope(permitted, actIDs) || !actIDsInScope(permitted, request.GetActIds()) ||
+		!actIDsInScope(allowedBizIDs, request.GetBizIds()) || !roles.CanManage(actIDs, userRoles) ||
+		!roles.CanManage(request.GetActIds(), userRoles) {
+		code = codes.PermissionDenied
+
+		return
+	}
+
+	err = updateServicerExData(ctx, impl.userManager, userID, impl.tokenHelper.GenExData(request.GetActIds(),
 		request.GetBizIds()))
 	if err != nil {
 		return
@@ -230,6 +471,19 @@ func (impl *servicerUserServerImpl) setPermissions(ctx context.Context, request
 	return
 }
 
+// updateServicerExData updates the keys one by one, the other keys such as the disabled flag and the token
+// version are kept.
+func updateServicerExData(ctx context.Context, userManager userpassmanager.Manager, userID uint64,
+	exData map[string]interface{}) error {
+	for key, val := range exData {
+		if err := userManager.UpdateUserExData(ctx, userID, key, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (impl *servicerUserServerImpl) UserIDS2N(id string) (uint64, error) {
 	return simencrypt.DecryptUint64(id)
 }
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// TrustedProxies are the gateways and the reverse proxies in front of the servers, the client ips forwarded by
+// the others are ignored because any client can set them.
+type TrustedProxies []*net.IPNet
+
+// NewTrustedProxies parses the ips and the cidrs of the proxies, the loopback addresses are trusted if none is given.
+func NewTrustedProxies(proxies []string) (TrustedProxies, error) {
+	if len(proxies) == 0 {
+		proxies = []string{"127.0.0.0/8", "::1/128"}
+	}
+
+	trustedProxies := make(TrustedProxies, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		trustedProxies = append(trustedProxies, ipNet)
+	}
+
+	return trustedProxies, nil
+}
+
+func (proxies TrustedProxies) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range proxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the client ip forwarded by the trusted gateways, or the peer address.
+func (proxies TrustedProxies) clientIP(ctx context.Context) string {
+	host := peerIP(ctx)
+
+	if !proxies.trusted(host) {
+		return host
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ips := md.Get(clientIPKeyOnMetadata); len(ips) > 0 && ips[0] != "" {
+			return ips[0]
+		}
+	}
+
+	return host
+}
+
+// httpClientIP returns the client ip given by the trusted proxies, or the remote address. The proxies append
+// the addresses to X-Forwarded-For, so the first untrusted one from the right is the client.
+func (proxies TrustedProxies) httpClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !proxies.trusted(host) {
+		return host
+	}
+
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+
+	ips := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for idx := len(ips) - 1; idx >= 0; idx-- {
+		ip := strings.TrimSpace(ips[idx])
+		if ip == "" {
+			continue
+		}
+
+		host = ip
+
+		if !proxies.trusted(ip) {
+			break
+		}
+	}
+
+	return host
+}
+
+// peerIP returns the address of the grpc peer.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
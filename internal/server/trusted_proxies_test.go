@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestTrustedProxiesHTTPClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	assert.Nil(t, err)
+
+	_, err = NewTrustedProxies([]string{"proxy"})
+	assert.NotNil(t, err)
+
+	r := httptest.NewRequest("POST", "/login", nil)
+
+	// the headers of the untrusted peers are spoofed
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.Header.Set("X-Real-IP", "5.6.7.8")
+	r.Header.Set("X-Forwarded-For", "5.6.7.8")
+	assert.Equal(t, "1.2.3.4", proxies.httpClientIP(r))
+
+	r.RemoteAddr = "10.0.0.1:5678"
+	assert.Equal(t, "5.6.7.8", proxies.httpClientIP(r))
+
+	// the client prepends a spoofed address, the first untrusted one from the right is the client
+	r.Header.Del("X-Real-IP")
+	r.Header.Set("X-Forwarded-For", "9.9.9.9, 1.2.3.4, 192.168.1.1")
+	assert.Equal(t, "1.2.3.4", proxies.httpClientIP(r))
+
+	r.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.0.0.1", proxies.httpClientIP(r))
+
+	// the loopback addresses by default
+	proxies, err = NewTrustedProxies(nil)
+	assert.Nil(t, err)
+
+	r.RemoteAddr = "[::1]:5678"
+	r.Header.Set("X-Real-IP", "5.6.7.8")
+	assert.Equal(t, "5.6.7.8", proxies.httpClientIP(r))
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	assert.Nil(t, err)
+
+	newContext := func(peerIP string) context.Context {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(clientIPKeyOnMetadata, "5.6.7.8"))
+
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 5678}})
+	}
+
+	assert.Equal(t, "1.2.3.4", proxies.clientIP(newContext("1.2.3.4")))
+	assert.Equal(t, "5.6.7.8", proxies.clientIP(newContext("10.1.2.3")))
+	assert.Equal(t, "", proxies.clientIP(context.Background()))
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/config"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/impls"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	attachmentFormFile      = "file"
+	attachmentKeyRandomSize = 16
+	defaultAttachmentSize   = 10 * 1024 * 1024
+	talkAccessTimeout       = 10 * time.Second
+	attachmentLinkDuration  = 5 * time.Minute
+	attachmentLinkSecretLen = 32
+)
+
+// the content types served as they are, the others are downloaded as octet streams.
+var inlineContentTypePrefixes = []string{"image/", "audio/"}
+
+// talkAccessChecker checks the token owner is a member of the talk.
+type talkAccessChecker func(ctx context.Context, token, talkID string) error
+
+type attachmentHandlers struct {
+	store       defs.BlobStore
+	maxSize     int64
+	linkSecret  []byte
+	checkAccess talkAccessChecker
+	logger      l.Wrapper
+}
+
+type attachmentLinkResponse struct {
+	URL      string `json:"url"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+func newAttachmentHandlers(store defs.BlobStore, maxSize int64, linkSecret string, checkAccess talkAccessChecker,
+	logger l.Wrapper) *attachmentHandlers {
+	if maxSize <= 0 {
+		maxSize = defaultAttachmentSize
+	}
+
+	secret := []byte(linkSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, attachmentLinkSecretLen)
+		_, _ = rand.Read(secret)
+	}
+
+	return &attachmentHandlers{
+		store:       store,
+		maxSize:     maxSize,
+		linkSecret:  secret,
+		checkAccess: checkAccess,
+		logger:      logger.WithFields(l.StringField(l.ClsKey, "attachmentHandlers")),
+	}
+}
+
+func setupAttachmentHandlers(mux *http.ServeMux, cfg *config.WSConfig, checkAccess talkAccessChecker) {
+	if cfg.Attachment.Store == "" {
+		return
+	}
+
+	store, err := impls.NewBlobStore(&cfg.Attachment)
+	if err != nil {
+		cfg.Logger.Fatal(err)
+	}
+
+	newAttachmentHandlers(store, cfg.Attachment.MaxSizeBytes, cfg.Attachment.LinkSecret, checkAccess, cfg.Logger).Setup(mux)
+}
+
+func (h *attachmentHandlers) Setup(mux *http.ServeMux) {
+	mux.HandleFunc("/attachment/upload", h.upload)
+	mux.HandleFunc("/attachment/link", h.link)
+	mux.HandleFunc("/attachment/download", h.download)
+}
+
+// upload accepts a multipart form with the file in the "file" field, the talk is given by the "talkID" parameter.
+func (h *attachmentHandlers) upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIResponse(w, nil, codes.InvalidArgument, commerr.ErrInvalidArgument)
+
+		return
+	}
+
+	talkID := r.URL.Query().Get("talkID")
+	if talkID == "" || strings.Contains(talkID, "/") {
+		writeAPIResponse(w, nil, codes.InvalidArgument, commerr.ErrInvalidArgument)
+
+		return
+	}
+
+	if err := h.checkAccess(r.Context(), r.Header.Get(httpTokenHeaderKey), talkID); err != nil {
+		writeAPIResponse(w, nil, codeFromError(err), err)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+1024*1024)
+
+	file, header, err := r.FormFile(attachmentFormFile)
+	if err != nil {
+		h.logger.WithFields(l.ErrorField(err)).Warn("FormFileFailed")
+		writeAPIResponse(w, nil, codes.InvalidArgument, err)
+
+		return
+	}
+
+	defer file.Close()
+
+	if header.Size <= 0 || header.Size > h.maxSize {
+		writeAPIResponse(w, nil, codes.InvalidArgument, commerr.ErrInvalidArgument)
+
+		return
+	}
+
+	fileRef := &defs.FileRef{
+		Key:      newAttachmentKey(talkID),
+		Name:     filepath.Base(header.Filename),
+		MimeType: attachmentMimeType(header.Filename, header.Header.Get("Content-Type")),
+		Size:     header.Size,
+	}
+
+	if err = h.store.Put(r.Context(), fileRef.Key, &defs.BlobInfo{
+		Name:        fileRef.Name,
+		ContentType: fileRef.MimeType,
+		Size:        fileRef.Size,
+	}, file); err != nil {
+		h.logger.WithFields(l.ErrorField(err)).Error("PutBlobFailed")
+		writeAPIResponse(w, nil, codes.Internal, err)
+
+		return
+	}
+
+	writeAPIResponse(w, fileRef, codes.OK, nil)
+}
+
+// link returns a short-lived signed download url of the attachment, for the links opened by browsers which
+// can't send the token header.
+func (h *attachmentHandlers) link(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+
+	talkID, _, ok := strings.Cut(key, "/")
+	if !ok || talkID == "" {
+		writeAPIResponse(w, nil, codes.InvalidArgument, commerr.ErrInvalidArgument)
+
+		return
+	}
+
+	if err := h.checkAccess(r.Context(), r.Header.Get(httpTokenHeaderKey), talkID); err != nil {
+		writeAPIResponse(w, nil, codeFromError(err), err)
+
+		return
+	}
+
+	expireAt := time.Now().Add(attachmentLinkDuration).Unix()
+
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("expires", strconv.FormatInt(expireAt, 10))
+	query.Set("signature", h.linkSignature(key, expireAt))
+
+	writeAPIResponse(w, &attachmentLinkResponse{
+		URL:      "/attachment/download?" + query.Encode(),
+		ExpireAt: expireAt,
+	}, codes.OK, nil)
+}
+
+// download serves the attachment to the members of the talk by the token header, or by a signed link.
+func (h *attachmentHandlers) download(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+
+	talkID, _, ok := strings.Cut(key, "/")
+	if !ok || talkID == "" {
+		writeAPIResponse(w, nil, codes.InvalidArgument, commerr.ErrInvalidArgument)
+
+		return
+	}
+
+	if r.URL.Query().Has("signature") {
+		if err := h.verifyLink(r.URL.Query(), key); err != nil {
+			writeAPIResponse(w, nil, codeFromError(err), err)
+
+			return
+		}
+	} else if err := h.checkAccess(r.Context(), r.Header.Get(httpTokenHeaderKey), talkID); err != nil {
+		writeAPIResponse(w, nil, codeFromError(err), err)
+
+		return
+	}
+
+	reader, info, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		writeAPIResponse(w, nil, codeFromError(err), err)
+
+		return
+	}
+
+	defer reader.Close()
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", servedContentType(info.ContentType))
+
+	if info.Name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": info.Name,
+		}))
+	}
+
+	if _, err = io.Copy(w, reader); err != nil {
+		h.logger.WithFields(l.ErrorField(err)).Warn("CopyBlobFailed")
+	}
+}
+
+func (h *attachmentHandlers) linkSignature(key string, expireAt int64) string {
+	mac := hmac.New(sha256.New, h.linkSecret)
+	_, _ = mac.Write([]byte(key + "\n" + strconv.FormatInt(expireAt, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *attachmentHandlers) verifyLink(query url.Values, key string) error {
+	expireAt, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return commerr.ErrInvalidArgument
+	}
+
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(h.linkSignature(key, expireAt))) {
+		return commerr.ErrPermissionDenied
+	}
+
+	if time.Now().Unix() > expireAt {
+		return commerr.ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// servedContentType keeps the uploader given types of images and audios only, the others may be rendered
+// by browsers as pages.
+func servedContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	for _, prefix := range inlineContentTypePrefixes {
+		if strings.HasPrefix(mediaType, prefix) && mediaType != "image/svg+xml" {
+			return mediaType
+		}
+	}
+
+	return "application/octet-stream"
+}
+
+func newAttachmentKey(talkID string) string {
+	d := make([]byte, attachmentKeyRandomSize)
+	_, _ = rand.Read(d)
+
+	return talkID + "/" + hex.EncodeToString(d)
+}
+
+func attachmentMimeType(fileName, contentType string) string {
+	if contentType != "" && contentType != "application/octet-stream" {
+		return contentType
+	}
+
+	if mimeType := mime.TypeByExtension(filepath.Ext(fileName)); mimeType != "" {
+		return mimeType
+	}
+
+	return "application/octet-stream"
+}
+
+//
+//
+//
+
+func customerTalkAccessChecker(gRPCClient talkpb.CustomerTalkServiceClient) talkAccessChecker {
+	return func(ctx context.Context, token, talkID string) error {
+		md := metadata.New(map[string]string{
+			httpTokenHeaderKey: token,
+		})
+
+		resp, err := gRPCClient.QueryTalks(metadata.NewOutgoingContext(ctx, md), &talkpb.QueryTalksRequest{})
+		if err != nil {
+			return commerr.ErrUnauthenticated
+		}
+
+		for _, talk := range resp.GetTalks() {
+			if talk.GetTalkId() == talkID {
+				return nil
+			}
+		}
+
+		return commerr.ErrPermissionDenied
+	}
+}
+
+// servicerTalkAccessChecker asks the servicer api of the backend, the gateway has no model access.
+func servicerTalkAccessChecker(apiURL string) talkAccessChecker {
+	client := &http.Client{
+		Timeout: talkAccessTimeout,
+	}
+
+	return func(ctx context.Context, token, talkID string) error {
+		d, _ := json.Marshal(&talkWatchRequest{
+			TalkID: talkID,
+		})
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(apiURL, "/")+"/api/talk/access",
+			bytes.NewReader(d))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set(httpTokenHeaderKey, token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		defer resp.Body.Close()
+
+		var apiResp apiResponse
+
+		if err = json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+			return err
+		}
+
+		switch apiResp.Code {
+		case codes.OK:
+			return nil
+		case codes.Unauthenticated:
+			return commerr.ErrUnauthenticated
+		case codes.NotFound:
+			return commerr.ErrNotFound
+		case codes.PermissionDenied:
+			return commerr.ErrPermissionDenied
+		default:
+			return errors.New(apiResp.Message)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/sgostarter/i/l"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+	"github.com/zservicer/talkbe/internal/impls"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAttachmentDownload(t *testing.T) {
+	store, err := impls.NewLocalBlobStore(t.TempDir())
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+
+	for key, contentType := range map[string]string{"t1/page": "text/html", "t1/img": "image/png; charset=x"} {
+		assert.Nil(t, store.Put(ctx, key, &defs.BlobInfo{Name: "f", ContentType: contentType, Size: 2},
+			strings.NewReader("<>")))
+	}
+
+	h := newAttachmentHandlers(store, 0, "secret", func(_ context.Context, token, talkID string) error {
+		if token != "token" || talkID != "t1" {
+			return commerr.ErrPermissionDenied
+		}
+
+		return nil
+	}, l.NewNopLoggerWrapper())
+
+	mux := http.NewServeMux()
+	h.Setup(mux)
+
+	serve := func(target, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			r.Header.Set(httpTokenHeaderKey, token)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// the pages are never served as they are
+	w := serve("/attachment/download?key=t1/page", "token")
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = serve("/attachment/download?key=t1/img", "token")
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	// the token isn't read from the query
+	w = serve("/attachment/download?key=t1/img&token=token", "")
+	assert.NotEqual(t, "<>", w.Body.String())
+
+	// the signed links
+	w = serve("/attachment/link?key=t1/img", "token")
+
+	var resp struct {
+		Code codes.Code              `json:"code"`
+		Data *attachmentLinkResponse `json:"data"`
+	}
+
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, codes.OK, resp.Code)
+
+	w = serve(resp.Data.URL, "")
+	assert.Equal(t, "<>", w.Body.String())
+
+	w = serve(strings.Replace(resp.Data.URL, "t1%2Fimg", "t1%2Fpage", 1), "")
+	assert.NotEqual(t, "<>", w.Body.String())
+
+	assert.NotEqual(t, codes.OK, decodeCode(t, serve("/attachment/link?key=t2/img", "token")))
+}
+
+func decodeCode(t *testing.T, w *httptest.ResponseRecorder) codes.Code {
+	var resp apiResponse
+
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	return resp.Code
+}
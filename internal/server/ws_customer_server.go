@@ -17,7 +17,8 @@ import (
 )
 
 const (
-	httpTokenHeaderKey = "token"
+	httpTokenHeaderKey    = "token"
+	httpIdentityHeaderKey = "identity"
 )
 
 func customerWSReceive(conn *websocket.Conn, stream talkpb.CustomerTalkService_TalkClient, logger l.Wrapper) {
@@ -217,7 +218,14 @@ func createHandler(gRPCClient talkpb.CustomerUserServicerClient, logger l.Wrappe
 			return
 		}
 
-		resp, err := gRPCClient.CreateToken(context.TODO(), &request)
+		ctx := context.TODO()
+		if identityToken := r.Header.Get(httpIdentityHeaderKey); identityToken != "" {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{
+				"identity": identityToken,
+			}))
+		}
+
+		resp, err := gRPCClient.CreateToken(ctx, &request)
 		if err != nil {
 			logger.WithFields(l.ErrorField(err)).Error("CheckTokenFailed")
 
@@ -288,4 +296,6 @@ func SetupHTTPCustomerServer(mux *http.ServeMux, cfg *config.WSConfig) {
 	mux.HandleFunc("/createToken", createHandler(gRPCUserClient, cfg.Logger))
 	mux.HandleFunc("/listTalk", listTalkHandler(gRPCTalkClient, cfg.Logger))
 	mux.HandleFunc("/ws/customer", customerWS(gRPCTalkClient, cfg.Logger))
+
+	setupAttachmentHandlers(mux, cfg, customerTalkAccessChecker(gRPCTalkClient))
 }
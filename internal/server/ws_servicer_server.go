@@ -155,17 +155,28 @@ func servicerWS(gRPCClient talkpb.ServiceTalkServiceClient, logger l.Wrapper) fu
 	}
 }
 
+// loginData is the first login step, or the second one with the TOTP code and the continue id instead of the password.
 type loginData struct {
-	UserName string `json:"user_name"`
-	Password string `json:"password"`
+	UserName   string `json:"user_name"`
+	Password   string `json:"password"`
+	TOTPCode   string `json:"totp_code"`
+	ContinueID string `json:"continue_id"`
 }
 
 type loginDataResponse struct {
 	Token    string `json:"token"`
 	UserName string `json:"user_name"`
+	// the token is only for changing the password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+}
+
+type loginErrorResponse struct {
+	Error      string `json:"error"`
+	ContinueID string `json:"continue_id,omitempty"`
 }
 
-func loginHandler(gRPCClient talkpb.ServicerUserServicerClient, logger l.Wrapper) func(w http.ResponseWriter, r *http.Request) {
+func loginHandler(gRPCClient talkpb.ServicerUserServicerClient, trustedProxies TrustedProxies,
+	logger l.Wrapper) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 
@@ -187,26 +198,52 @@ func loginHandler(gRPCClient talkpb.ServicerUserServicerClient, logger l.Wrapper
 			return
 		}
 
-		resp, err := gRPCClient.Login(r.Context(), &talkpb.LoginRequest{
+		md := metadata.New(map[string]string{
+			clientIPKeyOnMetadata: trustedProxies.httpClientIP(r),
+			deviceKeyOnMetadata:   r.UserAgent(),
+		})
+
+		if loginD.TOTPCode != "" {
+			md.Set(totpCodeKeyOnMetadata, loginD.TOTPCode)
+		}
+
+		if loginD.ContinueID != "" {
+			md.Set(continueIDKeyOnMetadata, loginD.ContinueID)
+		}
+
+		var trailer metadata.MD
+
+		resp, err := gRPCClient.Login(metadata.NewOutgoingContext(r.Context(), md), &talkpb.LoginRequest{
 			UserName: loginD.UserName,
 			Password: loginD.Password,
-		})
+		}, grpc.Trailer(&trailer))
 		if err != nil {
 			logger.WithFields(l.ErrorField(err)).Error("LoginFailed")
-			w.WriteHeader(http.StatusInternalServerError)
+
+			switch status.Code(err) {
+			case codes.ResourceExhausted:
+				w.WriteHeader(http.StatusTooManyRequests)
+			case codes.FailedPrecondition:
+				writeLoginError(w, status.Convert(err).Message(), trailer.Get(continueIDKeyOnMetadata))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
 
 			return
 		}
 
-		if len(resp.ActIds) == 0 || len(resp.BizIds) == 0 {
+		passwordChangeRequired := len(trailer.Get(passwordChangeRequiredKeyOnMetadata)) > 0
+
+		if !passwordChangeRequired && (len(resp.ActIds) == 0 || len(resp.BizIds) == 0) {
 			w.WriteHeader(http.StatusForbidden)
 
 			return
 		}
 
 		ldResp := &loginDataResponse{
-			Token:    resp.Token,
-			UserName: resp.UserName,
+			Token:                  resp.Token,
+			UserName:               resp.UserName,
+			PasswordChangeRequired: passwordChangeRequired,
 		}
 
 		d, _ = json.Marshal(ldResp)
@@ -214,6 +251,30 @@ func loginHandler(gRPCClient talkpb.ServicerUserServicerClient, logger l.Wrapper
 		_, _ = w.Write(d)
 	}
 }
+
+// writeLoginError tells the client to continue with a TOTP code, or to enroll the two-factor authentication first.
+func writeLoginError(w http.ResponseWriter, errMsg string, continueIDs []string) {
+	errResp := &loginErrorResponse{
+		Error: errMsg,
+	}
+
+	if len(continueIDs) > 0 {
+		errResp.ContinueID = continueIDs[0]
+	}
+
+	d, _ := json.Marshal(errResp)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if errResp.ContinueID != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+	} else {
+		w.WriteHeader(http.StatusForbidden)
+	}
+
+	_, _ = w.Write(d)
+}
+
 func SetupHTTPServicerServer(mux *http.ServeMux, cfg *config.WSConfig) {
 	talkConn, err := clienttoolset.DialGRPC(cfg.ServicerGRPCClientConfig, []grpc.DialOption{
 		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024 * 1024 * 1024)),
@@ -237,6 +298,15 @@ func SetupHTTPServicerServer(mux *http.ServeMux, cfg *config.WSConfig) {
 
 	gRPCUserClient := talkpb.NewServicerUserServicerClient(userConn)
 
-	mux.HandleFunc("/login", loginHandler(gRPCUserClient, cfg.Logger))
+	trustedProxies, err := NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		cfg.Logger.Fatal(err)
+	}
+
+	mux.HandleFunc("/login", loginHandler(gRPCUserClient, trustedProxies, cfg.Logger))
 	mux.HandleFunc("/ws/servicer", servicerWS(gRPCTalkClient, cfg.Logger))
+
+	if cfg.ServicerAPIURL != "" {
+		setupAttachmentHandlers(mux, cfg, servicerTalkAccessChecker(cfg.ServicerAPIURL))
+	}
 }
@@ -0,0 +1,114 @@
+package vo
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+// MessageCommandPrefix marks a pb text message as a json encoded defs.MessageCommand,
+// commands are handled by the server and never stored as messages.
+const MessageCommandPrefix = "\x1d"
+
+type MessageConfirmed struct {
+	SeqID     uint64 `json:"seqID"`
+	At        int64  `json:"at"`
+	MessageID string `json:"messageID"`
+}
+
+// RejectReasonRateLimited rejects the messages over the rate limit, a stream is kicked out with it
+// after repeated violations.
+const RejectReasonRateLimited = "rateLimited"
+
+// The reject reasons of the messages and commands failed the checks, RejectReasonInvalidMessage rejects the malformed
+// ones and the rich messages of the kinds written by the server.
+const (
+	RejectReasonInvalidMessage = "invalidMessage"
+	RejectReasonNotAllowed     = "notAllowed"
+	RejectReasonNotFound       = "notFound"
+	RejectReasonExpired        = "expired"
+	RejectReasonFailed         = "failed"
+)
+
+// The reject reasons of the images, the image messages over the limits or in a disallowed format are rejected.
+const (
+	RejectReasonImageInvalid       = "imageInvalid"
+	RejectReasonImageTooLarge      = "imageTooLarge"
+	RejectReasonImageTooManyPixels = "imageTooManyPixels"
+	RejectReasonImageFormat        = "imageFormatNotAllowed"
+)
+
+// ImageRejectReason returns the reject reason of an image processing error, or empty if the image isn't rejected.
+func ImageRejectReason(err error) string {
+	switch {
+	case errors.Is(err, defs.ErrImageInvalid):
+		return RejectReasonImageInvalid
+	case errors.Is(err, defs.ErrImageTooLarge):
+		return RejectReasonImageTooLarge
+	case errors.Is(err, defs.ErrImageTooManyPixels):
+		return RejectReasonImageTooManyPixels
+	case errors.Is(err, defs.ErrImageFormat):
+		return RejectReasonImageFormat
+	default:
+		return ""
+	}
+}
+
+// MessageRejectReason returns the reject reason of an error of checking or storing a message or command.
+func MessageRejectReason(err error) string {
+	if reason := ImageRejectReason(err); reason != "" {
+		return reason
+	}
+
+	switch {
+	case errors.Is(err, commerr.ErrInvalidArgument):
+		return RejectReasonInvalidMessage
+	case errors.Is(err, commerr.ErrPermissionDenied):
+		return RejectReasonNotAllowed
+	case errors.Is(err, commerr.ErrNotFound):
+		return RejectReasonNotFound
+	case errors.Is(err, commerr.ErrTimeout):
+		return RejectReasonExpired
+	default:
+		return RejectReasonFailed
+	}
+}
+
+// KickOutReasonBanned kicks out the streams of a banned customer.
+const KickOutReasonBanned = "banned"
+
+// KickOutReasonDisabled and KickOutReasonDeleted kick out the streams of a disabled or deleted servicer,
+// KickOutReasonRoleChanged the streams of a servicer whose roles are changed, they reconnect with the new roles,
+// KickOutReasonSessionRevoked the streams of the revoked sessions.
+const (
+	KickOutReasonDisabled       = "disabled"
+	KickOutReasonDeleted        = "deleted"
+	KickOutReasonRoleChanged    = "roleChanged"
+	KickOutReasonSessionRevoked = "sessionRevoked"
+)
+
+// MessageRejected tells the sender a message or an edit is rejected by the moderation.
+type MessageRejected struct {
+	SeqID     uint64 `json:"seqID,omitempty"`
+	TalkID    string `json:"talkID,omitempty"`
+	MessageID string `json:"messageID,omitempty"` // the edited message
+	Reason    string `json:"reason,omitempty"`
+}
+
+func MessageCommandFromPb(message *talkpb.TalkMessageW) (*defs.MessageCommand, bool) {
+	if message == nil || !strings.HasPrefix(message.GetText(), MessageCommandPrefix) {
+		return nil, false
+	}
+
+	var command defs.MessageCommand
+
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(message.GetText(), MessageCommandPrefix)), &command); err != nil {
+		return nil, false
+	}
+
+	return &command, true
+}
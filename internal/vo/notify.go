@@ -0,0 +1,52 @@
+package vo
+
+import (
+	"encoding/json"
+
+	"github.com/zservicer/protorepo/gens/talkpb"
+)
+
+const (
+	NotifyKindSLABreach     = "slaBreach"
+	NotifyKindQueuePosition = "queuePosition"
+	NotifyKindWhisper       = "whisper"
+	NotifyKindParticipants  = "participants"
+	NotifyKindRevision      = "messageRevision"
+	NotifyKindConfirmed     = "messageConfirmed"
+	NotifyKindRejected      = "messageRejected"
+)
+
+type notifyMessage struct {
+	Kind string      `json:"kind"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// NotifyMessage encodes a structured notify, the receiver should try to decode a notify message as json first.
+func NotifyMessage(kind string, data interface{}) string {
+	d, _ := json.Marshal(&notifyMessage{
+		Kind: kind,
+		Data: data,
+	})
+
+	return string(d)
+}
+
+func ServiceNotifyResponse(kind string, data interface{}) *talkpb.ServiceResponse {
+	return &talkpb.ServiceResponse{
+		Response: &talkpb.ServiceResponse_Notify{
+			Notify: &talkpb.ServiceTalkNotifyResponse{
+				Msg: NotifyMessage(kind, data),
+			},
+		},
+	}
+}
+
+func TalkNotifyResponse(kind string, data interface{}) *talkpb.TalkResponse {
+	return &talkpb.TalkResponse{
+		Talk: &talkpb.TalkResponse_Notify{
+			Notify: &talkpb.TalkNotifyResponse{
+				Msg: NotifyMessage(kind, data),
+			},
+		},
+	}
+}
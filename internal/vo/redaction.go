@@ -0,0 +1,67 @@
+package vo
+
+import (
+	"encoding/json"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+// RedactedTextFromDB decodes the redaction of a text message.
+func RedactedTextFromDB(message *talkinters.TalkMessageW) (*defs.RedactedText, bool) {
+	if message == nil || message.Type != talkinters.TalkMessageTypeText || len(message.Data) == 0 {
+		return nil, false
+	}
+
+	var redactedText defs.RedactedText
+
+	if err := json.Unmarshal(message.Data, &redactedText); err != nil {
+		return nil, false
+	}
+
+	return &redactedText, true
+}
+
+// servicerText is the text of a text message shown to servicers, it's masked if the message is
+// redacted for servicers only.
+func servicerText(message *talkinters.TalkMessageW) string {
+	if redactedText, ok := RedactedTextFromDB(message); ok && redactedText.Masked != "" {
+		return redactedText.Masked
+	}
+
+	return message.Text
+}
+
+// ServicerMessageRevision is the revision sent to servicers, the text of a redacted edit is masked.
+func ServicerMessageRevision(revision *defs.MessageRevision) *defs.MessageRevision {
+	if revision.Edit == nil {
+		return revision
+	}
+
+	r := *revision
+	r.Edit = &defs.TalkMessageEdit{
+		At: revision.Edit.At,
+		Text: servicerText(&talkinters.TalkMessageW{
+			Type: talkinters.TalkMessageTypeText,
+			Text: revision.Edit.Text,
+			Data: revision.Edit.Data,
+		}),
+	}
+
+	return &r
+}
+
+// CustomerMessageRevision is the revision sent to customers, without the redaction of the edit.
+func CustomerMessageRevision(revision *defs.MessageRevision) *defs.MessageRevision {
+	if revision.Edit == nil {
+		return revision
+	}
+
+	r := *revision
+	r.Edit = &defs.TalkMessageEdit{
+		At:   revision.Edit.At,
+		Text: revision.Edit.Text,
+	}
+
+	return &r
+}
@@ -0,0 +1,325 @@
+package vo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/zservicer/talkbe/internal/defs"
+	"golang.org/x/exp/slices"
+)
+
+// RichMessagePrefix marks a pb text message as a json encoded defs.RichMessage,
+// the pb message only has text and image fields.
+const RichMessagePrefix = "\x1e"
+
+const (
+	maxReplyButtons   = 10
+	maxRichTextLen    = 1024
+	maxRichTitleLen   = 128
+	maxButtonIDLen    = 64
+	maxQuoteTextLen   = 4096
+	maxSnippetRunes   = 64
+	maxLatitude       = 90
+	maxLongitude      = 180
+	richMessageAudio  = "[audio]"
+	richMessageImage  = "[image]"
+	richMessageRating = "[rating]"
+)
+
+var richMessageTypes = map[string]talkinters.TalkMessageType{
+	defs.RichMessageKindFile:       defs.TalkMessageTypeFile,
+	defs.RichMessageKindAudio:      defs.TalkMessageTypeAudio,
+	defs.RichMessageKindLocation:   defs.TalkMessageTypeLocation,
+	defs.RichMessageKindCard:       defs.TalkMessageTypeCard,
+	defs.RichMessageKindQuickReply: defs.TalkMessageTypeQuickReply,
+	defs.RichMessageKindReply:      defs.TalkMessageTypeReply,
+	defs.RichMessageKindQuote:      defs.TalkMessageTypeQuote,
+	defs.RichMessageKindSystem:     defs.TalkMessageTypeSystem,
+	defs.RichMessageKindRating:     defs.TalkMessageTypeRating,
+}
+
+var (
+	customerRichMessageKinds = []string{
+		defs.RichMessageKindFile, defs.RichMessageKindAudio, defs.RichMessageKindLocation, defs.RichMessageKindReply,
+		defs.RichMessageKindQuote,
+	}
+	servicerRichMessageKinds = []string{
+		defs.RichMessageKindFile, defs.RichMessageKindAudio, defs.RichMessageKindLocation, defs.RichMessageKindCard,
+		defs.RichMessageKindQuickReply, defs.RichMessageKindQuote,
+	}
+)
+
+func IsRichMessageType(messageType talkinters.TalkMessageType) bool {
+	for _, t := range richMessageTypes {
+		if t == messageType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RichMessageFromDB decodes the rich message payload of a db message.
+func RichMessageFromDB(message *talkinters.TalkMessageW) (*defs.RichMessage, bool) {
+	if message == nil || !IsRichMessageType(message.Type) {
+		return nil, false
+	}
+
+	var richMessage defs.RichMessage
+
+	if err := json.Unmarshal(message.Data, &richMessage); err != nil {
+		return nil, false
+	}
+
+	return &richMessage, true
+}
+
+// ValidateTalkMessage checks the rich message of a incoming message, attachments must be uploaded to the same talk,
+// customers can't send cards or quick replies, and servicers can't reply to them. The malformed rich messages and
+// commands are converted to the unknown type and rejected.
+func ValidateTalkMessage(talkID string, message *talkinters.TalkMessageW) error {
+	if message == nil || message.Type == talkinters.TalkMessageTypeUnknown {
+		return commerr.ErrInvalidArgument
+	}
+
+	if message.Type == talkinters.TalkMessageTypeText && (strings.HasPrefix(message.Text, RichMessagePrefix) ||
+		strings.HasPrefix(message.Text, MessageCommandPrefix)) {
+		return commerr.ErrInvalidArgument
+	}
+
+	if !IsRichMessageType(message.Type) {
+		return nil
+	}
+
+	richMessage, ok := RichMessageFromDB(message)
+	if !ok {
+		return commerr.ErrInvalidArgument
+	}
+
+	kinds := servicerRichMessageKinds
+	if message.CustomerMessage {
+		kinds = customerRichMessageKinds
+	}
+
+	if !slices.Contains(kinds, richMessage.Kind) {
+		return commerr.ErrPermissionDenied
+	}
+
+	for _, fileRef := range []*defs.FileRef{richMessage.File, audioFile(richMessage.Audio)} {
+		if fileRef != nil && !strings.HasPrefix(fileRef.Key, talkID+"/") {
+			return commerr.ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
+
+// UpdateRichMessage replaces the rich message payload of a db message.
+func UpdateRichMessage(message *talkinters.TalkMessageW, richMessage *defs.RichMessage) error {
+	d, err := json.Marshal(richMessage)
+	if err != nil {
+		return err
+	}
+
+	message.Text = richMessageText(richMessage)
+	message.Data = d
+
+	return nil
+}
+
+// NewRatingMessage creates the rating message of a customer for the rate command.
+func NewRatingMessage(at int64, score int) (*talkinters.TalkMessageW, error) {
+	if score < defs.MinRatingScore || score > defs.MaxRatingScore {
+		return nil, commerr.ErrInvalidArgument
+	}
+
+	message := &talkinters.TalkMessageW{
+		At:              at,
+		CustomerMessage: true,
+		Type:            defs.TalkMessageTypeRating,
+	}
+
+	if err := UpdateRichMessage(message, &defs.RichMessage{
+		Kind:   defs.RichMessageKindRating,
+		Rating: &defs.RatingMessage{Score: score},
+	}); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func richMessagePb2Db(text string, dbMessage *talkinters.TalkMessageW) bool {
+	if !strings.HasPrefix(text, RichMessagePrefix) {
+		return false
+	}
+
+	var richMessage defs.RichMessage
+
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(text, RichMessagePrefix)), &richMessage); err != nil {
+		return false
+	}
+
+	messageType, ok := richMessageTypes[richMessage.Kind]
+	if !ok || !validRichMessage(&richMessage) {
+		return false
+	}
+
+	if err := UpdateRichMessage(dbMessage, &richMessage); err != nil {
+		return false
+	}
+
+	dbMessage.Type = messageType
+
+	return true
+}
+
+var recalledMessageText = func() string {
+	d, _ := json.Marshal(&defs.RichMessage{Kind: defs.RichMessageKindRecalled})
+
+	return RichMessagePrefix + string(d)
+}()
+
+func richMessageDB2Pb(message *talkinters.TalkMessageW) string {
+	return RichMessagePrefix + string(message.Data)
+}
+
+// validRichMessage checks the payload matches the kind, payloads of other kinds are not allowed.
+func validRichMessage(m *defs.RichMessage) bool {
+	payloads := 0
+
+	for _, set := range []bool{m.File != nil, m.Audio != nil, m.Location != nil, m.Card != nil, m.QuickReply != nil,
+		m.Reply != nil, m.Quote != nil, m.System != nil, m.Rating != nil} {
+		if set {
+			payloads++
+		}
+	}
+
+	if payloads != 1 {
+		return false
+	}
+
+	switch m.Kind {
+	case defs.RichMessageKindFile:
+		return validFileRef(m.File)
+	case defs.RichMessageKindAudio:
+		return m.Audio != nil && validFileRef(m.Audio.File) && m.Audio.DurationSeconds >= 0
+	case defs.RichMessageKindLocation:
+		return m.Location != nil && m.Location.Latitude >= -maxLatitude && m.Location.Latitude <= maxLatitude &&
+			m.Location.Longitude >= -maxLongitude && m.Location.Longitude <= maxLongitude &&
+			len(m.Location.Name) <= maxRichTitleLen && len(m.Location.Address) <= maxRichTextLen
+	case defs.RichMessageKindCard:
+		return m.Card != nil && m.Card.Title != "" && len(m.Card.Title) <= maxRichTitleLen &&
+			len(m.Card.Description) <= maxRichTextLen && validURL(m.Card.ImageURL) && validURL(m.Card.URL) &&
+			validReplyButtons(m.Card.Buttons, false)
+	case defs.RichMessageKindQuickReply:
+		return m.QuickReply != nil && m.QuickReply.Text != "" && len(m.QuickReply.Text) <= maxRichTextLen &&
+			validReplyButtons(m.QuickReply.Buttons, true)
+	case defs.RichMessageKindReply:
+		return m.Reply != nil && m.Reply.MessageID != "" && m.Reply.ButtonID != ""
+	case defs.RichMessageKindQuote:
+		return m.Quote != nil && m.Quote.MessageID != "" && strings.TrimSpace(m.Quote.Text) != "" &&
+			len(m.Quote.Text) <= maxQuoteTextLen
+	case defs.RichMessageKindSystem:
+		return m.System != nil && m.System.Event != ""
+	case defs.RichMessageKindRating:
+		return m.Rating != nil && m.Rating.Score >= defs.MinRatingScore && m.Rating.Score <= defs.MaxRatingScore
+	default:
+		return false
+	}
+}
+
+func validFileRef(fileRef *defs.FileRef) bool {
+	return fileRef != nil && fileRef.Key != "" && fileRef.Size > 0
+}
+
+func validReplyButtons(buttons []*defs.ReplyButton, required bool) bool {
+	if (required && len(buttons) == 0) || len(buttons) > maxReplyButtons {
+		return false
+	}
+
+	ids := make([]string, 0, len(buttons))
+
+	for _, button := range buttons {
+		if button == nil || button.ID == "" || len(button.ID) > maxButtonIDLen || button.Title == "" ||
+			len(button.Title) > maxRichTitleLen || !validURL(button.URL) || slices.Contains(ids, button.ID) {
+			return false
+		}
+
+		ids = append(ids, button.ID)
+	}
+
+	return true
+}
+
+// validURL allows empty or http(s) urls only.
+func validURL(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	u, err := url.Parse(s)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func audioFile(audio *defs.AudioMessage) *defs.FileRef {
+	if audio == nil {
+		return nil
+	}
+
+	return audio.File
+}
+
+// richMessageText is the plain text of a rich message, kept in TalkMessageW.Text for searching.
+func richMessageText(m *defs.RichMessage) string {
+	switch m.Kind {
+	case defs.RichMessageKindFile:
+		return m.File.Name
+	case defs.RichMessageKindAudio:
+		return richMessageAudio
+	case defs.RichMessageKindLocation:
+		return strings.TrimSpace(m.Location.Name + " " + m.Location.Address)
+	case defs.RichMessageKindCard:
+		return m.Card.Title
+	case defs.RichMessageKindQuickReply:
+		return m.QuickReply.Text
+	case defs.RichMessageKindReply:
+		return m.Reply.Title
+	case defs.RichMessageKindQuote:
+		return m.Quote.Text
+	case defs.RichMessageKindSystem:
+		return m.System.Text
+	case defs.RichMessageKindRating:
+		return fmt.Sprintf("%s %d", richMessageRating, m.Rating.Score)
+	default:
+		return ""
+	}
+}
+
+// MessageSnippet is a short plain text of a message, shown in the quotes of it.
+func MessageSnippet(message *talkinters.TalkMessageW) string {
+	text := message.Text
+
+	switch message.Type {
+	case talkinters.TalkMessageTypeImage:
+		text = richMessageImage // the text of a image message is the key of the original
+	case talkinters.TalkMessageTypeText:
+		text = servicerText(message) // snippets are shared by customers and servicers
+	default:
+		if !IsRichMessageType(message.Type) {
+			return ""
+		}
+	}
+
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) > maxSnippetRunes {
+		return string(runes[:maxSnippetRunes]) + "…"
+	}
+
+	return string(runes)
+}
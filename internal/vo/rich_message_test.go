@@ -0,0 +1,138 @@
+package vo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func richText(t *testing.T, m *defs.RichMessage) string {
+	d, err := json.Marshal(m)
+	assert.Nil(t, err)
+
+	return RichMessagePrefix + string(d)
+}
+
+func TestRichMessageRoundTrip(t *testing.T) {
+	messages := []*defs.RichMessage{
+		{Kind: defs.RichMessageKindFile, File: &defs.FileRef{Key: "t1/k", Name: "a.pdf", MimeType: "application/pdf", Size: 3}},
+		{Kind: defs.RichMessageKindAudio, Audio: &defs.AudioMessage{File: &defs.FileRef{Key: "t1/k", Size: 3}, DurationSeconds: 2.5}},
+		{Kind: defs.RichMessageKindLocation, Location: &defs.LocationMessage{Latitude: 31.2, Longitude: 121.5, Name: "office"}},
+		{Kind: defs.RichMessageKindCard, Card: &defs.CardMessage{Title: "order", URL: "https://example.com/o/1",
+			Buttons: []*defs.ReplyButton{{ID: "confirm", Title: "Confirm"}}}},
+		{Kind: defs.RichMessageKindQuickReply, QuickReply: &defs.QuickReplyMessage{Text: "solved?",
+			Buttons: []*defs.ReplyButton{{ID: "y", Title: "Yes"}, {ID: "n", Title: "No"}}}},
+		{Kind: defs.RichMessageKindReply, Reply: &defs.ReplyMessage{MessageID: "1", ButtonID: "y"}},
+		{Kind: defs.RichMessageKindQuote, Quote: &defs.QuoteMessage{MessageID: "1", Text: "it's done", Snippet: "is it done?"}},
+		{Kind: defs.RichMessageKindRating, Rating: &defs.RatingMessage{Score: 5}},
+	}
+
+	for _, m := range messages {
+		text := richText(t, m)
+
+		dbMessage := TalkMessageWPb2Db(&talkpb.TalkMessageW{
+			Message: &talkpb.TalkMessageW_Text{Text: text},
+		})
+		assert.Equal(t, richMessageTypes[m.Kind], dbMessage.Type, m.Kind)
+
+		decoded, ok := RichMessageFromDB(dbMessage)
+		assert.True(t, ok)
+		assert.Equal(t, m, decoded)
+
+		pbMessage := TalkMessageDB2Pb4Servicer(dbMessage)
+		assert.Equal(t, text, pbMessage.GetText())
+	}
+}
+
+func TestRichMessageInvalid(t *testing.T) {
+	for _, m := range []*defs.RichMessage{
+		{Kind: "unknown"},
+		{Kind: defs.RichMessageKindFile},
+		{Kind: defs.RichMessageKindFile, File: &defs.FileRef{Key: "t1/k", Size: 1}, Location: &defs.LocationMessage{}},
+		{Kind: defs.RichMessageKindLocation, Location: &defs.LocationMessage{Latitude: 91}},
+		{Kind: defs.RichMessageKindCard, Card: &defs.CardMessage{Title: "x", URL: "javascript:alert(1)"}},
+		{Kind: defs.RichMessageKindQuickReply, QuickReply: &defs.QuickReplyMessage{Text: "x"}},
+		{Kind: defs.RichMessageKindQuickReply, QuickReply: &defs.QuickReplyMessage{Text: "x",
+			Buttons: []*defs.ReplyButton{{ID: "a", Title: "A"}, {ID: "a", Title: "B"}}}},
+		{Kind: defs.RichMessageKindRating, Rating: &defs.RatingMessage{Score: 6}},
+	} {
+		dbMessage := TalkMessageWPb2Db(&talkpb.TalkMessageW{
+			Message: &talkpb.TalkMessageW_Text{Text: richText(t, m)},
+		})
+		assert.Equal(t, talkinters.TalkMessageTypeUnknown, dbMessage.Type, m.Kind)
+		assert.ErrorIs(t, ValidateTalkMessage("t1", dbMessage), commerr.ErrInvalidArgument)
+	}
+
+	// the malformed and reserved payloads are never stored as plain texts with the prefixes
+	for _, text := range []string{RichMessagePrefix + "{", RichMessagePrefix + `{"kind":"recalled"}`,
+		MessageCommandPrefix + "garbage"} {
+		dbMessage := TalkMessageWPb2Db(&talkpb.TalkMessageW{
+			Message: &talkpb.TalkMessageW_Text{Text: text},
+		})
+		assert.Equal(t, talkinters.TalkMessageTypeUnknown, dbMessage.Type, text)
+		assert.Equal(t, RejectReasonInvalidMessage, MessageRejectReason(ValidateTalkMessage("t1", dbMessage)))
+	}
+
+	system := &defs.RichMessage{Kind: defs.RichMessageKindSystem, System: &defs.SystemMessage{Event: "closed"}}
+	dbMessage := TalkMessageWPb2Db(&talkpb.TalkMessageW{
+		Message: &talkpb.TalkMessageW_Text{Text: richText(t, system)},
+	})
+	dbMessage.CustomerMessage = true
+	assert.Equal(t, RejectReasonNotAllowed, MessageRejectReason(ValidateTalkMessage("t1", dbMessage)))
+}
+
+func TestValidateTalkMessage(t *testing.T) {
+	newMessage := func(m *defs.RichMessage, customer bool) *talkinters.TalkMessageW {
+		dbMessage := TalkMessageWPb2Db(&talkpb.TalkMessageW{
+			Message: &talkpb.TalkMessageW_Text{Text: richText(t, m)},
+		})
+		dbMessage.CustomerMessage = customer
+
+		return dbMessage
+	}
+
+	file := &defs.RichMessage{Kind: defs.RichMessageKindFile, File: &defs.FileRef{Key: "t1/k", Size: 1}}
+	assert.Nil(t, ValidateTalkMessage("t1", newMessage(file, true)))
+	assert.ErrorIs(t, ValidateTalkMessage("t2", newMessage(file, true)), commerr.ErrPermissionDenied)
+
+	card := &defs.RichMessage{Kind: defs.RichMessageKindCard, Card: &defs.CardMessage{Title: "x"}}
+	assert.Nil(t, ValidateTalkMessage("t1", newMessage(card, false)))
+	assert.ErrorIs(t, ValidateTalkMessage("t1", newMessage(card, true)), commerr.ErrPermissionDenied)
+
+	reply := &defs.RichMessage{Kind: defs.RichMessageKindReply, Reply: &defs.ReplyMessage{MessageID: "1", ButtonID: "y"}}
+	assert.Nil(t, ValidateTalkMessage("t1", newMessage(reply, true)))
+	assert.ErrorIs(t, ValidateTalkMessage("t1", newMessage(reply, false)), commerr.ErrPermissionDenied)
+
+	// the ratings are written by the server
+	rating := &defs.RichMessage{Kind: defs.RichMessageKindRating, Rating: &defs.RatingMessage{Score: 4}}
+	assert.ErrorIs(t, ValidateTalkMessage("t1", newMessage(rating, true)), commerr.ErrPermissionDenied)
+	assert.ErrorIs(t, ValidateTalkMessage("t1", newMessage(rating, false)), commerr.ErrPermissionDenied)
+
+	ratingMessage, err := NewRatingMessage(100, 4)
+	assert.Nil(t, err)
+	assert.True(t, ratingMessage.CustomerMessage)
+	assert.Equal(t, defs.TalkMessageTypeRating, ratingMessage.Type)
+
+	richMessage, ok := RichMessageFromDB(ratingMessage)
+	assert.True(t, ok)
+	assert.Equal(t, 4, richMessage.Rating.Score)
+
+	_, err = NewRatingMessage(100, defs.MaxRatingScore+1)
+	assert.ErrorIs(t, err, commerr.ErrInvalidArgument)
+}
+
+func TestMessageSnippet(t *testing.T) {
+	assert.Equal(t, "hello", MessageSnippet(&talkinters.TalkMessageW{Type: talkinters.TalkMessageTypeText, Text: " hello "}))
+	assert.Equal(t, richMessageImage, MessageSnippet(&talkinters.TalkMessageW{Type: talkinters.TalkMessageTypeImage,
+		Text: "t1/img-k"}))
+	assert.Equal(t, "", MessageSnippet(&talkinters.TalkMessageW{Type: defs.TalkMessageTypeRecalled}))
+
+	long := MessageSnippet(&talkinters.TalkMessageW{Type: talkinters.TalkMessageTypeText, Text: strings.Repeat("问", 100)})
+	assert.Equal(t, maxSnippetRunes+1, len([]rune(long)))
+}
@@ -0,0 +1,64 @@
+package vo
+
+import (
+	"fmt"
+
+	"github.com/sbasestarter/bizinters/talkinters"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+const (
+	SystemMessageUser = "系统"
+
+	ClosedByCustomer = "customer"
+	ClosedByServicer = "servicer"
+)
+
+// NewSystemMessage builds the db message of a talk event, the narrative is filled if it's empty.
+func NewSystemMessage(at int64, system *defs.SystemMessage) (*talkinters.TalkMessageW, error) {
+	if system.Text == "" {
+		system.Text = systemMessageText(system)
+	}
+
+	message := &talkinters.TalkMessageW{
+		At:   at,
+		Type: defs.TalkMessageTypeSystem,
+	}
+
+	if err := UpdateRichMessage(message, &defs.RichMessage{
+		Kind:   defs.RichMessageKindSystem,
+		System: system,
+	}); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func systemMessageText(system *defs.SystemMessage) string {
+	servicer := fmt.Sprintf("客服[%d]", system.ServicerID)
+	if system.ServicerName != "" {
+		servicer = fmt.Sprintf("客服 %s", system.ServicerName)
+	}
+
+	switch system.Event {
+	case defs.SystemEventAttach:
+		return servicer + " 加入了会话"
+	case defs.SystemEventDetach:
+		return servicer + " 离开了会话"
+	case defs.SystemEventTransfer:
+		return fmt.Sprintf("会话由客服[%d]转给%s", system.FromServicerID, servicer)
+	case defs.SystemEventClose:
+		if system.ClosedBy == ClosedByCustomer {
+			return "客户结束了会话"
+		}
+
+		if system.ClosedBy == ClosedByServicer {
+			return servicer + " 结束了会话"
+		}
+
+		return "会话已结束"
+	default:
+		return system.Event
+	}
+}
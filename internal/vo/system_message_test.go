@@ -0,0 +1,33 @@
+package vo
+
+import (
+	"testing"
+
+	"github.com/sgostarter/i/commerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/zservicer/talkbe/internal/defs"
+)
+
+func TestSystemMessage(t *testing.T) {
+	message, err := NewSystemMessage(100, &defs.SystemMessage{
+		Event:          defs.SystemEventTransfer,
+		ServicerID:     2,
+		ServicerName:   "alice",
+		FromServicerID: 1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, defs.TalkMessageTypeSystem, message.Type)
+	assert.Equal(t, "会话由客服[1]转给客服 alice", message.Text)
+
+	richMessage, ok := RichMessageFromDB(message)
+	assert.True(t, ok)
+	assert.Equal(t, defs.SystemEventTransfer, richMessage.System.Event)
+
+	customerMessage := TalkMessageDB2Pb4Customer(message)
+	servicerMessage := TalkMessageDB2Pb4Servicer(message)
+	assert.Equal(t, SystemMessageUser, customerMessage.User)
+	assert.Equal(t, SystemMessageUser, servicerMessage.User)
+	assert.Equal(t, customerMessage.GetText(), servicerMessage.GetText())
+
+	assert.ErrorIs(t, ValidateTalkMessage("t1", message), commerr.ErrPermissionDenied)
+}
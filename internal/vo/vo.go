@@ -2,9 +2,11 @@ package vo
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/sbasestarter/bizinters/talkinters"
 	"github.com/zservicer/protorepo/gens/talkpb"
+	"github.com/zservicer/talkbe/internal/defs"
 )
 
 func TaskStatusMapPb2Db(status talkpb.TalkStatus) talkinters.TalkStatus {
@@ -79,6 +81,15 @@ func TalkMessageWPb2Db(message *talkpb.TalkMessageW) *talkinters.TalkMessageW {
 
 	dbMessage := &talkinters.TalkMessageW{}
 
+	// the prefixed texts are never stored as they are, the clients would show them as rich messages
+	if text := message.GetText(); strings.HasPrefix(text, RichMessagePrefix) || strings.HasPrefix(text, MessageCommandPrefix) {
+		if !richMessagePb2Db(text, dbMessage) {
+			dbMessage.Type = talkinters.TalkMessageTypeUnknown
+		}
+
+		return dbMessage
+	}
+
 	if message.GetText() != "" {
 		dbMessage.Type = talkinters.TalkMessageTypeText
 		dbMessage.Text = message.GetText()
@@ -95,10 +106,16 @@ func TalkMessageWPb2Db(message *talkpb.TalkMessageW) *talkinters.TalkMessageW {
 func TalkMessageDB2Pb4Customer(message *talkinters.TalkMessageW) *talkpb.TalkMessage {
 	pbMessage := talkMessageDB2Pb(message)
 	if pbMessage != nil {
-		if pbMessage.CustomerMessage {
+		if message.Type == defs.TalkMessageTypeSystem {
+			pbMessage.User = SystemMessageUser
+		} else if pbMessage.CustomerMessage {
 			pbMessage.User = "您"
 		} else {
 			pbMessage.User = "客服"
+
+			if message.SenderUserName != "" {
+				pbMessage.User = fmt.Sprintf("客服(%s)", message.SenderUserName)
+			}
 		}
 	}
 
@@ -108,7 +125,17 @@ func TalkMessageDB2Pb4Customer(message *talkinters.TalkMessageW) *talkpb.TalkMes
 func TalkMessageDB2Pb4Servicer(message *talkinters.TalkMessageW) *talkpb.TalkMessage {
 	pbMessage := talkMessageDB2Pb(message)
 	if pbMessage != nil {
-		pbMessage.User = fmt.Sprintf("%s[%d]", pbMessage.User, message.SenderID)
+		if message.Type == defs.TalkMessageTypeSystem {
+			pbMessage.User = SystemMessageUser
+		} else {
+			pbMessage.User = fmt.Sprintf("%s[%d]", pbMessage.User, message.SenderID)
+		}
+
+		if message.Type == talkinters.TalkMessageTypeText {
+			pbMessage.Message = &talkpb.TalkMessage_Text{
+				Text: servicerText(message),
+			}
+		}
 	}
 
 	return pbMessage
@@ -134,6 +161,16 @@ func talkMessageDB2Pb(message *talkinters.TalkMessageW) *talkpb.TalkMessage {
 		pbMessage.Message = &talkpb.TalkMessage_Image{
 			Image: message.Data,
 		}
+	case defs.TalkMessageTypeRecalled:
+		pbMessage.Message = &talkpb.TalkMessage_Text{
+			Text: recalledMessageText,
+		}
+	default:
+		if IsRichMessageType(message.Type) {
+			pbMessage.Message = &talkpb.TalkMessage_Text{
+				Text: richMessageDB2Pb(message),
+			}
+		}
 	}
 
 	return pbMessage